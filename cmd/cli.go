@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/google/uuid"
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+// command is one subcommand of the binary; run receives the arguments following its name.
+type command struct {
+	usage   string
+	summary string
+	run     func(args []string)
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"serve":       {"serve", "start the API server (the default)", serve},
+		"migrate":     {"migrate up|down|status|seed", "manage the database schema and seed data", runMigrate},
+		"rotate-keys": {"rotate-keys [-batch-size N]", "re-encrypt custom headers under the active master key", rotateKeys},
+		"user":        {"user create|passwd|disable|list", "manage user accounts", runUser},
+		"template":    {"template import|export|list", "manage shared device templates", runTemplate},
+		"profile":     {"profile export -user NAME", "export the device profiles of a user", runProfile},
+		"config":      {"config print", "print the effective configuration, secrets masked", runConfig},
+		"help":        {"help", "show this help", printUsage},
+	}
+}
+
+func printUsage([]string) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: server <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-36s %s\n", commands[name].usage, commands[name].summary)
+	}
+}
+
+// dispatch runs the subcommand of a command group named by args[0].
+func dispatch(logger applog.AppLogger, group string, args []string, subcommands map[string]func(args []string)) {
+	if len(args) == 0 {
+		logger.Fatal(group+": missing subcommand", "usage", commands[group].usage)
+	}
+	run, ok := subcommands[args[0]]
+	if !ok {
+		logger.Fatal(group+": unknown subcommand", "subcommand", args[0], "usage", commands[group].usage)
+	}
+	run(args[1:])
+}
+
+// cliLogger logs to stderr, keeping stdout for the output of the command.
+func cliLogger() applog.AppLogger {
+	return infra.NewLogger(os.Stderr)
+}
+
+// cliContext is cancelled on SIGINT or SIGTERM. It carries request metadata so audit
+// events written by a command can be told apart from those of API requests.
+func cliContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	return util.WithRequestMeta(ctx, util.RequestMeta{UserAgent: "zenrows-cli", RequestID: uuid.NewString()}), stop
+}
+
+// cliDeps holds the repositories commands share with the server.
+type cliDeps struct {
+	db  *gorm.DB
+	uow *repo.UnitOfWorkImpl
+}
+
+func newCLIDeps(logger applog.AppLogger) cliDeps {
+	keys, err := infra.EncryptionKeyring()
+	if err != nil {
+		logger.Fatal("Failed to load encryption keys", "error", err)
+	}
+	db := infra.ConnectToDatabase(logger)
+	return cliDeps{db: db, uow: repo.NewUnitOfWorkImpl(logger, db, keys)}
+}
+
+// fail logs err, with the field violations of validation errors, and exits.
+func fail(logger applog.AppLogger, msg string, err error) {
+	var inv *apperr.InvalidArgErr
+	if errors.As(err, &inv) {
+		for _, v := range inv.Details() {
+			logger.Error(msg, "field", v.Field, "problem", v.Message)
+		}
+	}
+	logger.Fatal(msg, "error", err)
+}
+
+// readPassword prompts for a password on the terminal, twice, or reads one line from
+// stdin when it is not a terminal, so passwords never appear in the process arguments.
+func readPassword() (redact.Secret, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return redact.Secret(strings.TrimRight(line, "\r\n")), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(first) != string(second) {
+		return "", errors.New("passwords do not match")
+	}
+	return redact.Secret(first), nil
+}
+
+// openOutput returns stdout for "" or "-", and the created file otherwise.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// openInput returns stdin for "" or "-", and the opened file otherwise.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// writeJSON writes v as indented JSON to path, see openOutput.
+func writeJSON(path string, v any) error {
+	w, err := openOutput(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Join(enc.Encode(v), w.Close())
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"zenrows-challenge/internal/infra"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// runConfig prints the configuration the server would run with, after defaults,
+// environment overrides and secret files, as YAML with secrets masked. Loading already
+// validated it.
+//
+//	go run ./cmd config print
+func runConfig(args []string) {
+	logger := cliLogger()
+	dispatch(logger, "config", args, map[string]func([]string){
+		"print": func([]string) {
+			out, err := yaml.Marshal(infra.RedactedSettings())
+			if err != nil {
+				logger.Fatal("config print: failed to encode", "error", err)
+			}
+			fmt.Fprintf(os.Stdout, "# %s\n%s", viper.ConfigFileUsed(), out)
+		},
+	})
+}
@@ -1,7 +1,17 @@
 package main
 
 import (
-	"sync"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 	"zenrows-challenge/internal/adapter/http"
 	"zenrows-challenge/internal/adapter/repo"
 	"zenrows-challenge/internal/core/port"
@@ -9,78 +19,345 @@ import (
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/log"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 
 	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/infra/migrate"
 	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
 	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/openapi"
+	"zenrows-challenge/internal/pkg/ratelimit"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/validation"
 
 	"github.com/go-playground/validator/v10"
 )
 
 var (
-	logger applog.AppLogger
-	v      *validator.Validate
-	server *fiber.App
-	db     *gorm.DB
+	logger      applog.AppLogger
+	v           *validator.Validate
+	server      *fiber.App
+	adminServer *fiber.App
+	db          *gorm.DB
+	health      *infra.Health
+	replicaDB   *sql.DB
 
-	wg sync.WaitGroup
+	shutdownTracing func(context.Context) error
 
 	// repo
 	userRepo            port.UserRepo
 	deviceTemplatesRepo port.DeviceTemplateRepo
 	deviceProfileRepo   port.DeviceProfileRepo
+	quotaRepo           port.QuotaRepo
+	rateLimitStore      ratelimit.Store
+	rateLimitRepo       *repo.RateLimitRepoImpl
+	idempotencyRepo     *repo.IdempotencyRepoImpl
+	auditRepo           port.AuditRepo
+	unitOfWork          port.UnitOfWork
 
 	// service
 	userSvc           port.AuthenticationService
 	deviceTemplateSvc port.DeviceTemplateService
 	deviceProfileSvc  port.DeviceProfileService
+	quotaSvc          port.QuotaService
+	auditSvc          port.AuditService
 
 	// http handler
 	deviceTemplateHandler port.DeviceTemplateHandler
 	deviceProfileHandler  port.DeviceProfileHandler
+	userHandler           port.UserHandler
+	auditHandler          port.AuditHandler
+	logLevelHandler       port.LogLevelHandler
+	openAPIHandler        port.OpenAPIHandler
 )
 
 func initComponents() {
-	logger = applog.NewAppDefaultLogger()
-	db = infra.ConnectToDatabase()
-	v = validator.New()
+	appLogger := infra.NewLogger(os.Stdout)
+	logger = appLogger
+	logger.Info("Configuration loaded", "file", viper.ConfigFileUsed(), "config", infra.RedactedSettings())
+	var err error
+	if shutdownTracing, err = infra.InitTracing(context.Background()); err != nil {
+		logger.Fatal("Failed to initialise tracing", "error", err)
+	}
+	db = infra.ConnectToDatabase(logger)
+	if infra.MigrateOnStart() {
+		migrateOnStart(context.Background())
+	}
+	v = validation.New()
+	keys, err := infra.EncryptionKeyring()
+	if err != nil {
+		logger.Fatal("Failed to load encryption keys", "error", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Fatal("Failed to access connection pool", "error", err)
+	}
+	health = infra.NewHealth(logger, infra.HealthCheckTimeout(),
+		infra.DatabaseChecker{DB: sqlDB},
+		infra.MigrationChecker{DB: db, Expected: migrate.Latest()},
+		infra.PoolChecker{DB: sqlDB, MaxUtilization: infra.MaxPoolUtilization()},
+	)
+	if replicaDB = infra.ConnectToReplica(logger, db); replicaDB != nil {
+		health.Register(infra.ReplicaChecker{DB: replicaDB})
+	}
 
 	userRepo = repo.NewUserRepoImpl(logger, db)
 	deviceTemplatesRepo = repo.NewDeviceTemplateRepoImpl(logger, db)
-	deviceProfileRepo = repo.NewDeviceProfileRepoImpl(logger, db)
+	deviceProfileRepo = repo.NewDeviceProfileRepoImpl(logger, db, keys)
+	quotaRepo = repo.NewQuotaRepoImpl(logger, db)
+	idempotencyRepo = repo.NewIdempotencyRepoImpl(logger, db, keys)
+	auditRepo = repo.NewAuditRepoImpl(logger, db)
+	unitOfWork = repo.NewUnitOfWorkImpl(logger, db, keys)
+	if infra.RateLimitStore() == infra.RateLimitStorePostgres {
+		rateLimitRepo = repo.NewRateLimitRepoImpl(logger, db)
+		rateLimitStore = rateLimitRepo
+	} else {
+		rateLimitStore = ratelimit.NewMemoryStore()
+	}
 
-	userSvc = usecase.NewAuthenticationService(logger, userRepo)
+	userSvc = usecase.NewAuthenticationService(logger, userRepo, auditRepo)
 
-	deviceTemplateSvc = usecase.NewDeviceTemplateServiceImpl(logger, deviceTemplatesRepo)
-	deviceProfileSvc = usecase.NewDeviceProfileServiceImpl(logger, deviceProfileRepo, deviceTemplatesRepo, v)
+	plans, defaultPlan := infra.QuotaPlans()
+	quotas := usecase.NewQuotaServiceImpl(logger, quotaRepo, deviceProfileRepo, plans, defaultPlan)
+	quotaSvc = quotas
+	deviceTemplateSvc = usecase.NewDeviceTemplateServiceImpl(logger, deviceTemplatesRepo, unitOfWork, v)
+	deviceProfileSvc = usecase.NewDeviceProfileServiceImpl(logger, deviceProfileRepo, deviceTemplatesRepo, quotaSvc, unitOfWork, v)
+	auditSvc = usecase.NewAuditServiceImpl(logger, auditRepo, userRepo)
 
 	deviceTemplateHandler = http.NewDeviceTemplateHandlerImpl(logger, deviceTemplateSvc)
-	deviceProfileHandler = http.NewDeviceProfileHandlerImpl(logger, deviceProfileSvc, v)
+	deviceProfileHandler = http.NewDeviceProfileHandlerImpl(logger, deviceProfileSvc, v, responseRedactor())
+	userHandler = http.NewUserHandlerImpl(logger, quotaSvc)
+	auditHandler = http.NewAuditHandlerImpl(logger, auditSvc)
+	logLevelHandler = http.NewLogLevelHandlerImpl(logger, appLogger, v)
+	openAPIHandler = http.NewOpenAPIHandlerImpl()
+
+	// Rate limits, CORS origins and feature flags are read from infra on each request;
+	// the log level and quota plans are pushed here when a reload changes them.
+	infra.OnReload(func(old, cur *infra.Config) {
+		if cur.Log.Level != old.Log.Level {
+			if err := appLogger.SetLevel(cur.Log.Level); err != nil {
+				logger.Error("Failed to apply reloaded log level", "error", err)
+			}
+		}
+		quotas.SetPlans(infra.QuotaPlans())
+	})
 }
 
 func initRoutes(server *fiber.App) {
-	// Unprotected route
-	server.Get("/health", func(c fiber.Ctx) error { return c.SendString("UP!") })
-
-	// Protected routes group: apply BasicAuth to everything except /health
-	protected := server.Group("/", middleware.BasicAuthCheckMiddleware(userSvc, v))
-	protected.Get("/device-templates", deviceTemplateHandler.List)
-	protected.Get("/device-profiles", deviceProfileHandler.ListDeviceProfilesByUserID)
-	protected.Post("/device-profiles", deviceProfileHandler.CreateDeviceProfile)
-	protected.Put("/device-profiles/:id", deviceProfileHandler.UpdateDeviceProfile)
-	protected.Delete("/device-profiles/:id", deviceProfileHandler.DeleteDeviceProfile)
+	publicLimit, authLimit, apiLimit := rateLimitMiddlewares()
+	doc, err := openapi.Load(http.OpenAPISpec())
+	if err != nil {
+		logger.Fatal("Invalid OpenAPI document", "error", err)
+	}
+
+	if infra.ValidateResponses() {
+		server.Use(middleware.ResponseValidationMiddleware(logger, doc))
+	}
+	server.Use(
+		middleware.MetricsMiddleware(),
+		middleware.CORSMiddleware(infra.CORSOriginAllowed, infra.CORSAllowCredentials(), infra.CORSMaxAge()),
+		middleware.RequestIDMiddleware(),
+		middleware.RequestContextMiddleware(infra.DisconnectCheck()),
+		middleware.TracingMiddleware(),
+		middleware.AccessLogMiddleware(logger),
+		middleware.RequestTimeoutMiddleware(infra.RequestTimeout()),
+	)
+
+	// Invalid requests count against the rate limits but not against the quota.
+	var protected []fiber.Handler
+	if infra.ValidateRequests() {
+		protected = append(protected, middleware.RequestValidationMiddleware(doc))
+	}
+	protected = append(protected, middleware.RequestQuotaMiddleware(quotaSvc))
+
+	routes := http.Routes{
+		PublicLimit: publicLimit,
+		AuthLimit:   authLimit,
+		Authenticate: []fiber.Handler{
+			middleware.ClientCertAuthMiddleware(userSvc, infra.CertificateIdentity()),
+			middleware.BasicAuthCheckMiddleware(userSvc, v),
+		},
+		APILimit:        apiLimit,
+		Protected:       protected,
+		Idempotency:     middleware.IdempotencyMiddleware(logger, idempotencyRepo, infra.IdempotencyTTL(), infra.IdempotencyLease()),
+		Livez:           health.Livez,
+		Readyz:          health.Readyz,
+		DeviceTemplates: deviceTemplateHandler,
+		DeviceProfiles:  deviceProfileHandler,
+		Users:           userHandler,
+		Audit:           auditHandler,
+		OpenAPI:         openAPIHandler,
+	}
+	if adminServer == nil {
+		admin := adminRoutes()
+		routes.Admin = &admin
+	}
+	http.RegisterRoutes(server, routes)
+}
+
+// adminRoutes returns the operational endpoints to serve, on the admin listener when
+// server.admin_port is set and on the API server otherwise.
+func adminRoutes() http.AdminRoutes {
+	var a http.AdminRoutes
+	if infra.MetricsEnabled() {
+		a.Metrics, a.MetricsGuard = adaptor.HTTPHandler(metrics.Handler()), tokenGuard(infra.MetricsToken())
+	}
+
+	// Changing the log level is never left open on the API listener.
+	if adminToken := infra.AdminToken(); adminServer != nil || adminToken != "" {
+		a.LogLevel, a.LogLevelGuard = logLevelHandler, tokenGuard(adminToken)
+	}
+	return a
+}
+
+// tokenGuard requires token as bearer token, or lets every request through when it is empty.
+func tokenGuard(token string) fiber.Handler {
+	if token == "" {
+		return func(c fiber.Ctx) error { return c.Next() }
+	}
+	return middleware.BearerTokenMiddleware(token)
+}
+
+// responseRedactor returns the redactor masking custom headers in list responses, or nil
+// when redaction.mask_responses is off.
+func responseRedactor() *redact.Redactor {
+	if !infra.MaskResponses() {
+		return nil
+	}
+	r, err := infra.Redactor()
+	if err != nil {
+		logger.Fatal("Invalid redaction config", "error", err)
+	}
+	return r
+}
+
+// rateLimitMiddlewares returns the limiters of the public routes and of the other routes
+// before and after authentication, or nil ones when rate limiting is disabled. The
+// public and auth groups are keyed by client IP; the api group by authenticated user,
+// so users sharing an address get buckets of their own.
+func rateLimitMiddlewares() (public, auth, api fiber.Handler) {
+	if !infra.RateLimitEnabled() {
+		return func(c fiber.Ctx) error { return c.Next() }, nil, nil
+	}
+	limiter := func(group string) fiber.Handler {
+		return middleware.RateLimitMiddleware(logger, rateLimitStore, func() ratelimit.Policy { return infra.RateLimitPolicy(group) })
+	}
+	return limiter("public"), limiter("auth"), limiter("api")
 }
 
 func main() {
-	if err := infra.LoadConfig(); err != nil {
-		log.Fatal("Failed to load config: ", err)
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	} else if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		name = "help"
 	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(nil)
+		os.Exit(2)
+	}
+	// Usage is printed without a configuration, so it works before one is set up.
+	if name != "help" {
+		if err := infra.LoadConfig(); err != nil {
+			log.Fatal("Failed to load config: ", err)
+		}
+	}
+	cmd.run(args)
+}
 
+// serve starts the API server, and the admin server when server.admin_port is set, and
+// blocks until a shutdown signal has been handled. It exits with status 1 when a server
+// cannot listen or a component fails to start or stop.
+//
+//	go run ./cmd [serve]
+func serve([]string) {
 	initComponents()
 
-	server = infra.StartServer(logger, &wg)
+	tlsConfig, err := infra.ServerTLSConfig(logger)
+	if err != nil {
+		logger.Fatal("Invalid TLS configuration", "error", err)
+	}
+	server = infra.NewServer()
+	adminPort := infra.AdminPort()
+	if adminPort != "" {
+		adminServer = infra.NewAdminServer()
+		http.RegisterAdminRoutes(adminServer, adminRoutes())
+	}
 	initRoutes(server)
 
-	infra.GracefulShutdownServer(logger, &wg, server, nil)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	lc := infra.NewLifecycle(logger, infra.ShutdownTimeout())
+	addComponents(lc, adminPort, tlsConfig)
+	if err := lc.Run(ctx); err != nil {
+		logger.Error("Server stopped with errors", "error", err)
+		os.Exit(1)
+	}
+}
+
+// pruneInterval is how often expired rows are deleted from the database.
+const pruneInterval = time.Minute
+
+// addComponents registers the parts of the server in the order they start; they stop in
+// reverse. On shutdown, readiness fails for server.drain_delay first, so load balancers
+// stop routing requests here, then the listeners drain, the config watcher and the
+// pruners stop, pending spans are flushed and the connection pools are closed last.
+func addComponents(lc *infra.Lifecycle, adminPort string, tlsConfig *tls.Config) {
+	lc.Add(infra.Component{Name: "database", Stop: func(context.Context) error {
+		return closeDatabase()
+	}})
+	lc.Add(infra.Component{Name: "tracing", Stop: shutdownTracing})
+	if rateLimitRepo != nil {
+		lc.Add(infra.PeriodicComponent(lc, "rate_limit_pruner", pruneInterval, func(ctx context.Context) error {
+			_, err := rateLimitRepo.PruneRateLimitBuckets(ctx)
+			return err
+		}))
+	}
+	lc.Add(infra.PeriodicComponent(lc, "idempotency_pruner", pruneInterval, func(ctx context.Context) error {
+		_, err := idempotencyRepo.PurgeExpiredIdempotencyKeys(ctx)
+		return err
+	}))
+	var stopWatching func()
+	lc.Add(infra.Component{
+		Name:  "config_watcher",
+		Start: func(context.Context) error { stopWatching = infra.WatchConfig(logger); return nil },
+		Stop:  func(context.Context) error { stopWatching(); return nil },
+	})
+	if adminServer != nil {
+		lc.Add(infra.ServerComponent(lc, "admin_server", adminServer, adminPort, nil))
+	}
+	lc.Add(infra.ServerComponent(lc, "api_server", server, strconv.Itoa(infra.Current().Server.Port), tlsConfig))
+	lc.Add(infra.Component{Name: "readiness", Stop: drain})
+}
+
+// drain fails readiness and waits server.drain_delay, or until ctx is done.
+func drain(ctx context.Context) error {
+	health.Drain()
+	t := time.NewTimer(infra.DrainDelay())
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeDatabase closes the connection pools of the primary and, if one is configured,
+// the replica.
+func closeDatabase() error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	err = sqlDB.Close()
+	if replicaDB != nil {
+		err = errors.Join(err, replicaDB.Close())
+	}
+	return err
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/infra/migrate"
+)
+
+// runMigrate manages the schema of the configured database. Concurrent runs, including
+// instances migrating on start, wait for each other on an advisory lock.
+//
+//	go run ./cmd migrate up
+//	go run ./cmd migrate down [-steps 1]
+//	go run ./cmd migrate status
+//	go run ./cmd migrate seed [name...]
+func runMigrate(args []string) {
+	logger := cliLogger()
+	if len(args) == 0 {
+		logger.Fatal("migrate: expected one of up, down, status, seed")
+	}
+
+	ctx, stop := cliContext()
+	defer stop()
+
+	db, err := infra.ConnectToDatabase(logger).DB()
+	if err != nil {
+		logger.Fatal("migrate: failed to access connection pool", "error", err)
+	}
+	m := migrate.New(logger, db)
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			logger.Fatal("migrate: up failed", "applied", n, "error", err)
+		}
+		logger.Info("migrate: up complete", "applied", n, "version", migrate.Latest())
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "migrations rolled back, newest first")
+		_ = fs.Parse(args)
+		if *steps <= 0 {
+			logger.Fatal("migrate: steps must be positive")
+		}
+		n, err := m.Down(ctx, *steps)
+		if err != nil {
+			logger.Fatal("migrate: down failed", "rolled_back", n, "error", err)
+		}
+		logger.Info("migrate: down complete", "rolled_back", n)
+	case "status":
+		states, err := m.Status(ctx)
+		if err != nil {
+			logger.Fatal("migrate: status failed", "error", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range states {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		_ = w.Flush()
+	case "seed":
+		if err := m.Seed(ctx, args...); err != nil {
+			logger.Fatal("migrate: seed failed", "error", err)
+		}
+		logger.Info("migrate: seed complete")
+	default:
+		logger.Fatal("migrate: unknown command", "command", cmd)
+	}
+}
+
+// migrateOnStart applies pending migrations, and the seeds when database.migrations.seed
+// is set, before the service starts serving.
+func migrateOnStart(ctx context.Context) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Fatal("Failed to access connection pool", "error", err)
+	}
+	m := migrate.New(logger, sqlDB)
+	n, err := m.Up(ctx)
+	if err != nil {
+		logger.Fatal("Failed to migrate database", "applied", n, "error", err)
+	}
+	logger.Info("Database schema up to date", "applied", n, "version", migrate.Latest())
+	if !infra.SeedOnStart() {
+		return
+	}
+	if err := m.Seed(ctx); err != nil {
+		logger.Fatal("Failed to seed database", "error", err)
+	}
+}
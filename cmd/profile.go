@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/redact"
+)
+
+// profileExportPageSize is the page size used to walk a user's profiles; the repository
+// caps pages at 100.
+const profileExportPageSize = 100
+
+// runProfile exports device profiles. Values of sensitive custom headers are masked as in
+// list responses unless -reveal is set.
+//
+//	go run ./cmd profile export -user alice [-o profiles.json] [-reveal]
+func runProfile(args []string) {
+	logger := cliLogger()
+	dispatch(logger, "profile", args, map[string]func([]string){
+		"export": func(args []string) { exportProfiles(logger, args) },
+	})
+}
+
+func exportProfiles(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("profile export", flag.ExitOnError)
+	username := fs.String("user", "", "username whose profiles are exported")
+	out := fs.String("o", "-", "file to write, - for stdout")
+	reveal := fs.Bool("reveal", false, "write sensitive custom header values in clear")
+	_ = fs.Parse(args)
+	if *username == "" {
+		logger.Fatal("profile export: -user is required")
+	}
+	r, err := infra.Redactor()
+	if err != nil {
+		logger.Fatal("profile export: invalid redaction config", "error", err)
+	}
+	keys, err := infra.EncryptionKeyring()
+	if err != nil {
+		logger.Fatal("profile export: failed to load encryption keys", "error", err)
+	}
+
+	ctx, stop := cliContext()
+	defer stop()
+	db := infra.ConnectToDatabase(logger)
+	u, err := repo.NewUserRepoImpl(logger, db).GetUserByUsername(ctx, *username)
+	if err != nil {
+		logger.Fatal("profile export: user not found", "error", err)
+	}
+
+	profiles := repo.NewDeviceProfileRepoImpl(logger, db, keys)
+	all := []entity.DeviceProfile{}
+	for page := 1; ; page++ {
+		items, err := profiles.ListDeviceProfiles(ctx, u.ID.String(), page, profileExportPageSize)
+		if err != nil {
+			logger.Fatal("profile export: failed to list profiles", "page", page, "error", err)
+		}
+		all = append(all, items...)
+		if len(items) < profileExportPageSize {
+			break
+		}
+	}
+	if !*reveal {
+		for i := range all {
+			for k := range all[i].CustomHeaders {
+				if r.IsSensitiveHeader(k) {
+					all[i].CustomHeaders[k] = redact.Mask
+				}
+			}
+		}
+	}
+
+	if err := writeJSON(*out, all); err != nil {
+		logger.Fatal("profile export: failed to write output", "error", err)
+	}
+	logger.Info("profile export: done", "exported", len(all))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/infra"
+)
+
+// rotateKeys re-encrypts the custom headers of every device profile not yet stored under
+// the active master key, in batches of one transaction each, so it can run next to the
+// API and be interrupted and restarted at any point.
+//
+//	go run ./cmd rotate-keys [-batch-size 500]
+func rotateKeys(args []string) {
+	fs := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	batchSize := fs.Int("batch-size", 500, "profiles re-encrypted per transaction")
+	_ = fs.Parse(args)
+
+	logger := cliLogger()
+	if *batchSize <= 0 {
+		logger.Fatal("rotate-keys: batch size must be positive")
+	}
+	keys, err := infra.EncryptionKeyring()
+	if err != nil {
+		logger.Fatal("rotate-keys: failed to load encryption keys", "error", err)
+	}
+	if keys == nil {
+		logger.Fatal("rotate-keys: encryption is disabled")
+	}
+
+	ctx, stop := cliContext()
+	defer stop()
+
+	r := repo.NewDeviceProfileRepoImpl(logger, infra.ConnectToDatabase(logger), keys)
+	total := 0
+	for {
+		n, err := r.ReencryptDeviceProfiles(ctx, *batchSize)
+		if err != nil {
+			logger.Fatal("rotate-keys: batch failed", "rotated", total, "error", err)
+		}
+		total += n
+		logger.Info("rotate-keys: batch done", "rotated", total, "active_key_id", keys.ActiveKeyID())
+		if n < *batchSize {
+			break
+		}
+	}
+	logger.Info("rotate-keys: complete", "rotated", total)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/core/usecase"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/validation"
+
+	"github.com/google/uuid"
+)
+
+// runTemplate manages the shared device templates. Files hold a JSON array of templates
+// as returned by GET /device-templates; imports match existing templates by name and
+// ignore the id and created_at fields, so an export can be imported into any database.
+//
+//	go run ./cmd template import [-f templates.json]
+//	go run ./cmd template export [-o templates.json]
+//	go run ./cmd template list
+func runTemplate(args []string) {
+	logger := cliLogger()
+	dispatch(logger, "template", args, map[string]func([]string){
+		"import": func(args []string) { importTemplates(logger, args) },
+		"export": func(args []string) { exportTemplates(logger, args) },
+		"list":   func(args []string) { listTemplates(logger, args) },
+	})
+}
+
+func newDeviceTemplateService(logger applog.AppLogger) port.DeviceTemplateService {
+	deps := newCLIDeps(logger)
+	return usecase.NewDeviceTemplateServiceImpl(logger, repo.NewDeviceTemplateRepoImpl(logger, deps.db), deps.uow, validation.New())
+}
+
+func importTemplates(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("template import", flag.ExitOnError)
+	file := fs.String("f", "-", "JSON file to import, - for stdin")
+	_ = fs.Parse(args)
+
+	in, err := openInput(*file)
+	if err != nil {
+		logger.Fatal("template import: failed to open input", "error", err)
+	}
+	var templates []entity.DeviceTemplate
+	dec := json.NewDecoder(in)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&templates)
+	_ = in.Close()
+	if err != nil {
+		logger.Fatal("template import: invalid JSON", "error", err)
+	}
+	for i := range templates {
+		templates[i].ID, templates[i].CreatedAt = uuid.Nil, time.Time{}
+	}
+
+	ctx, stop := cliContext()
+	defer stop()
+	created, updated, err := newDeviceTemplateService(logger).ImportDeviceTemplates(ctx, templates)
+	if err != nil {
+		fail(logger, "template import: failed", err)
+	}
+	logger.Info("template import: done", "created", created, "updated", updated)
+}
+
+func exportTemplates(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("template export", flag.ExitOnError)
+	out := fs.String("o", "-", "file to write, - for stdout")
+	_ = fs.Parse(args)
+
+	ctx, stop := cliContext()
+	defer stop()
+	templates, err := newDeviceTemplateService(logger).RetrieveDeviceTemplates(ctx)
+	if err != nil {
+		fail(logger, "template export: failed", err)
+	}
+	if templates == nil {
+		templates = []entity.DeviceTemplate{}
+	}
+	if err := writeJSON(*out, templates); err != nil {
+		logger.Fatal("template export: failed to write output", "error", err)
+	}
+	logger.Info("template export: done", "exported", len(templates))
+}
+
+func listTemplates(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("template list", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	ctx, stop := cliContext()
+	defer stop()
+	templates, err := newDeviceTemplateService(logger).RetrieveDeviceTemplates(ctx)
+	if err != nil {
+		fail(logger, "template list: failed", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tNAME\tDEVICE TYPE\tSIZE\tCOUNTRY")
+	for _, t := range templates {
+		size, country := "-", "-"
+		if t.Width != nil && t.Height != nil {
+			size = fmt.Sprintf("%dx%d", *t.Width, *t.Height)
+		}
+		if t.CountryCode != nil {
+			country = *t.CountryCode
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.DeviceType, size, country)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/core/usecase"
+	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/validation"
+)
+
+// runUser manages user accounts. Passwords are prompted for, or read from stdin when it
+// is not a terminal, and stored as bcrypt hashes.
+//
+//	go run ./cmd user create -username alice [-role user|admin] [-plan free]
+//	go run ./cmd user passwd -username alice
+//	go run ./cmd user disable -username alice
+//	go run ./cmd user list
+func runUser(args []string) {
+	logger := cliLogger()
+	dispatch(logger, "user", args, map[string]func([]string){
+		"create":  func(args []string) { createUser(logger, args) },
+		"passwd":  func(args []string) { changePassword(logger, args) },
+		"disable": func(args []string) { disableUser(logger, args) },
+		"list":    func(args []string) { listUsers(logger, args) },
+	})
+}
+
+func newUserAdminService(logger applog.AppLogger) port.UserAdminService {
+	deps := newCLIDeps(logger)
+	return usecase.NewUserAdminServiceImpl(logger, repo.NewUserRepoImpl(logger, deps.db), deps.uow, validation.New())
+}
+
+func createUser(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("user create", flag.ExitOnError)
+	username := fs.String("username", "", "login name, 3 to 64 characters")
+	role := fs.String("role", entity.UserRoleUser, "user or admin")
+	plan := fs.String("plan", "", "quota plan; the default plan when empty")
+	_ = fs.Parse(args)
+
+	plans, defaultPlan := infra.QuotaPlans()
+	if *plan == "" {
+		*plan = defaultPlan
+	}
+	if _, ok := plans[*plan]; !ok {
+		logger.Fatal("user create: unknown plan", "plan", *plan)
+	}
+	password, err := readPassword()
+	if err != nil {
+		logger.Fatal("user create: failed to read password", "error", err)
+	}
+
+	ctx, stop := cliContext()
+	defer stop()
+	u := &entity.User{Username: *username, Role: *role, Plan: *plan}
+	if err := newUserAdminService(logger).CreateUser(ctx, u, password); err != nil {
+		fail(logger, "user create: failed", err)
+	}
+	logger.Info("user create: done", "id", u.ID.String(), "role", u.Role, "plan", u.Plan)
+}
+
+func changePassword(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("user passwd", flag.ExitOnError)
+	username := fs.String("username", "", "login name")
+	_ = fs.Parse(args)
+
+	password, err := readPassword()
+	if err != nil {
+		logger.Fatal("user passwd: failed to read password", "error", err)
+	}
+
+	ctx, stop := cliContext()
+	defer stop()
+	if err := newUserAdminService(logger).ChangePassword(ctx, *username, password); err != nil {
+		fail(logger, "user passwd: failed", err)
+	}
+	logger.Info("user passwd: done")
+}
+
+func disableUser(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("user disable", flag.ExitOnError)
+	username := fs.String("username", "", "login name")
+	_ = fs.Parse(args)
+
+	ctx, stop := cliContext()
+	defer stop()
+	if err := newUserAdminService(logger).DisableUser(ctx, *username); err != nil {
+		fail(logger, "user disable: failed", err)
+	}
+	logger.Info("user disable: done")
+}
+
+func listUsers(logger applog.AppLogger, args []string) {
+	fs := flag.NewFlagSet("user list", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	ctx, stop := cliContext()
+	defer stop()
+	users, err := newUserAdminService(logger).ListUsers(ctx)
+	if err != nil {
+		fail(logger, "user list: failed", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tUSERNAME\tROLE\tPLAN\tCREATED AT\tDISABLED AT")
+	for _, u := range users {
+		disabledAt := "-"
+		if u.DisabledAt != nil {
+			disabledAt = u.DisabledAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.Username, u.Role, u.Plan, u.CreatedAt.Format(time.RFC3339), disabledAt)
+	}
+	_ = w.Flush()
+}
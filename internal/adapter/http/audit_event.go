@@ -0,0 +1,112 @@
+package http
+
+import (
+	"time"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+type AuditHandlerImpl struct {
+	log applog.AppLogger
+	svc port.AuditService
+}
+
+func NewAuditHandlerImpl(log applog.AppLogger, svc port.AuditService) *AuditHandlerImpl {
+	return &AuditHandlerImpl{log: log, svc: svc}
+}
+
+func (h *AuditHandlerImpl) ListAuditEvents(c fiber.Ctx) error {
+	f, err := parseAuditEventFilter(c)
+	if err != nil {
+		return handleError(c, err)
+	}
+	if actor := c.Query("actor_id"); actor != "" {
+		id, err := uuid.Parse(actor)
+		if err != nil {
+			return handleError(c, invalidQueryParam("actor_id", "uuid", "must be a valid UUID"))
+		}
+		f.ActorID = &id
+	}
+
+	ctx, _, err := principalContext(c)
+	if err != nil {
+		return err
+	}
+
+	items, err := h.svc.ListAuditEvents(ctx, f)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(mapAuditEvents(items))
+}
+
+func (h *AuditHandlerImpl) ListMyAuditEvents(c fiber.Ctx) error {
+	f, err := parseAuditEventFilter(c)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	ctx, _, err := principalContext(c)
+	if err != nil {
+		return err
+	}
+
+	items, err := h.svc.ListUserAuditEvents(ctx, f)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(mapAuditEvents(items))
+}
+
+// parseAuditEventFilter reads the filters shared by the audit listings. from and to are RFC 3339 timestamps.
+func parseAuditEventFilter(c fiber.Ctx) (entity.AuditEventFilter, error) {
+	page, pageSize, err := parsePagination(c.Query("page", "1"), c.Query("page_size", "20"))
+	if err != nil {
+		return entity.AuditEventFilter{}, err
+	}
+	f := entity.AuditEventFilter{
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+		Outcome:      c.Query("outcome"),
+		Page:         page,
+		PageSize:     pageSize,
+	}
+	if f.Outcome != "" && f.Outcome != entity.AuditOutcomeSuccess && f.Outcome != entity.AuditOutcomeFailure {
+		return entity.AuditEventFilter{}, invalidQueryParam("outcome", "oneof", "must be one of: success, failure")
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return entity.AuditEventFilter{}, invalidQueryParam("from", "datetime", "must be an RFC 3339 timestamp")
+		}
+		f.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return entity.AuditEventFilter{}, invalidQueryParam("to", "datetime", "must be an RFC 3339 timestamp")
+		}
+		f.To = &t
+	}
+	return f, nil
+}
+
+func invalidQueryParam(field, rule, msg string) *apperr.InvalidArgErr {
+	return apperr.NewInvalidArgErr("invalid "+field+" parameter", nil).
+		WithDetails(apperr.FieldViolation{Field: field, Rule: rule, Message: msg})
+}
+
+func mapAuditEvents(items []entity.AuditEvent) []AuditEventResponse {
+	resp := make([]AuditEventResponse, len(items))
+	for i, item := range items {
+		resp[i] = mapToAuditEventResponse(item)
+	}
+	return resp
+}
@@ -0,0 +1,20 @@
+package http
+
+import (
+	"context"
+
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// principalContext returns the request context together with the authenticated caller.
+// When no caller is attached it writes the 401 response and returns the write result as err.
+func principalContext(c fiber.Ctx) (context.Context, util.Principal, error) {
+	ctx := c.Context()
+	p, ok := util.PrincipalFrom(ctx)
+	if !ok {
+		return nil, util.Principal{}, unauthorized(c)
+	}
+	return ctx, p, nil
+}
@@ -1,38 +1,48 @@
 package http
 
 import (
-	"context"
-	"errors"
-	"fmt"
 	"net/http"
 	"strconv"
 
+	"zenrows-challenge/internal/core/entity"
 	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/apperr"
 	"zenrows-challenge/internal/pkg/applog"
-	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/validation"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
 )
 
+var invalidIDViolation = apperr.FieldViolation{Field: "id", Rule: "uuid", Message: "must be a valid UUID"}
+
 type DeviceProfileHandlerImpl struct {
-	log applog.AppLogger
-	svc port.DeviceProfileService
-	v   *validator.Validate
+	log    applog.AppLogger
+	svc    port.DeviceProfileService
+	v      *validator.Validate
+	redact *redact.Redactor
 }
 
-func NewDeviceProfileHandlerImpl(log applog.AppLogger, svc port.DeviceProfileService, v *validator.Validate) *DeviceProfileHandlerImpl {
-	return &DeviceProfileHandlerImpl{log: log, svc: svc, v: v}
+// NewDeviceProfileHandlerImpl constructs a DeviceProfileHandlerImpl. When r is not nil,
+// sensitive custom header values are masked in every profile response unless
+// ?reveal=true is set.
+func NewDeviceProfileHandlerImpl(log applog.AppLogger, svc port.DeviceProfileService, v *validator.Validate, r *redact.Redactor) *DeviceProfileHandlerImpl {
+	return &DeviceProfileHandlerImpl{log: log, svc: svc, v: v, redact: r}
 }
 
 func (h *DeviceProfileHandlerImpl) ListDeviceProfilesByUserID(c fiber.Ctx) error {
 	page, pageSize, err := parsePagination(c.Query("page", "1"), c.Query("page_size", "20"))
 	if err != nil {
-		return badRequest(c, err.Error())
+		return handleError(c, err)
+	}
+	reveal, err := parseReveal(c.Query("reveal", "false"))
+	if err != nil {
+		return handleError(c, err)
 	}
 
-	ctx, _, err := h.userContext(c)
+	ctx, _, err := principalContext(c)
 	if err != nil {
 		return err
 	}
@@ -42,54 +52,82 @@ func (h *DeviceProfileHandlerImpl) ListDeviceProfilesByUserID(c fiber.Ctx) error
 		return handleError(c, err)
 	}
 
+	// Only the caller's own profiles are listed, so revealing is always done by the owner.
 	resp := make([]DeviceProfileResponse, len(items))
 	for i, item := range items {
-		resp[i] = mapToDeviceProfileResponse(item)
+		resp[i] = h.toResponse(item, reveal)
 	}
 	return c.JSON(resp)
 }
 
+func (h *DeviceProfileHandlerImpl) GetDeviceProfile(c fiber.Ctx) error {
+	idStr := c.Params("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		return badRequest(c, "invalid device profile id", invalidIDViolation)
+	}
+	reveal, err := parseReveal(c.Query("reveal", "false"))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	ctx, _, err := principalContext(c)
+	if err != nil {
+		return err
+	}
+
+	dp, err := h.svc.GetDeviceProfile(ctx, idStr)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return c.JSON(h.toResponse(*dp, reveal))
+}
+
 func (h *DeviceProfileHandlerImpl) CreateDeviceProfile(c fiber.Ctx) error {
+	reveal, err := parseReveal(c.Query("reveal", "false"))
+	if err != nil {
+		return handleError(c, err)
+	}
+
 	var req DeviceProfileCreateRequest
 	if err := c.Bind().Body(&req); err != nil {
-		return badRequest(c, "invalid request body")
+		return invalidBody(c, err)
 	}
 	if err := h.v.Struct(req); err != nil {
-		return badRequest(c, fmt.Sprintf("validation failed: %v", err))
+		return badRequest(c, "validation failed", validation.Violations(err)...)
 	}
 
-	ctx, userIDStr, err := h.userContext(c)
+	ctx, p, err := principalContext(c)
 	if err != nil {
 		return err
 	}
 
-	userUUID, err := uuid.Parse(userIDStr)
+	dp, err := mapDeviceProfileCreateRequestToEntity(req, p.UserID)
 	if err != nil {
-		return badRequest(c, "invalid user id in context")
-	}
-
-	dp, err := mapDeviceProfileCreateRequestToEntity(req, userUUID)
-	if err != nil {
-		return badRequest(c, err.Error())
+		return handleError(c, err)
 	}
 
 	if err := h.svc.CreateDeviceProfile(ctx, dp); err != nil {
 		return handleError(c, err)
 	}
 
-	return c.Status(http.StatusCreated).JSON(mapToDeviceProfileResponse(*dp))
+	return c.Status(http.StatusCreated).JSON(h.toResponse(*dp, reveal))
 }
 
 func (h *DeviceProfileHandlerImpl) UpdateDeviceProfile(c fiber.Ctx) error {
 	idStr := c.Params("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		return badRequest(c, "invalid device profile id")
+		return badRequest(c, "invalid device profile id", invalidIDViolation)
+	}
+	reveal, err := parseReveal(c.Query("reveal", "false"))
+	if err != nil {
+		return handleError(c, err)
 	}
 
 	var req DeviceProfileUpdateRequest
 	if err := c.Bind().Body(&req); err != nil {
-		return badRequest(c, "invalid request body")
+		return invalidBody(c, err)
 	}
 
 	if isEmptyUpdateRequest(req) {
@@ -97,35 +135,30 @@ func (h *DeviceProfileHandlerImpl) UpdateDeviceProfile(c fiber.Ctx) error {
 	}
 
 	if err := h.v.Struct(req); err != nil {
-		return badRequest(c, fmt.Sprintf("validation failed: %v", err))
+		return badRequest(c, "validation failed", validation.Violations(err)...)
 	}
 
-	ctx, userIDStr, err := h.userContext(c)
+	ctx, p, err := principalContext(c)
 	if err != nil {
 		return err
 	}
 
-	userUUID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		return badRequest(c, "invalid user id in context")
-	}
-
-	dp := mapDeviceProfileUpdateRequestToEntity(req, id, userUUID)
+	dp := mapDeviceProfileUpdateRequestToEntity(req, id, p.UserID)
 	updated, err := h.svc.UpdateDeviceProfile(ctx, &dp)
 	if err != nil {
 		return handleError(c, err)
 	}
 
-	return c.JSON(mapToDeviceProfileResponse(*updated))
+	return c.JSON(h.toResponse(*updated, reveal))
 }
 
 func (h *DeviceProfileHandlerImpl) DeleteDeviceProfile(c fiber.Ctx) error {
 	idStr := c.Params("id")
 	if _, err := uuid.Parse(idStr); err != nil {
-		return badRequest(c, "invalid device profile id")
+		return badRequest(c, "invalid device profile id", invalidIDViolation)
 	}
 
-	ctx, _, err := h.userContext(c)
+	ctx, _, err := principalContext(c)
 	if err != nil {
 		return err
 	}
@@ -137,36 +170,37 @@ func (h *DeviceProfileHandlerImpl) DeleteDeviceProfile(c fiber.Ctx) error {
 	return c.SendStatus(http.StatusNoContent)
 }
 
-func (h *DeviceProfileHandlerImpl) userContext(c fiber.Ctx) (context.Context, string, error) {
-	val := c.Locals(middleware.AuthUserIDKey)
-	userID, ok := val.(string)
-	if !ok || userID == "" {
-		return nil, "", c.Status(http.StatusUnauthorized).JSON(map[string]string{
-			"code":    "NOT_AUTHORIZED",
-			"message": "unauthorized",
-		})
-	}
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, userID)
-	return ctx, userID, nil
+// toResponse maps dp to its response, masking sensitive custom header values unless
+// reveal is set.
+func (h *DeviceProfileHandlerImpl) toResponse(dp entity.DeviceProfile, reveal bool) DeviceProfileResponse {
+	resp := mapToDeviceProfileResponse(dp)
+	if h.redact != nil && !reveal {
+		resp.CustomHeaders = h.redact.Headers(resp.CustomHeaders)
+	}
+	return resp
 }
 
 func parsePagination(pageStr, sizeStr string) (int, int, error) {
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
-		return 0, 0, errors.New("invalid page parameter")
+		return 0, 0, apperr.NewInvalidArgErr("invalid page parameter", err).
+			WithDetails(apperr.FieldViolation{Field: "page", Rule: "min", Param: "1", Message: "must be an integer of at least 1"})
 	}
 	pageSize, err := strconv.Atoi(sizeStr)
 	if err != nil || pageSize < 1 {
-		return 0, 0, errors.New("invalid page_size parameter")
+		return 0, 0, apperr.NewInvalidArgErr("invalid page_size parameter", err).
+			WithDetails(apperr.FieldViolation{Field: "page_size", Rule: "min", Param: "1", Message: "must be an integer of at least 1"})
 	}
 	return page, pageSize, nil
 }
 
-func badRequest(c fiber.Ctx, msg string) error {
-	return c.Status(http.StatusBadRequest).JSON(map[string]string{
-		"code":    "INVALID_ARGUMENT",
-		"message": msg,
-	})
+func parseReveal(s string) (bool, error) {
+	reveal, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, apperr.NewInvalidArgErr("invalid reveal parameter", err).
+			WithDetails(apperr.FieldViolation{Field: "reveal", Rule: "boolean", Message: "must be true or false"})
+	}
+	return reveal, nil
 }
 
 func isEmptyUpdateRequest(req DeviceProfileUpdateRequest) bool {
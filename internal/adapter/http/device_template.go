@@ -17,10 +17,14 @@ func NewDeviceTemplateHandlerImpl(log applog.AppLogger, svc port.DeviceTemplateS
 }
 
 func (h *DeviceTemplateHandlerImpl) List(c fiber.Ctx) error {
-	h.log.Trace("DeviceTemplateHandlerImpl: List called")
-	items, err := h.svc.RetrieveDeviceTemplates()
+	h.log.WithContext(c.Context()).Trace("DeviceTemplateHandlerImpl: List called")
+	items, err := h.svc.RetrieveDeviceTemplates(c.Context())
 	if err != nil {
 		return handleError(c, err)
 	}
-	return c.JSON(items)
+	resp := make([]DeviceTemplatesResponse, len(items))
+	for i, item := range items {
+		resp[i] = mapToDeviceTemplateResponse(item)
+	}
+	return c.JSON(resp)
 }
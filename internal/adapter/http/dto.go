@@ -54,3 +54,44 @@ type DeviceProfileUpdateRequest struct {
 	CountryCode   *string           `json:"country_code,omitempty" validate:"omitempty,len=2,uppercase"`
 	CustomHeaders map[string]string `json:"custom_headers,omitempty"`
 }
+
+type QuotaLimitsResponse struct {
+	MaxProfiles        int `json:"max_profiles"`
+	MaxCustomHeaders   int `json:"max_custom_headers"`
+	MaxHeaderValueSize int `json:"max_header_value_size"`
+	RequestsPerMinute  int `json:"requests_per_minute"`
+}
+
+type QuotaConsumptionResponse struct {
+	Profiles                int64 `json:"profiles"`
+	RequestsInCurrentMinute int   `json:"requests_in_current_minute"`
+}
+
+type UsageResponse struct {
+	Plan   string                   `json:"plan"`
+	Limits QuotaLimitsResponse      `json:"limits"`
+	Usage  QuotaConsumptionResponse `json:"usage"`
+}
+
+type AuditEventResponse struct {
+	ID           uuid.UUID      `json:"id"`
+	OccurredAt   time.Time      `json:"occurred_at"`
+	ActorID      *uuid.UUID     `json:"actor_id,omitempty"`
+	ActorName    *string        `json:"actor_name,omitempty"`
+	Action       string         `json:"action"`
+	ResourceType string         `json:"resource_type"`
+	ResourceID   *string        `json:"resource_id,omitempty"`
+	Outcome      string         `json:"outcome"`
+	SourceIP     *string        `json:"source_ip,omitempty"`
+	UserAgent    *string        `json:"user_agent,omitempty"`
+	RequestID    *string        `json:"request_id,omitempty"`
+	Details      map[string]any `json:"details,omitempty"`
+}
+
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=trace debug info warn warning error"`
+}
+
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
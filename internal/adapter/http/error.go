@@ -1,9 +1,13 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"zenrows-challenge/internal/core/entity"
 	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/problem"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -15,20 +19,66 @@ func handleError(c fiber.Ctx, err error) error {
 		nf  *apperr.NotFoundErr
 		ae  *apperr.AlreadyExistsErr
 		na  *apperr.NotAuthorizedErr
+		qe  *apperr.QuotaExceededErr
+		pd  *apperr.PermissionDeniedErr
+		to  *apperr.TimeoutErr
+		ce  *apperr.CanceledErr
 		in  *apperr.InternalErr
 	)
 	switch {
 	case errors.As(err, &inv):
-		return c.Status(http.StatusBadRequest).JSON(map[string]string{"code": inv.Code(), "message": inv.Message()})
+		return writeError(c, http.StatusBadRequest, inv)
 	case errors.As(err, &nf):
-		return c.Status(http.StatusNotFound).JSON(map[string]string{"code": nf.Code(), "message": nf.Message()})
+		return writeError(c, http.StatusNotFound, nf)
 	case errors.As(err, &ae):
-		return c.Status(http.StatusConflict).JSON(map[string]string{"code": ae.Code(), "message": ae.Message()})
+		return writeError(c, http.StatusConflict, ae)
 	case errors.As(err, &na):
-		return c.Status(http.StatusUnauthorized).JSON(map[string]string{"code": na.Code(), "message": na.Message()})
+		return writeError(c, http.StatusUnauthorized, na)
+	case errors.As(err, &pd):
+		return writeError(c, http.StatusForbidden, pd)
+	case errors.As(err, &qe):
+		status := http.StatusForbidden
+		if qe.Resource() == entity.QuotaRequestsPerMinute {
+			status = http.StatusTooManyRequests
+		}
+		return writeError(c, status, qe)
+	case errors.As(err, &to):
+		return writeError(c, http.StatusGatewayTimeout, to)
+	case errors.As(err, &ce):
+		return c.Status(problem.StatusClientClosedRequest).Send(nil)
 	case errors.As(err, &in):
 		fallthrough
 	default:
-		return c.Status(http.StatusInternalServerError).JSON(map[string]string{"code": "INTERNAL_ERROR", "message": "internal error"})
+		return problem.Write(c, http.StatusInternalServerError, "INTERNAL_ERROR", "internal error")
 	}
 }
+
+func writeError(c fiber.Ctx, status int, err apperr.BaseError) error {
+	return problem.Write(c, status, err.Code(), err.Message(), err.Details()...)
+}
+
+// badRequest writes an INVALID_ARGUMENT response with optional field violations.
+func badRequest(c fiber.Ctx, msg string, details ...apperr.FieldViolation) error {
+	return problem.Write(c, http.StatusBadRequest, "INVALID_ARGUMENT", msg, details...)
+}
+
+// invalidBody reports a body that could not be decoded, naming the field when the
+// failure is a type mismatch.
+func invalidBody(c fiber.Ctx, err error) error {
+	var ute *json.UnmarshalTypeError
+	if errors.As(err, &ute) && ute.Field != "" {
+		return badRequest(c, "invalid request body", apperr.FieldViolation{
+			Field:   ute.Field,
+			Rule:    "type",
+			Param:   ute.Type.String(),
+			Message: fmt.Sprintf("must be of type %s", ute.Type.String()),
+		})
+	}
+	return badRequest(c, "invalid request body")
+}
+
+// unauthorized writes the NOT_AUTHORIZED response used when a handler runs without an
+// authenticated user.
+func unauthorized(c fiber.Ctx) error {
+	return problem.Write(c, http.StatusUnauthorized, "NOT_AUTHORIZED", "unauthorized")
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/problem"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleError_TimeoutsAndCancellation(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		body   bool
+	}{
+		{name: "timeout", err: apperr.NewTimeoutErr("list device profiles", context.DeadlineExceeded), status: http.StatusGatewayTimeout, body: true},
+		{name: "cancellation", err: apperr.NewCanceledErr("list device profiles", context.Canceled), status: problem.StatusClientClosedRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c fiber.Ctx) error { return handleError(c, tc.err) })
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.body, len(body) > 0)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+)
+
+type LogLevelHandlerImpl struct {
+	log    applog.AppLogger
+	levels applog.LevelController
+	v      *validator.Validate
+}
+
+func NewLogLevelHandlerImpl(log applog.AppLogger, levels applog.LevelController, v *validator.Validate) *LogLevelHandlerImpl {
+	return &LogLevelHandlerImpl{log: log, levels: levels, v: v}
+}
+
+func (h *LogLevelHandlerImpl) GetLogLevel(c fiber.Ctx) error {
+	return c.JSON(LogLevelResponse{Level: h.levels.Level()})
+}
+
+func (h *LogLevelHandlerImpl) SetLogLevel(c fiber.Ctx) error {
+	var req LogLevelRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return invalidBody(c, err)
+	}
+	if err := h.v.Struct(req); err != nil {
+		return badRequest(c, "validation failed", validation.Violations(err)...)
+	}
+
+	previous := h.levels.Level()
+	if err := h.levels.SetLevel(req.Level); err != nil {
+		return handleError(c, err)
+	}
+	h.log.WithContext(c.Context()).Warn("log.level_changed", "from", previous, "to", h.levels.Level())
+	return c.JSON(LogLevelResponse{Level: h.levels.Level()})
+}
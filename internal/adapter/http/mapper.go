@@ -1,13 +1,33 @@
 package http
 
 import (
-	"fmt"
 	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/apperr"
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 )
 
+func mapToDeviceTemplateResponse(e entity.DeviceTemplate) DeviceTemplatesResponse {
+	headers := make(map[string]string)
+	for k, v := range e.DefaultHeaders {
+		if str, ok := v.(string); ok {
+			headers[k] = str
+		}
+	}
+	return DeviceTemplatesResponse{
+		ID:             e.ID,
+		Name:           e.Name,
+		DeviceType:     e.DeviceType,
+		Width:          e.Width,
+		Height:         e.Height,
+		UserAgent:      e.UserAgent,
+		CountryCode:    e.CountryCode,
+		DefaultHeaders: headers,
+		CreatedAt:      e.CreatedAt,
+	}
+}
+
 func mapToDeviceProfileResponse(e entity.DeviceProfile) DeviceProfileResponse {
 	headers := make(map[string]string)
 	for k, v := range e.CustomHeaders {
@@ -84,7 +104,8 @@ func mapDeviceProfileCreateRequestToEntity(req DeviceProfileCreateRequest, userI
 	if req.TemplateID != nil && *req.TemplateID != "" {
 		tid, err := uuid.Parse(*req.TemplateID)
 		if err != nil {
-			return nil, fmt.Errorf("invalid template_id")
+			return nil, apperr.NewInvalidArgErr("invalid template_id", err).
+				WithDetails(apperr.FieldViolation{Field: "template_id", Rule: "uuid", Message: "must be a valid UUID"})
 		}
 		dp.TemplateID = &tid
 	}
@@ -95,3 +116,36 @@ func mapDeviceProfileCreateRequestToEntity(req DeviceProfileCreateRequest, userI
 
 	return dp, nil
 }
+
+func mapToUsageResponse(u entity.QuotaUsage) UsageResponse {
+	return UsageResponse{
+		Plan: u.Plan,
+		Limits: QuotaLimitsResponse{
+			MaxProfiles:        u.Limits.MaxProfiles,
+			MaxCustomHeaders:   u.Limits.MaxCustomHeaders,
+			MaxHeaderValueSize: u.Limits.MaxHeaderValueSize,
+			RequestsPerMinute:  u.Limits.RequestsPerMinute,
+		},
+		Usage: QuotaConsumptionResponse{
+			Profiles:                u.Profiles,
+			RequestsInCurrentMinute: u.RequestsInCurrentMinute,
+		},
+	}
+}
+
+func mapToAuditEventResponse(e entity.AuditEvent) AuditEventResponse {
+	return AuditEventResponse{
+		ID:           e.ID,
+		OccurredAt:   e.OccurredAt,
+		ActorID:      e.ActorID,
+		ActorName:    e.ActorName,
+		Action:       e.Action,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Outcome:      e.Outcome,
+		SourceIP:     e.SourceIP,
+		UserAgent:    e.UserAgent,
+		RequestID:    e.RequestID,
+		Details:      e.Details,
+	}
+}
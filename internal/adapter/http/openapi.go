@@ -0,0 +1,57 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// openAPISpec is the OpenAPI 3.1 document of the API. The routes and the DTO schemas it
+// describes are checked against the code by TestOpenAPISpec.
+//
+//go:embed openapi/openapi.json
+var openAPISpec []byte
+
+// openAPIDocs renders openAPISpec with an inline script, so the page needs no network
+// access beyond the service and loads no third-party code.
+//
+//go:embed openapi/docs.html
+var openAPIDocs []byte
+
+// docsCSP only lets the docs page run its own script and fetch from the service.
+var docsCSP = "default-src 'none'; connect-src 'self'; style-src 'unsafe-inline'; script-src 'sha256-" +
+	inlineScriptHash(openAPIDocs) + "'"
+
+// inlineScriptHash returns the base64 SHA-256 of the content of the first script element
+// of page, as a Content-Security-Policy source expects it.
+func inlineScriptHash(page []byte) string {
+	_, script, _ := bytes.Cut(page, []byte("<script>"))
+	script, _, _ = bytes.Cut(script, []byte("</script>"))
+	sum := sha256.Sum256(script)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// OpenAPISpec returns the OpenAPI document of the API, to validate requests against.
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+type OpenAPIHandlerImpl struct{}
+
+func NewOpenAPIHandlerImpl() *OpenAPIHandlerImpl {
+	return &OpenAPIHandlerImpl{}
+}
+
+func (h *OpenAPIHandlerImpl) Spec(c fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(openAPISpec)
+}
+
+func (h *OpenAPIHandlerImpl) Docs(c fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	c.Set(fiber.HeaderContentSecurityPolicy, docsCSP)
+	return c.Send(openAPIDocs)
+}
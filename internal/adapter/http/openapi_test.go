@@ -0,0 +1,289 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/openapi"
+	"zenrows-challenge/internal/pkg/problem"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// specSchemas maps the component schemas of the spec to the types they describe.
+var specSchemas = map[string]reflect.Type{
+	"DeviceTemplate":             reflect.TypeOf(DeviceTemplatesResponse{}),
+	"DeviceProfile":              reflect.TypeOf(DeviceProfileResponse{}),
+	"DeviceProfileCreateRequest": reflect.TypeOf(DeviceProfileCreateRequest{}),
+	"DeviceProfileUpdateRequest": reflect.TypeOf(DeviceProfileUpdateRequest{}),
+	"Usage":                      reflect.TypeOf(UsageResponse{}),
+	"QuotaLimits":                reflect.TypeOf(QuotaLimitsResponse{}),
+	"QuotaConsumption":           reflect.TypeOf(QuotaConsumptionResponse{}),
+	"AuditEvent":                 reflect.TypeOf(AuditEventResponse{}),
+	"LogLevelRequest":            reflect.TypeOf(LogLevelRequest{}),
+	"LogLevelResponse":           reflect.TypeOf(LogLevelResponse{}),
+	"HealthReport":               reflect.TypeOf(infra.HealthReport{}),
+	"CheckResult":                reflect.TypeOf(infra.CheckResult{}),
+	"FieldViolation":             reflect.TypeOf(apperr.FieldViolation{}),
+	"Problem":                    reflect.TypeOf(problem.Details{}),
+	"LegacyError":                reflect.TypeOf(problem.LegacyError{}),
+}
+
+// stubHandlers implements every handler interface with a no-op.
+type stubHandlers struct{}
+
+func (stubHandlers) List(fiber.Ctx) error                       { return nil }
+func (stubHandlers) ListDeviceProfilesByUserID(fiber.Ctx) error { return nil }
+func (stubHandlers) GetDeviceProfile(fiber.Ctx) error           { return nil }
+func (stubHandlers) CreateDeviceProfile(fiber.Ctx) error        { return nil }
+func (stubHandlers) UpdateDeviceProfile(fiber.Ctx) error        { return nil }
+func (stubHandlers) DeleteDeviceProfile(fiber.Ctx) error        { return nil }
+func (stubHandlers) GetUsage(fiber.Ctx) error                   { return nil }
+func (stubHandlers) ListAuditEvents(fiber.Ctx) error            { return nil }
+func (stubHandlers) ListMyAuditEvents(fiber.Ctx) error          { return nil }
+func (stubHandlers) GetLogLevel(fiber.Ctx) error                { return nil }
+func (stubHandlers) SetLogLevel(fiber.Ctx) error                { return nil }
+
+func TestOpenAPISpec(t *testing.T) {
+	var spec struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+	_, err := openapi.Load(openAPISpec)
+	require.NoError(t, err, "the request validator cannot load the spec")
+
+	t.Run("documents every route", func(t *testing.T) {
+		next := func(c fiber.Ctx) error { return c.Next() }
+		stub := stubHandlers{}
+		app := fiber.New()
+		RegisterRoutes(app, Routes{
+			PublicLimit: next, Idempotency: next, Livez: next, Readyz: next,
+			DeviceTemplates: stub, DeviceProfiles: stub, Users: stub, Audit: stub,
+			OpenAPI: NewOpenAPIHandlerImpl(),
+			Admin:   &AdminRoutes{Metrics: next, MetricsGuard: next, LogLevel: stub, LogLevelGuard: next},
+		})
+
+		param := regexp.MustCompile(`:(\w+)`)
+		var registered []string
+		for _, r := range app.GetRoutes(true) {
+			if r.Method != fiber.MethodHead {
+				registered = append(registered, r.Method+" "+param.ReplaceAllString(r.Path, "{$1}"))
+			}
+		}
+		var documented []string
+		for path, ops := range spec.Paths {
+			for method := range ops {
+				documented = append(documented, strings.ToUpper(method)+" "+path)
+			}
+		}
+		assert.ElementsMatch(t, registered, documented)
+	})
+
+	t.Run("schemas match the DTOs", func(t *testing.T) {
+		for name, typ := range specSchemas {
+			documented, ok := spec.Components.Schemas[name]
+			if !assert.True(t, ok, "schema %s is missing from the spec", name) {
+				continue
+			}
+			want, err := schemaOf(typ)
+			require.NoError(t, err, name)
+			assert.Equal(t, roundTrip(t, want), stripAnnotations(documented), "schema %s drifted from %s", name, typ)
+		}
+		for name := range spec.Components.Schemas {
+			assert.Contains(t, specSchemas, name, "schema %s is not checked against a type", name)
+		}
+	})
+
+	t.Run("serves the spec and the docs page", func(t *testing.T) {
+		h := NewOpenAPIHandlerImpl()
+		app := fiber.New()
+		app.Get("/openapi.json", h.Spec)
+		app.Get("/docs", h.Docs)
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON)
+
+		resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/docs", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get(fiber.HeaderContentType), fiber.MIMETextHTML)
+		assert.Contains(t, resp.Header.Get(fiber.HeaderContentSecurityPolicy), "script-src 'sha256-")
+		page, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.NotRegexp(t, `(src|href)="(https?:)?//`, string(page), "the page must not load third-party resources")
+	})
+}
+
+// schemaOf derives the JSON schema of a DTO from its json and validate tags: fields
+// without omitempty are required, and validation rules become schema keywords.
+func schemaOf(t reflect.Type) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case reflect.TypeOf(uuid.UUID{}):
+		return map[string]any{"type": "string", "format": "uuid"}, nil
+	case reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice:
+		items, err := schemaRef(t.Elem())
+		return map[string]any{"type": "array", "items": items}, err
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return map[string]any{"type": "object"}, nil
+		}
+		values, err := schemaRef(t.Elem())
+		return map[string]any{"type": "object", "additionalProperties": values}, err
+	case reflect.Struct:
+		props := make(map[string]any)
+		var required []any
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			s, err := schemaRef(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if err := applyRules(s, f.Tag.Get("validate")); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			props[name] = s
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		s := map[string]any{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("no schema for %s", t)
+}
+
+// schemaRef refers to the component schema of t, if it has one.
+func schemaRef(t reflect.Type) (map[string]any, error) {
+	for name, typ := range specSchemas {
+		if typ == t {
+			return map[string]any{"$ref": "#/components/schemas/" + name}, nil
+		}
+	}
+	return schemaOf(t)
+}
+
+// applyRules adds the keywords of the validate rules to s. Unknown rules are errors, so
+// a new constraint cannot be added to a DTO without deciding how the spec shows it.
+func applyRules(s map[string]any, rules string) error {
+	if rules == "" {
+		return nil
+	}
+	isString := s["type"] == "string"
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch {
+		case name == "required" || name == "omitempty":
+		case name == "oneof":
+			var enum []any
+			for _, v := range strings.Fields(param) {
+				enum = append(enum, v)
+			}
+			s["enum"] = enum
+		case name == "uuid4":
+			s["format"] = "uuid"
+		case name == "uppercase":
+			s["pattern"] = "^[^a-z]*$"
+		case isString && (name == "min" || name == "max" || name == "len"):
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				return err
+			}
+			if name != "max" {
+				s["minLength"] = n
+			}
+			if name != "min" {
+				s["maxLength"] = n
+			}
+		case !isString && (name == "gt" || name == "gte" || name == "min" || name == "lt" || name == "lte" || name == "max"):
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				return err
+			}
+			keyword := map[string]string{
+				"gt": "exclusiveMinimum", "gte": "minimum", "min": "minimum",
+				"lt": "exclusiveMaximum", "lte": "maximum", "max": "maximum",
+			}[name]
+			s[keyword] = n
+		default:
+			return fmt.Errorf("no schema keyword for validate rule %q", rule)
+		}
+	}
+	return nil
+}
+
+// stripAnnotations removes the keywords that document rather than constrain a schema.
+func stripAnnotations(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			if k == "description" || k == "example" {
+				continue
+			}
+			out[k] = stripAnnotations(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = stripAnnotations(e)
+		}
+		return out
+	}
+	return v
+}
+
+// roundTrip converts v to its generic JSON form, as decoded from the spec.
+func roundTrip(t *testing.T, v any) any {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out any
+	require.NoError(t, json.Unmarshal(b, &out))
+	return out
+}
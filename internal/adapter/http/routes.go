@@ -0,0 +1,92 @@
+package http
+
+import (
+	"zenrows-challenge/internal/core/port"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Routes holds the handlers and middleware the API routes are built from.
+type Routes struct {
+	// PublicLimit rate limits the unauthenticated routes.
+	PublicLimit fiber.Handler
+	// AuthLimit, when not nil, rate limits the other routes by client IP before they are
+	// authenticated, so that guessing passwords is limited too.
+	AuthLimit fiber.Handler
+	// Authenticate attaches the principal to the request or rejects it, in order.
+	Authenticate []fiber.Handler
+	// APILimit, when not nil, rate limits authenticated requests per principal.
+	APILimit fiber.Handler
+	// Protected run after APILimit on the authenticated routes, in order.
+	Protected []fiber.Handler
+	// Idempotency handles Idempotency-Key on profile creation.
+	Idempotency fiber.Handler
+
+	Livez  fiber.Handler
+	Readyz fiber.Handler
+
+	DeviceTemplates port.DeviceTemplateHandler
+	DeviceProfiles  port.DeviceProfileHandler
+	Users           port.UserHandler
+	Audit           port.AuditHandler
+	OpenAPI         port.OpenAPIHandler
+
+	// Admin, when not nil, serves the admin routes on the API listener as well.
+	Admin *AdminRoutes
+}
+
+// AdminRoutes holds the handlers of the operational endpoints.
+type AdminRoutes struct {
+	// Metrics serves /metrics when not nil, behind MetricsGuard.
+	Metrics      fiber.Handler
+	MetricsGuard fiber.Handler
+	// LogLevel serves /admin/log-level when not nil, behind LogLevelGuard.
+	LogLevel      port.LogLevelHandler
+	LogLevelGuard fiber.Handler
+}
+
+// RegisterRoutes registers the API routes on r. The public and admin routes come first
+// so the middleware of the protected group does not apply to them. Every route must be
+// described in openapi/openapi.json.
+func RegisterRoutes(r fiber.Router, rt Routes) {
+	r.Get("/health", rt.PublicLimit, func(c fiber.Ctx) error { return c.SendString("UP!") })
+	r.Get("/livez", rt.Livez)
+	r.Get("/readyz", rt.Readyz)
+	r.Get("/openapi.json", rt.PublicLimit, rt.OpenAPI.Spec)
+	r.Get("/docs", rt.PublicLimit, rt.OpenAPI.Docs)
+	if rt.Admin != nil {
+		RegisterAdminRoutes(r, *rt.Admin)
+	}
+
+	var chain []fiber.Handler
+	if rt.AuthLimit != nil {
+		chain = append(chain, rt.AuthLimit)
+	}
+	chain = append(chain, rt.Authenticate...)
+	if rt.APILimit != nil {
+		chain = append(chain, rt.APILimit)
+	}
+	chain = append(chain, rt.Protected...)
+	protected := r.Group("/", chain...)
+	protected.Get("/users/me/usage", rt.Users.GetUsage)
+	protected.Get("/users/me/audit-events", rt.Audit.ListMyAuditEvents)
+	protected.Get("/audit-events", rt.Audit.ListAuditEvents)
+	protected.Get("/device-templates", rt.DeviceTemplates.List)
+	protected.Get("/device-profiles", rt.DeviceProfiles.ListDeviceProfilesByUserID)
+	protected.Post("/device-profiles", rt.Idempotency, rt.DeviceProfiles.CreateDeviceProfile)
+	protected.Get("/device-profiles/:id", rt.DeviceProfiles.GetDeviceProfile)
+	protected.Put("/device-profiles/:id", rt.DeviceProfiles.UpdateDeviceProfile)
+	protected.Delete("/device-profiles/:id", rt.DeviceProfiles.DeleteDeviceProfile)
+}
+
+// RegisterAdminRoutes registers the operational endpoints on r, which is the admin
+// listener when server.admin_port is set and the API server otherwise.
+func RegisterAdminRoutes(r fiber.Router, a AdminRoutes) {
+	if a.Metrics != nil {
+		r.Get("/metrics", a.MetricsGuard, a.Metrics)
+	}
+	if a.LogLevel != nil {
+		r.Get("/admin/log-level", a.LogLevelGuard, a.LogLevel.GetLogLevel)
+		r.Put("/admin/log-level", a.LogLevelGuard, a.LogLevel.SetLogLevel)
+	}
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/applog"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type UserHandlerImpl struct {
+	log   applog.AppLogger
+	quota port.QuotaService
+}
+
+func NewUserHandlerImpl(log applog.AppLogger, quota port.QuotaService) *UserHandlerImpl {
+	return &UserHandlerImpl{log: log, quota: quota}
+}
+
+func (h *UserHandlerImpl) GetUsage(c fiber.Ctx) error {
+	h.log.WithContext(c.Context()).Trace("UserHandlerImpl: GetUsage called")
+	ctx, _, err := principalContext(c)
+	if err != nil {
+		return err
+	}
+
+	usage, err := h.quota.GetUsage(ctx)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(mapToUsageResponse(*usage))
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"context"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type AuditRepoImpl struct {
+	log applog.AppLogger
+	db  *gorm.DB
+}
+
+func NewAuditRepoImpl(log applog.AppLogger, db *gorm.DB) *AuditRepoImpl {
+	return &AuditRepoImpl{log: log, db: db}
+}
+
+func (r *AuditRepoImpl) CreateAuditEvent(ctx context.Context, e *entity.AuditEvent) error {
+	if e.Details == nil {
+		e.Details = datatypes.JSONMap{}
+	}
+	r.log.WithContext(ctx).Trace("audit_event.create", "action", e.Action, "resource_type", e.ResourceType)
+	defer metrics.ObserveQuery("audit_event.create")()
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+func (r *AuditRepoImpl) ListAuditEvents(ctx context.Context, f entity.AuditEventFilter) ([]entity.AuditEvent, error) {
+	r.log.WithContext(ctx).Trace("audit_event.list", "page", f.Page, "page_size", f.PageSize)
+	defer metrics.ObserveQuery("audit_event.list")()
+	if f.PageSize > 100 {
+		f.PageSize = 100
+	}
+	q := r.db.WithContext(ctx).Model(&entity.AuditEvent{})
+	if f.ActorID != nil {
+		q = q.Where("actor_id = ?", *f.ActorID)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.ResourceType != "" {
+		q = q.Where("resource_type = ?", f.ResourceType)
+	}
+	if f.ResourceID != "" {
+		q = q.Where("resource_id = ?", f.ResourceID)
+	}
+	if f.Outcome != "" {
+		q = q.Where("outcome = ?", f.Outcome)
+	}
+	if f.From != nil {
+		q = q.Where("occurred_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("occurred_at < ?", *f.To)
+	}
+
+	var out []entity.AuditEvent
+	if err := q.Order("occurred_at DESC").
+		Limit(f.PageSize).
+		Offset((f.Page - 1) * f.PageSize).
+		Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -1,25 +1,38 @@
 package repo
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"zenrows-challenge/internal/core/entity"
 	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/envelope"
+	"zenrows-challenge/internal/pkg/metrics"
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
 )
 
 type DeviceProfileRepoImpl struct {
-	log applog.AppLogger
-	db  *gorm.DB
+	log  applog.AppLogger
+	db   *gorm.DB
+	keys *envelope.Keyring
 }
 
-func NewDeviceProfileRepoImpl(log applog.AppLogger, db *gorm.DB) *DeviceProfileRepoImpl {
-	return &DeviceProfileRepoImpl{log: log, db: db}
+// NewDeviceProfileRepoImpl constructs a DeviceProfileRepoImpl. With a keyring, custom
+// header values are encrypted before they are written and decrypted when read; with a
+// nil one they are stored in plaintext.
+func NewDeviceProfileRepoImpl(log applog.AppLogger, db *gorm.DB, keys *envelope.Keyring) *DeviceProfileRepoImpl {
+	return &DeviceProfileRepoImpl{log: log, db: db, keys: keys}
 }
 
-func (r *DeviceProfileRepoImpl) ListDeviceProfiles(userID string, page, pageSize int) ([]entity.DeviceProfile, error) {
-	r.log.Trace("device_profile.list", "user_id", userID)
+func (r *DeviceProfileRepoImpl) ListDeviceProfiles(ctx context.Context, userID string, page, pageSize int) ([]entity.DeviceProfile, error) {
+	r.log.WithContext(ctx).Trace("device_profile.list", "user_id", userID)
+	defer metrics.ObserveQuery("device_profile.list")()
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
@@ -30,36 +43,167 @@ func (r *DeviceProfileRepoImpl) ListDeviceProfiles(userID string, page, pageSize
 	}
 	offset := (page - 1) * pageSize
 	var out []entity.DeviceProfile
-	if err := r.db.Where("user_id = ?", uid).
+	if err := r.db.WithContext(ctx).Where("user_id = ?", uid).
 		Order("created_at DESC").
 		Limit(pageSize).
 		Offset(offset).
 		Find(&out).Error; err != nil {
 		return nil, err
 	}
+	for i := range out {
+		if err := r.decrypt(&out[i]); err != nil {
+			return nil, err
+		}
+	}
 	return out, nil
 }
 
-func (r *DeviceProfileRepoImpl) CreateDeviceProfile(dp *entity.DeviceProfile) error {
+func (r *DeviceProfileRepoImpl) GetDeviceProfile(ctx context.Context, userID, id string) (*entity.DeviceProfile, error) {
+	r.log.WithContext(ctx).Trace("device_profile.get", "id", id, "user_id", userID)
+	defer metrics.ObserveQuery("device_profile.get")()
+
+	pid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	var dp entity.DeviceProfile
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", pid, userID).First(&dp).Error; err != nil {
+		return nil, err
+	}
+	if err := r.decrypt(&dp); err != nil {
+		return nil, err
+	}
+	return &dp, nil
+}
+
+func (r *DeviceProfileRepoImpl) CreateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) error {
 	if dp.CustomHeaders == nil {
 		dp.CustomHeaders = datatypes.JSONMap{}
 	}
-	r.log.Trace("device_profile.create", "user_id", dp.UserID.String(), "name", dp.Name)
-	return r.db.Create(dp).Error
+	r.log.WithContext(ctx).Trace("device_profile.create", "user_id", dp.UserID.String(), "name", dp.Name)
+	defer metrics.ObserveQuery("device_profile.create")()
+
+	row, err := r.encrypt(dp)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Create(row).Error; err != nil {
+		return err
+	}
+	dp.ID, dp.CreatedAt, dp.UpdatedAt = row.ID, row.CreatedAt, row.UpdatedAt
+	return nil
 }
 
-func (r *DeviceProfileRepoImpl) UpdateDeviceProfile(dp *entity.DeviceProfile) error {
-	r.log.Trace("device_profile.update_selective", "id", dp.ID.String(), "user_id", dp.UserID.String())
-	return r.db.Model(&entity.DeviceProfile{}).
+func (r *DeviceProfileRepoImpl) UpdateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) error {
+	r.log.WithContext(ctx).Trace("device_profile.update_selective", "id", dp.ID.String(), "user_id", dp.UserID.String())
+	defer metrics.ObserveQuery("device_profile.update_selective")()
+
+	// Zero fields are left untouched, so the key columns only change with the headers.
+	row, err := r.encrypt(dp)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Model(&entity.DeviceProfile{}).
 		Where("id = ? AND user_id = ?", dp.ID, dp.UserID).
-		Updates(dp).Error
+		Updates(row).Error
 }
 
-func (r *DeviceProfileRepoImpl) DeleteDeviceProfile(userID, id string) error {
-	r.log.Trace("device_profile.delete", "id", id)
+func (r *DeviceProfileRepoImpl) DeleteDeviceProfile(ctx context.Context, userID, id string) error {
+	r.log.WithContext(ctx).Trace("device_profile.delete", "id", id)
+	defer metrics.ObserveQuery("device_profile.delete")()
 	pid, err := uuid.Parse(id)
 	if err != nil {
 		return err
 	}
-	return r.db.Where("id = ? AND user_id = ?", pid, userID).Delete(&entity.DeviceProfile{}).Error
+	return r.db.WithContext(ctx).Where("id = ? AND user_id = ?", pid, userID).Delete(&entity.DeviceProfile{}).Error
+}
+
+func (r *DeviceProfileRepoImpl) CountDeviceProfiles(ctx context.Context, userID string) (int64, error) {
+	r.log.WithContext(ctx).Trace("device_profile.count", "user_id", userID)
+	defer metrics.ObserveQuery("device_profile.count")()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, err
+	}
+	// The count decides on quota enforcement, so it must not lag behind on a replica.
+	var count int64
+	if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Model(&entity.DeviceProfile{}).Where("user_id = ?", uid).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ReencryptDeviceProfiles rewrites the custom headers of up to batchSize profiles not
+// stored under the active master key, plaintext ones included, and returns how many it
+// rewrote. Rows locked by other transactions are skipped, to be picked up by a later run.
+func (r *DeviceProfileRepoImpl) ReencryptDeviceProfiles(ctx context.Context, batchSize int) (int, error) {
+	r.log.WithContext(ctx).Trace("device_profile.reencrypt", "batch_size", batchSize)
+	defer metrics.ObserveQuery("device_profile.reencrypt")()
+	if r.keys == nil {
+		return 0, errors.New("encryption is not configured")
+	}
+
+	var n int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []entity.DeviceProfile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("encryption_key_id IS DISTINCT FROM ?", r.keys.ActiveKeyID()).
+			Order("id").
+			Limit(batchSize).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			if err := r.decrypt(&rows[i]); err != nil {
+				return fmt.Errorf("decrypt device profile %s: %w", rows[i].ID, err)
+			}
+			row, err := r.encrypt(&rows[i])
+			if err != nil {
+				return err
+			}
+			// UpdateColumns keeps updated_at, as the profile itself did not change.
+			if err := tx.Model(&entity.DeviceProfile{}).Where("id = ?", row.ID).UpdateColumns(map[string]any{
+				"custom_headers":    row.CustomHeaders,
+				"encryption_key_id": row.EncryptionKeyID,
+				"wrapped_data_key":  row.WrappedDataKey,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		n = len(rows)
+		return nil
+	})
+	return n, err
+}
+
+// encrypt returns a copy of dp with its custom header values encrypted under a new
+// data key, or dp itself when encryption is off or the headers are not being written.
+func (r *DeviceProfileRepoImpl) encrypt(dp *entity.DeviceProfile) (*entity.DeviceProfile, error) {
+	if r.keys == nil || dp.CustomHeaders == nil {
+		return dp, nil
+	}
+	headers, keyID, wrapped, err := r.keys.EncryptHeaders(dp.CustomHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt custom headers: %w", err)
+	}
+	row := *dp
+	row.CustomHeaders, row.EncryptionKeyID, row.WrappedDataKey = headers, &keyID, wrapped
+	return &row, nil
+}
+
+// decrypt replaces the custom header values of dp, as read from the database, by their
+// plaintext.
+func (r *DeviceProfileRepoImpl) decrypt(dp *entity.DeviceProfile) error {
+	if dp.EncryptionKeyID == nil {
+		return nil
+	}
+	if r.keys == nil {
+		return fmt.Errorf("device profile %s is encrypted but encryption is not configured", dp.ID)
+	}
+	headers, err := r.keys.DecryptHeaders(dp.CustomHeaders, dp.EncryptionKeyID, dp.WrappedDataKey)
+	if err != nil {
+		return err
+	}
+	dp.CustomHeaders = headers
+	return nil
 }
@@ -1,8 +1,11 @@
 package repo
 
 import (
+	"context"
+
 	"zenrows-challenge/internal/core/entity"
 	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -17,24 +20,60 @@ func NewDeviceTemplateRepoImpl(log applog.AppLogger, db *gorm.DB) *DeviceTemplat
 	return &DeviceTemplateRepoImpl{log: log, db: db}
 }
 
-func (r *DeviceTemplateRepoImpl) GetDeviceTemplates() ([]entity.DeviceTemplate, error) {
-	r.log.Trace("device_template.list")
+func (r *DeviceTemplateRepoImpl) GetDeviceTemplates(ctx context.Context) ([]entity.DeviceTemplate, error) {
+	r.log.WithContext(ctx).Trace("device_template.list")
+	defer metrics.ObserveQuery("device_template.list")()
 	var out []entity.DeviceTemplate
-	if err := r.db.Find(&out).Error; err != nil {
+	if err := r.db.WithContext(ctx).Find(&out).Error; err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (r *DeviceTemplateRepoImpl) GetDeviceTemplateByID(id *uuid.UUID) (*entity.DeviceTemplate, error) {
-	r.log.Trace("device_template.get", "id", id.String())
+func (r *DeviceTemplateRepoImpl) GetDeviceTemplateByID(ctx context.Context, id *uuid.UUID) (*entity.DeviceTemplate, error) {
+	r.log.WithContext(ctx).Trace("device_template.get", "id", id.String())
+	defer metrics.ObserveQuery("device_template.get")()
 	if id == nil {
 		return nil, gorm.ErrRecordNotFound
 	}
-	r.log.Trace("device_template.get", "id", id.String())
+	r.log.WithContext(ctx).Trace("device_template.get", "id", id.String())
 	var out entity.DeviceTemplate
-	if err := r.db.First(&out, "id = ?", *id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&out, "id = ?", *id).Error; err != nil {
 		return nil, err
 	}
 	return &out, nil
 }
+
+// UpsertDeviceTemplates creates the templates whose name is not taken yet and overwrites
+// the others, all in one transaction.
+func (r *DeviceTemplateRepoImpl) UpsertDeviceTemplates(ctx context.Context, ts []entity.DeviceTemplate) (created, updated int, err error) {
+	r.log.WithContext(ctx).Trace("device_template.upsert", "count", len(ts))
+	defer metrics.ObserveQuery("device_template.upsert")()
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range ts {
+			t := &ts[i]
+			var existing entity.DeviceTemplate
+			err := tx.Where("name = ?", t.Name).Order("created_at").Limit(1).Find(&existing).Error
+			if err != nil {
+				return err
+			}
+			if existing.ID == uuid.Nil {
+				if err := tx.Create(t).Error; err != nil {
+					return err
+				}
+				created++
+				continue
+			}
+			t.ID, t.CreatedAt = existing.ID, existing.CreatedAt
+			if err := tx.Select("*").Omit("created_at").Updates(t).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/envelope"
+	"zenrows-challenge/internal/pkg/metrics"
+
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepoImpl struct {
+	log  applog.AppLogger
+	db   *gorm.DB
+	keys *envelope.Keyring
+}
+
+// NewIdempotencyRepoImpl constructs an IdempotencyRepoImpl. With a keyring, stored
+// response bodies are encrypted, as they may echo custom header values; with a nil one
+// they are stored in plaintext.
+func NewIdempotencyRepoImpl(log applog.AppLogger, db *gorm.DB, keys *envelope.Keyring) *IdempotencyRepoImpl {
+	return &IdempotencyRepoImpl{log: log, db: db, keys: keys}
+}
+
+// ReserveIdempotencyKey inserts the key, replacing an expired record with the same scope
+// and key, or an in-progress one for the same request whose lease ran out, as when the
+// instance running it crashed. Concurrent reservations are serialised by the primary
+// key, so only one caller gets reserved == true.
+func (r *IdempotencyRepoImpl) ReserveIdempotencyKey(ctx context.Context, k *entity.IdempotencyKey) (*entity.IdempotencyKey, bool, error) {
+	r.log.WithContext(ctx).Trace("idempotency_key.reserve", "scope", k.Scope, "key", k.Key)
+	defer metrics.ObserveQuery("idempotency_key.reserve")()
+	res := r.db.WithContext(ctx).Exec(`INSERT INTO zenrows.idempotency_key AS k (scope, key, fingerprint, status, created_at, expires_at, locked_until)
+		VALUES (?, ?, ?, ?, now(), ?, ?)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status = EXCLUDED.status,
+			response_status = NULL,
+			response_body = NULL,
+			content_type = NULL,
+			encryption_key_id = NULL,
+			wrapped_data_key = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at,
+			locked_until = EXCLUDED.locked_until
+		WHERE k.expires_at < now()
+			OR (k.status = ? AND k.locked_until < now() AND k.fingerprint = EXCLUDED.fingerprint)`,
+		k.Scope, k.Key, k.Fingerprint, entity.IdempotencyInProgress, k.ExpiresAt, k.LockedUntil, entity.IdempotencyInProgress)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+
+	var out entity.IdempotencyKey
+	if err := r.db.WithContext(ctx).First(&out, "scope = ? AND key = ?", k.Scope, k.Key).Error; err != nil {
+		return nil, false, err
+	}
+	if err := r.decrypt(&out); err != nil {
+		return nil, false, err
+	}
+	return &out, res.RowsAffected == 1, nil
+}
+
+func (r *IdempotencyRepoImpl) CompleteIdempotencyKey(ctx context.Context, k *entity.IdempotencyKey) error {
+	r.log.WithContext(ctx).Trace("idempotency_key.complete", "scope", k.Scope, "key", k.Key)
+	defer metrics.ObserveQuery("idempotency_key.complete")()
+	body, keyID, wrapped := k.ResponseBody, (*string)(nil), []byte(nil)
+	if r.keys != nil {
+		ct, id, w, err := r.keys.Encrypt(k.ResponseBody, responseAAD(k.Scope, k.Key))
+		if err != nil {
+			return fmt.Errorf("encrypt idempotent response: %w", err)
+		}
+		body, keyID, wrapped = ct, &id, w
+	}
+	return r.db.WithContext(ctx).Model(&entity.IdempotencyKey{}).
+		Where("scope = ? AND key = ?", k.Scope, k.Key).
+		Updates(map[string]any{
+			"status":            entity.IdempotencyCompleted,
+			"response_status":   k.ResponseStatus,
+			"response_body":     body,
+			"content_type":      k.ContentType,
+			"encryption_key_id": keyID,
+			"wrapped_data_key":  wrapped,
+			"locked_until":      nil,
+		}).Error
+}
+
+func (r *IdempotencyRepoImpl) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	r.log.WithContext(ctx).Trace("idempotency_key.release", "scope", scope, "key", key)
+	defer metrics.ObserveQuery("idempotency_key.release")()
+	return r.db.WithContext(ctx).Where("scope = ? AND key = ? AND status = ?", scope, key, entity.IdempotencyInProgress).
+		Delete(&entity.IdempotencyKey{}).Error
+}
+
+// PurgeExpiredIdempotencyKeys deletes the records no longer replayed and returns how
+// many it deleted.
+func (r *IdempotencyRepoImpl) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	r.log.WithContext(ctx).Trace("idempotency_key.purge")
+	defer metrics.ObserveQuery("idempotency_key.purge")()
+	res := r.db.WithContext(ctx).Exec(`DELETE FROM zenrows.idempotency_key WHERE expires_at < now()`)
+	return res.RowsAffected, res.Error
+}
+
+// decrypt replaces the response body of k, as read from the database, by its plaintext.
+func (r *IdempotencyRepoImpl) decrypt(k *entity.IdempotencyKey) error {
+	if k.EncryptionKeyID == nil {
+		return nil
+	}
+	if r.keys == nil {
+		return fmt.Errorf("idempotency key %s is encrypted but encryption is not configured", k.Key)
+	}
+	body, err := r.keys.Decrypt(k.ResponseBody, *k.EncryptionKeyID, k.WrappedDataKey, responseAAD(k.Scope, k.Key))
+	if err != nil {
+		return fmt.Errorf("decrypt idempotent response: %w", err)
+	}
+	k.ResponseBody = body
+	return nil
+}
+
+// responseAAD binds a stored response to its scope and key, so it cannot be replayed
+// to another user by copying it to their row.
+func responseAAD(scope, key string) []byte {
+	return []byte(scope + "\x00" + key)
+}
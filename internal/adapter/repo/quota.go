@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type QuotaRepoImpl struct {
+	log applog.AppLogger
+	db  *gorm.DB
+}
+
+func NewQuotaRepoImpl(log applog.AppLogger, db *gorm.DB) *QuotaRepoImpl {
+	return &QuotaRepoImpl{log: log, db: db}
+}
+
+// userQuotaRow is a user's plan joined with its overrides, which are all NULL when the
+// user has none.
+type userQuotaRow struct {
+	Plan               string
+	OverrideUserID     *uuid.UUID
+	MaxProfiles        *int
+	MaxCustomHeaders   *int
+	MaxHeaderValueSize *int
+	RequestsPerMinute  *int
+}
+
+func (r *QuotaRepoImpl) GetUserQuota(ctx context.Context, userID string) (string, *entity.UserQuota, error) {
+	r.log.WithContext(ctx).Trace("quota.get_user_quota", "user_id", userID)
+	defer metrics.ObserveQuery("quota.get_user_quota")()
+	return r.userQuota(ctx, userID, false)
+}
+
+func (r *QuotaRepoImpl) LockUserQuota(ctx context.Context, userID string) (string, *entity.UserQuota, error) {
+	r.log.WithContext(ctx).Trace("quota.lock_user_quota", "user_id", userID)
+	defer metrics.ObserveQuery("quota.lock_user_quota")()
+	return r.userQuota(ctx, userID, true)
+}
+
+func (r *QuotaRepoImpl) userQuota(ctx context.Context, userID string, lock bool) (string, *entity.UserQuota, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	q := r.db.WithContext(ctx).Table(`zenrows."user" AS u`).
+		Select("u.plan, q.user_id AS override_user_id, q.max_profiles, q.max_custom_headers, q.max_header_value_size, q.requests_per_minute").
+		Joins("LEFT JOIN zenrows.user_quota AS q ON q.user_id = u.id").
+		Where("u.id = ?", uid)
+	if lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "u"}})
+	}
+	var row userQuotaRow
+	if err := q.Take(&row).Error; err != nil {
+		return "", nil, err
+	}
+	if row.OverrideUserID == nil {
+		return row.Plan, nil, nil
+	}
+	return row.Plan, &entity.UserQuota{
+		UserID:             *row.OverrideUserID,
+		MaxProfiles:        row.MaxProfiles,
+		MaxCustomHeaders:   row.MaxCustomHeaders,
+		MaxHeaderValueSize: row.MaxHeaderValueSize,
+		RequestsPerMinute:  row.RequestsPerMinute,
+	}, nil
+}
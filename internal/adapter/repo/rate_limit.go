@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
+	"zenrows-challenge/internal/pkg/ratelimit"
+
+	"gorm.io/gorm"
+)
+
+// RateLimitRepoImpl is a ratelimit.Store backed by Postgres so several service
+// instances share the same buckets. Row locks serialise concurrent takes per key.
+type RateLimitRepoImpl struct {
+	log applog.AppLogger
+	db  *gorm.DB
+}
+
+func NewRateLimitRepoImpl(log applog.AppLogger, db *gorm.DB) *RateLimitRepoImpl {
+	return &RateLimitRepoImpl{log: log, db: db}
+}
+
+type rateLimitRow struct {
+	Tokens    float64
+	UpdatedAt time.Time
+	Now       time.Time
+}
+
+func (r *RateLimitRepoImpl) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	r.log.WithContext(ctx).Trace("rate_limit.take", "key", key)
+	defer metrics.ObserveQuery("rate_limit.take")()
+	var res ratelimit.Result
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The no-op update locks an existing bucket, so creating or locking it is one statement.
+		var row rateLimitRow
+		if err := tx.Raw(`INSERT INTO zenrows.rate_limit_bucket AS b (key, tokens, updated_at, full_at)
+			VALUES (?, ?, now(), now()) ON CONFLICT (key) DO UPDATE SET tokens = b.tokens
+			RETURNING tokens, updated_at, now() AS now`, key, limit.Burst).Scan(&row).Error; err != nil {
+			return err
+		}
+
+		var b ratelimit.Bucket
+		b, res = ratelimit.Take(ratelimit.Bucket{Tokens: row.Tokens, UpdatedAt: row.UpdatedAt}, limit, row.Now)
+		return tx.Exec(`UPDATE zenrows.rate_limit_bucket SET tokens = ?, updated_at = ?, full_at = ? WHERE key = ?`,
+			b.Tokens, b.UpdatedAt, b.UpdatedAt.Add(res.ResetAfter), key).Error
+	})
+	return res, err
+}
+
+// PruneRateLimitBuckets deletes the buckets that are full by now, since they are
+// equivalent to missing ones, and returns how many it deleted.
+func (r *RateLimitRepoImpl) PruneRateLimitBuckets(ctx context.Context) (int64, error) {
+	r.log.WithContext(ctx).Trace("rate_limit.prune")
+	defer metrics.ObserveQuery("rate_limit.prune")()
+	res := r.db.WithContext(ctx).Exec(`DELETE FROM zenrows.rate_limit_bucket WHERE full_at <= now()`)
+	return res.RowsAffected, res.Error
+}
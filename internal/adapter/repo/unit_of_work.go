@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"context"
+
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/envelope"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWorkImpl runs repository calls within a single GORM transaction.
+type UnitOfWorkImpl struct {
+	log  applog.AppLogger
+	db   *gorm.DB
+	keys *envelope.Keyring
+}
+
+// NewUnitOfWorkImpl constructs a UnitOfWorkImpl; keys is passed on to the device profile
+// repository, see NewDeviceProfileRepoImpl.
+func NewUnitOfWorkImpl(log applog.AppLogger, db *gorm.DB, keys *envelope.Keyring) *UnitOfWorkImpl {
+	return &UnitOfWorkImpl{log: log, db: db, keys: keys}
+}
+
+func (u *UnitOfWorkImpl) Do(ctx context.Context, fn func(repos port.TxRepos) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(txRepos{log: u.log, tx: tx, keys: u.keys})
+	})
+}
+
+// txRepos builds repositories sharing the transaction tx.
+type txRepos struct {
+	log  applog.AppLogger
+	tx   *gorm.DB
+	keys *envelope.Keyring
+}
+
+func (r txRepos) DeviceProfiles() port.DeviceProfileRepo {
+	return NewDeviceProfileRepoImpl(r.log, r.tx, r.keys)
+}
+
+func (r txRepos) AuditEvents() port.AuditRepo {
+	return NewAuditRepoImpl(r.log, r.tx)
+}
+
+func (r txRepos) Users() port.UserRepo {
+	return NewUserRepoImpl(r.log, r.tx)
+}
+
+func (r txRepos) DeviceTemplates() port.DeviceTemplateRepo {
+	return NewDeviceTemplateRepoImpl(r.log, r.tx)
+}
+
+func (r txRepos) Quotas() port.QuotaRepo {
+	return NewQuotaRepoImpl(r.log, r.tx)
+}
@@ -1,11 +1,15 @@
 package repo
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"zenrows-challenge/internal/core/entity"
 	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -18,11 +22,12 @@ func NewUserRepoImpl(log applog.AppLogger, db *gorm.DB) *UserRepoImpl {
 	return &UserRepoImpl{log: log, db: db}
 }
 
-func (r *UserRepoImpl) RetrieveCredentials(u entity.User) (string, string, error) {
-	r.log.Trace("user.retrieve_credentials", "username", u.Username)
+func (r *UserRepoImpl) RetrieveCredentials(ctx context.Context, u entity.User) (string, string, error) {
+	r.log.WithContext(ctx).Trace("user.retrieve_credentials", "username", u.Username)
+	defer metrics.ObserveQuery("user.retrieve_credentials")()
 	var found entity.User
 
-	err := r.db.Where("username = ?", u.Username).First(&found).Error
+	err := r.db.WithContext(ctx).Where("username = ? AND disabled_at IS NULL", u.Username).First(&found).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return "", "", nil
@@ -31,3 +36,69 @@ func (r *UserRepoImpl) RetrieveCredentials(u entity.User) (string, string, error
 	}
 	return found.ID.String(), found.PasswordHash, nil
 }
+
+func (r *UserRepoImpl) GetUserByID(ctx context.Context, id string) (*entity.User, error) {
+	r.log.WithContext(ctx).Trace("user.get", "id", id)
+	defer metrics.ObserveQuery("user.get")()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	var out entity.User
+	if err := r.db.WithContext(ctx).First(&out, "id = ?", uid).Error; err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *UserRepoImpl) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	r.log.WithContext(ctx).Trace("user.get_by_username", "username", username)
+	defer metrics.ObserveQuery("user.get_by_username")()
+	var out entity.User
+	if err := r.db.WithContext(ctx).First(&out, "username = ?", username).Error; err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *UserRepoImpl) ListUsers(ctx context.Context) ([]entity.User, error) {
+	r.log.WithContext(ctx).Trace("user.list")
+	defer metrics.ObserveQuery("user.list")()
+	var out []entity.User
+	if err := r.db.WithContext(ctx).Order("username").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *UserRepoImpl) CreateUser(ctx context.Context, u *entity.User) error {
+	r.log.WithContext(ctx).Trace("user.create", "username", u.Username)
+	defer metrics.ObserveQuery("user.create")()
+	return r.db.WithContext(ctx).Create(u).Error
+}
+
+func (r *UserRepoImpl) UpdatePasswordHash(ctx context.Context, id uuid.UUID, hash string) error {
+	r.log.WithContext(ctx).Trace("user.update_password", "id", id.String())
+	defer metrics.ObserveQuery("user.update_password")()
+	res := r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).Update("password_hash", hash)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepoImpl) DisableUser(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.log.WithContext(ctx).Trace("user.disable", "id", id.String())
+	defer metrics.ObserveQuery("user.disable")()
+	res := r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ? AND disabled_at IS NULL", id).Update("disabled_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Audit event actions.
+const (
+	AuditActionDeviceProfileCreate = "device_profile.create"
+	AuditActionDeviceProfileUpdate = "device_profile.update"
+	AuditActionDeviceProfileDelete = "device_profile.delete"
+	AuditActionAuthFailure         = "auth.failure"
+	AuditActionUserCreate          = "user.create"
+	AuditActionUserPasswordChange  = "user.password_change"
+	AuditActionUserDisable         = "user.disable"
+	AuditActionTemplateImport      = "device_template.import"
+)
+
+// Audit resource types.
+const (
+	AuditResourceDeviceProfile  = "device_profile"
+	AuditResourceUser           = "user"
+	AuditResourceDeviceTemplate = "device_template"
+)
+
+// Audit event outcomes.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent records who performed an action on which resource, from where and with which outcome.
+type AuditEvent struct {
+	ID           uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OccurredAt   time.Time         `gorm:"autoCreateTime" json:"occurred_at"`
+	ActorID      *uuid.UUID        `gorm:"type:uuid" json:"actor_id"`
+	ActorName    *string           `gorm:"type:text" json:"actor_name"`
+	Action       string            `gorm:"type:text;not null" json:"action"`
+	ResourceType string            `gorm:"type:text;not null" json:"resource_type"`
+	ResourceID   *string           `gorm:"type:text" json:"resource_id"`
+	Outcome      string            `gorm:"type:text;not null" json:"outcome"`
+	SourceIP     *string           `gorm:"type:text" json:"source_ip"`
+	UserAgent    *string           `gorm:"type:text" json:"user_agent"`
+	RequestID    *string           `gorm:"type:text" json:"request_id"`
+	Details      datatypes.JSONMap `gorm:"type:jsonb" json:"details"`
+}
+
+func (AuditEvent) TableName() string { return "zenrows.audit_event" }
+
+// AuditEventFilter narrows an audit event listing. Zero values are ignored.
+type AuditEventFilter struct {
+	ActorID      *uuid.UUID
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Outcome      string
+	From         *time.Time
+	To           *time.Time
+	Page         int
+	PageSize     int
+}
@@ -20,6 +20,11 @@ type DeviceProfile struct {
 	CustomHeaders datatypes.JSONMap `gorm:"type:jsonb" json:"custom_headers"`
 	CreatedAt     time.Time         `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// EncryptionKeyID names the master key wrapping WrappedDataKey, the key encrypting the
+	// stored custom header values. Both are nil for rows stored in plaintext.
+	EncryptionKeyID *string `gorm:"type:text" json:"-"`
+	WrappedDataKey  []byte  `gorm:"type:bytea" json:"-"`
 }
 
 func (DeviceProfile) TableName() string { return "zenrows.device_profile" }
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// Idempotency key states.
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyKey records a client supplied Idempotency-Key, the fingerprint of the
+// request that first used it and, once processed, the response to replay. While in
+// progress, the reservation is held until LockedUntil; a retry may take it over after.
+type IdempotencyKey struct {
+	Scope           string     `gorm:"type:text;primaryKey" json:"scope"`
+	Key             string     `gorm:"type:text;primaryKey" json:"key"`
+	Fingerprint     string     `gorm:"type:text;not null" json:"fingerprint"`
+	Status          string     `gorm:"type:text;not null" json:"status"`
+	ResponseStatus  *int       `json:"response_status"`
+	ResponseBody    []byte     `gorm:"type:bytea" json:"response_body"`
+	ContentType     *string    `gorm:"type:text" json:"content_type"`
+	EncryptionKeyID *string    `gorm:"type:text" json:"-"`
+	WrappedDataKey  []byte     `gorm:"type:bytea" json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	LockedUntil     *time.Time `json:"locked_until"`
+}
+
+func (IdempotencyKey) TableName() string { return "zenrows.idempotency_key" }
@@ -0,0 +1,38 @@
+package entity
+
+import "github.com/google/uuid"
+
+// Quota resource names used when reporting which limit was exceeded.
+const (
+	QuotaMaxProfiles        = "max_profiles"
+	QuotaMaxCustomHeaders   = "max_custom_headers"
+	QuotaMaxHeaderValueSize = "max_header_value_size"
+	QuotaRequestsPerMinute  = "requests_per_minute"
+)
+
+// Quota holds the limits applied to a user. A zero or negative value means unlimited.
+type Quota struct {
+	MaxProfiles        int `json:"max_profiles"`
+	MaxCustomHeaders   int `json:"max_custom_headers"`
+	MaxHeaderValueSize int `json:"max_header_value_size"`
+	RequestsPerMinute  int `json:"requests_per_minute"`
+}
+
+// UserQuota stores per-user overrides of the plan limits. Nil fields fall back to the plan.
+type UserQuota struct {
+	UserID             uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	MaxProfiles        *int      `json:"max_profiles"`
+	MaxCustomHeaders   *int      `json:"max_custom_headers"`
+	MaxHeaderValueSize *int      `json:"max_header_value_size"`
+	RequestsPerMinute  *int      `json:"requests_per_minute"`
+}
+
+func (UserQuota) TableName() string { return "zenrows.user_quota" }
+
+// QuotaUsage reports the current consumption of a user against its limits.
+type QuotaUsage struct {
+	Plan                    string
+	Limits                  Quota
+	Profiles                int64
+	RequestsInCurrentMinute int
+}
@@ -6,11 +6,21 @@ import (
     "github.com/google/uuid"
 )
 
+// User roles.
+const (
+    UserRoleUser  = "user"
+    UserRoleAdmin = "admin"
+)
+
 type User struct {
     ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
     Username     string    `gorm:"type:text;not null;unique" json:"username" validate:"required,min=3,max=64"`
     PasswordHash string    `gorm:"type:text;not null" json:"password_hash" validate:"required,min=20"`
+    Plan         string    `gorm:"type:text;not null;default:free" json:"plan"`
+    Role         string    `gorm:"type:text;not null;default:user" json:"role"`
     CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+    // DisabledAt is set once the user is disabled; disabled users cannot authenticate.
+    DisabledAt *time.Time `json:"disabled_at"`
 }
 
 func (User) TableName() string { return "zenrows.user" }
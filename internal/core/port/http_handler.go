@@ -12,6 +12,8 @@ type DeviceTemplateHandler interface {
 type DeviceProfileHandler interface {
 	// ListDeviceProfilesByUserID returns profiles owned by the authenticated user.
 	ListDeviceProfilesByUserID(c fiber.Ctx) error
+	// GetDeviceProfile returns a single profile owned by the authenticated user.
+	GetDeviceProfile(c fiber.Ctx) error
 	// CreateDeviceProfile persists a new device profile.
 	CreateDeviceProfile(c fiber.Ctx) error
 	// UpdateDeviceProfile modifies an existing device profile.
@@ -19,3 +21,33 @@ type DeviceProfileHandler interface {
 	// DeleteDeviceProfile removes a device profile.
 	DeleteDeviceProfile(c fiber.Ctx) error
 }
+
+// UserHandler defines the HTTP handlers for the authenticated user's account.
+type UserHandler interface {
+	// GetUsage returns the user's quota consumption against its limits.
+	GetUsage(c fiber.Ctx) error
+}
+
+// AuditHandler defines the HTTP handlers for audit events.
+type AuditHandler interface {
+	// ListAuditEvents returns events across all users for administrators.
+	ListAuditEvents(c fiber.Ctx) error
+	// ListMyAuditEvents returns events performed by the authenticated user.
+	ListMyAuditEvents(c fiber.Ctx) error
+}
+
+// LogLevelHandler defines the admin HTTP handlers for the runtime log level.
+type LogLevelHandler interface {
+	// GetLogLevel returns the current minimum log level.
+	GetLogLevel(c fiber.Ctx) error
+	// SetLogLevel changes the minimum log level until the next restart.
+	SetLogLevel(c fiber.Ctx) error
+}
+
+// OpenAPIHandler defines the HTTP handlers documenting the API.
+type OpenAPIHandler interface {
+	// Spec returns the OpenAPI document.
+	Spec(c fiber.Ctx) error
+	// Docs returns an HTML page rendering the OpenAPI document.
+	Docs(c fiber.Ctx) error
+}
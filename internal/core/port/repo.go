@@ -1,33 +1,105 @@
 package port
 
 import (
+	"context"
+	"time"
+
 	"zenrows-challenge/internal/core/entity"
 
 	"github.com/google/uuid"
 )
 
-// UserRepo exposes persistence operations for authenticating users.
+// UserRepo exposes persistence operations for authenticating and managing users.
 type UserRepo interface {
-	// RetrieveCredentials returns the existing password hash for the provided user.
-	RetrieveCredentials(u entity.User) (string, string, error)
+	// RetrieveCredentials returns the existing password hash for the provided user, or
+	// empty values when the user does not exist or is disabled.
+	RetrieveCredentials(ctx context.Context, u entity.User) (string, string, error)
+	// GetUserByID retrieves a user by its identifier.
+	GetUserByID(ctx context.Context, id string) (*entity.User, error)
+	// GetUserByUsername retrieves a user, disabled or not, by its username.
+	GetUserByUsername(ctx context.Context, username string) (*entity.User, error)
+	// ListUsers returns every user ordered by username.
+	ListUsers(ctx context.Context) ([]entity.User, error)
+	// CreateUser persists a new user.
+	CreateUser(ctx context.Context, u *entity.User) error
+	// UpdatePasswordHash replaces the password hash of the user.
+	UpdatePasswordHash(ctx context.Context, id uuid.UUID, hash string) error
+	// DisableUser marks an enabled user as disabled at the given time.
+	DisableUser(ctx context.Context, id uuid.UUID, at time.Time) error
 }
 
 // DeviceTemplateRepo exposes queries for shared device templates.
 type DeviceTemplateRepo interface {
 	// GetDeviceTemplates returns every available device template.
-	GetDeviceTemplates() ([]entity.DeviceTemplate, error)
+	GetDeviceTemplates(ctx context.Context) ([]entity.DeviceTemplate, error)
 	// GetDeviceTemplateByID retrieves a template by its identifier.
-	GetDeviceTemplateByID(id *uuid.UUID) (*entity.DeviceTemplate, error)
+	GetDeviceTemplateByID(ctx context.Context, id *uuid.UUID) (*entity.DeviceTemplate, error)
+	// UpsertDeviceTemplates creates or, matching by name, overwrites the templates atomically.
+	UpsertDeviceTemplates(ctx context.Context, ts []entity.DeviceTemplate) (created, updated int, err error)
 }
 
 // DeviceProfileRepo exposes CRUD operations for user device profiles.
 type DeviceProfileRepo interface {
 	// ListDeviceProfiles returns the paginated profiles for a given user.
-	ListDeviceProfiles(userID string, page, pageSize int) ([]entity.DeviceProfile, error)
+	ListDeviceProfiles(ctx context.Context, userID string, page, pageSize int) ([]entity.DeviceProfile, error)
+	// GetDeviceProfile returns a profile belonging to the supplied user.
+	GetDeviceProfile(ctx context.Context, userID, id string) (*entity.DeviceProfile, error)
 	// CreateDeviceProfile persists a new profile.
-	CreateDeviceProfile(dp *entity.DeviceProfile) error
+	CreateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) error
 	// UpdateDeviceProfile modifies an existing profile.
-	UpdateDeviceProfile(dp *entity.DeviceProfile) error
+	UpdateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) error
 	// DeleteDeviceProfile removes a profile belonging to the supplied user.
-	DeleteDeviceProfile(userID, id string) error
+	DeleteDeviceProfile(ctx context.Context, userID, id string) error
+	// CountDeviceProfiles returns how many profiles the supplied user owns.
+	CountDeviceProfiles(ctx context.Context, userID string) (int64, error)
+}
+
+// QuotaRepo exposes the persisted plan and quota overrides of users.
+type QuotaRepo interface {
+	// GetUserQuota returns the plan name assigned to the user along with the per-user
+	// overrides, or nil overrides when none are stored.
+	GetUserQuota(ctx context.Context, userID string) (string, *entity.UserQuota, error)
+	// LockUserQuota is GetUserQuota, also locking the user until the transaction ends so
+	// that quota checks of concurrent requests of the user run one after the other.
+	LockUserQuota(ctx context.Context, userID string) (string, *entity.UserQuota, error)
+}
+
+// IdempotencyRepo stores Idempotency-Key reservations and the responses to replay.
+type IdempotencyRepo interface {
+	// ReserveIdempotencyKey stores k as in progress unless an unexpired record exists for the
+	// same scope and key, other than an in-progress one for the same fingerprint whose
+	// LockedUntil has passed. It returns the stored record and whether k was reserved.
+	ReserveIdempotencyKey(ctx context.Context, k *entity.IdempotencyKey) (*entity.IdempotencyKey, bool, error)
+	// CompleteIdempotencyKey saves the response of a reserved key.
+	CompleteIdempotencyKey(ctx context.Context, k *entity.IdempotencyKey) error
+	// ReleaseIdempotencyKey drops an in-progress reservation so the request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, scope, key string) error
+}
+
+// AuditRepo persists and queries audit events.
+type AuditRepo interface {
+	// CreateAuditEvent stores a new audit event.
+	CreateAuditEvent(ctx context.Context, e *entity.AuditEvent) error
+	// ListAuditEvents returns the paginated events matching the filter, newest first.
+	ListAuditEvents(ctx context.Context, f entity.AuditEventFilter) ([]entity.AuditEvent, error)
+}
+
+// TxRepos gives access to repositories bound to a single database transaction.
+type TxRepos interface {
+	// DeviceProfiles returns the device profile repository of the transaction.
+	DeviceProfiles() DeviceProfileRepo
+	// AuditEvents returns the audit repository of the transaction.
+	AuditEvents() AuditRepo
+	// Users returns the user repository of the transaction.
+	Users() UserRepo
+	// DeviceTemplates returns the device template repository of the transaction.
+	DeviceTemplates() DeviceTemplateRepo
+	// Quotas returns the quota repository of the transaction.
+	Quotas() QuotaRepo
+}
+
+// UnitOfWork runs several repository calls atomically.
+type UnitOfWork interface {
+	// Do runs fn within a transaction bound to ctx, committing when fn returns nil and rolling back otherwise.
+	Do(ctx context.Context, fn func(repos TxRepos) error) error
 }
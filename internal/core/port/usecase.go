@@ -3,24 +3,50 @@ package port
 import (
 	"context"
 	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/redact"
+
+	"gorm.io/datatypes"
 )
 
 // AuthenticationService exposes the business logic for verifying user credentials.
 type AuthenticationService interface {
 	// CheckCredentials returns the user ID when the supplied username and password are valid.
-	CheckCredentials(username string, password string) (string, error)
+	CheckCredentials(ctx context.Context, username string, password redact.Secret) (string, error)
+	// CheckCertificateUser returns the user ID of the active user named username, for
+	// callers that presented a verified client certificate mapped to that name.
+	CheckCertificateUser(ctx context.Context, username string) (string, error)
+	// RecordAuthFailure audits a rejected authentication attempt.
+	RecordAuthFailure(ctx context.Context, username string, reason string)
+}
+
+// UserAdminService exposes the operator use cases for managing user accounts.
+type UserAdminService interface {
+	// CreateUser validates u and stores it with the bcrypt hash of password.
+	CreateUser(ctx context.Context, u *entity.User, password redact.Secret) error
+	// ChangePassword replaces the password of the named user.
+	ChangePassword(ctx context.Context, username string, password redact.Secret) error
+	// DisableUser prevents the named user from authenticating from now on. Disabling a
+	// disabled user is a no-op.
+	DisableUser(ctx context.Context, username string) error
+	// ListUsers returns every user, disabled ones included.
+	ListUsers(ctx context.Context) ([]entity.User, error)
 }
 
 // DeviceTemplateService exposes the use cases for shared device templates.
 type DeviceTemplateService interface {
 	// RetrieveDeviceTemplates returns every available device template.
-	RetrieveDeviceTemplates() ([]entity.DeviceTemplate, error)
+	RetrieveDeviceTemplates(ctx context.Context) ([]entity.DeviceTemplate, error)
+	// ImportDeviceTemplates validates the templates and creates them, overwriting existing
+	// templates of the same name. It reports how many were created and updated.
+	ImportDeviceTemplates(ctx context.Context, ts []entity.DeviceTemplate) (created, updated int, err error)
 }
 
 // DeviceProfileService exposes the use cases for user device profiles.
 type DeviceProfileService interface {
 	// ListDeviceProfilesByUserID returns paginated profiles scoped to the authenticated user.
 	ListDeviceProfilesByUserID(ctx context.Context, page, pageSize int) ([]entity.DeviceProfile, error)
+	// GetDeviceProfile returns a profile of the authenticated user by identifier.
+	GetDeviceProfile(ctx context.Context, id string) (*entity.DeviceProfile, error)
 	// CreateDeviceProfile persists a new profile instance.
 	CreateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) error
 	// UpdateDeviceProfile applies modifications to an existing profile.
@@ -28,3 +54,25 @@ type DeviceProfileService interface {
 	// DeleteDeviceProfile removes a profile by identifier.
 	DeleteDeviceProfile(ctx context.Context, id string) error
 }
+
+// QuotaService enforces the per-user and per-plan limits.
+type QuotaService interface {
+	// ConsumeRequest counts one API request against the user's per-minute quota.
+	ConsumeRequest(ctx context.Context, userID string) error
+	// CheckDeviceProfileCreate verifies, within the transaction of tx, that the user may
+	// create the supplied profile. The user stays locked until the transaction ends, so
+	// concurrent creates cannot exceed the profile limit.
+	CheckDeviceProfileCreate(ctx context.Context, tx TxRepos, userID string, dp *entity.DeviceProfile) error
+	// CheckCustomHeaders verifies the headers respect the user's header limits.
+	CheckCustomHeaders(ctx context.Context, userID string, headers datatypes.JSONMap) error
+	// GetUsage reports the authenticated user's consumption against its limits.
+	GetUsage(ctx context.Context) (*entity.QuotaUsage, error)
+}
+
+// AuditService exposes the audit trail of mutating API calls.
+type AuditService interface {
+	// ListAuditEvents returns events matching the filter; restricted to administrators.
+	ListAuditEvents(ctx context.Context, f entity.AuditEventFilter) ([]entity.AuditEvent, error)
+	// ListUserAuditEvents returns events performed by the authenticated user.
+	ListUserAuditEvents(ctx context.Context, f entity.AuditEventFilter) ([]entity.AuditEvent, error)
+}
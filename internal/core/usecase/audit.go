@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/datatypes"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/tracing"
+	"zenrows-challenge/internal/pkg/util"
+)
+
+// AuditServiceImpl exposes the audit trail to administrators and to the acting users.
+type AuditServiceImpl struct {
+	log      applog.AppLogger
+	repo     port.AuditRepo
+	userRepo port.UserRepo
+}
+
+// NewAuditServiceImpl constructs an AuditServiceImpl.
+func NewAuditServiceImpl(log applog.AppLogger, ar port.AuditRepo, ur port.UserRepo) *AuditServiceImpl {
+	return &AuditServiceImpl{log: log, repo: ar, userRepo: ur}
+}
+
+func (s *AuditServiceImpl) ListAuditEvents(ctx context.Context, f entity.AuditEventFilter) (_ []entity.AuditEvent, err error) {
+	ctx, span := tracing.Start(ctx, "audit_event.list")
+	defer tracing.End(span, &err)
+	p, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.log.WithContext(ctx).Trace("audit_event.list", "user_id", p.UserID.String())
+
+	user, err := s.userRepo.GetUserByID(ctx, p.UserID.String())
+	if err != nil {
+		return nil, mapRepoErr("get user", err)
+	}
+	if user.Role != entity.UserRoleAdmin {
+		return nil, apperr.NewPermissionDeniedErr("audit events are restricted to administrators", nil)
+	}
+
+	items, err := s.repo.ListAuditEvents(ctx, f)
+	if err != nil {
+		s.log.WithContext(ctx).Error("audit_event.list failed", "error", err)
+		return nil, mapRepoErr("list audit events", err)
+	}
+	return items, nil
+}
+
+func (s *AuditServiceImpl) ListUserAuditEvents(ctx context.Context, f entity.AuditEventFilter) (_ []entity.AuditEvent, err error) {
+	ctx, span := tracing.Start(ctx, "audit_event.list_by_user")
+	defer tracing.End(span, &err)
+	p, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.log.WithContext(ctx).Trace("audit_event.list_by_user", "user_id", p.UserID.String())
+
+	f.ActorID = &p.UserID
+
+	items, err := s.repo.ListAuditEvents(ctx, f)
+	if err != nil {
+		s.log.WithContext(ctx).Error("audit_event.list failed", "error", err)
+		return nil, mapRepoErr("list audit events", err)
+	}
+	return items, nil
+}
+
+// newAuditEvent builds an event for action on the given resource, taking the actor and
+// request origin from ctx. A non-nil err marks the outcome as a failure.
+func newAuditEvent(ctx context.Context, action, resourceType, resourceID string, err error) *entity.AuditEvent {
+	e := &entity.AuditEvent{
+		Action:       action,
+		ResourceType: resourceType,
+		Outcome:      entity.AuditOutcomeSuccess,
+		Details:      datatypes.JSONMap{},
+	}
+	if p, ok := util.PrincipalFrom(ctx); ok {
+		e.ActorID = &p.UserID
+		e.ActorName = optionalString(p.Username)
+	}
+	if resourceID != "" {
+		e.ResourceID = &resourceID
+	}
+
+	meta := util.RequestMetaFrom(ctx)
+	e.SourceIP = optionalString(meta.SourceIP)
+	e.UserAgent = optionalString(meta.UserAgent)
+	e.RequestID = optionalString(meta.RequestID)
+
+	if err != nil {
+		e.Outcome = entity.AuditOutcomeFailure
+		var be apperr.BaseError
+		if errors.As(err, &be) {
+			e.Details["error_code"] = be.Code()
+		}
+	}
+	return e
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
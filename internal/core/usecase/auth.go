@@ -1,39 +1,158 @@
 package usecase
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"zenrows-challenge/internal/core/entity"
 	"zenrows-challenge/internal/core/port"
 	"zenrows-challenge/internal/pkg/apperr"
 	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/tracing"
+	"zenrows-challenge/internal/pkg/util"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// authFailureWindow is how long repeated failures for the same username and source
+	// address are folded into a single audit event.
+	authFailureWindow = time.Minute
+	// maxAuthFailureWindows bounds the windows tracked at once. Failures of addresses
+	// beyond it are not audited; the auth_attempts metric still counts them.
+	maxAuthFailureWindows = 10000
+)
+
+// failureWindow counts the authentication failures of a username and source address
+// since the last one audited.
+type failureWindow struct {
+	start      time.Time
+	suppressed int
+}
+
 type AuthenticationServiceImpl struct {
-	log      applog.AppLogger
-	userRepo port.UserRepo
+	log       applog.AppLogger
+	userRepo  port.UserRepo
+	auditRepo port.AuditRepo
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  map[string]*failureWindow
+	lastSweep time.Time
 }
 
-func NewAuthenticationService(log applog.AppLogger, ur port.UserRepo) *AuthenticationServiceImpl {
-	return &AuthenticationServiceImpl{log: log, userRepo: ur}
+func NewAuthenticationService(log applog.AppLogger, ur port.UserRepo, ar port.AuditRepo) *AuthenticationServiceImpl {
+	return &AuthenticationServiceImpl{
+		log:       log,
+		userRepo:  ur,
+		auditRepo: ar,
+		now:       time.Now,
+		failures:  make(map[string]*failureWindow),
+	}
 }
 
-func (s *AuthenticationServiceImpl) CheckCredentials(username string, password string) (string, error) {
+func (s *AuthenticationServiceImpl) CheckCredentials(ctx context.Context, username string, password redact.Secret) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.check_credentials")
+	defer tracing.End(span, &err)
 	user := entity.User{
 		Username: username,
 	}
 
-	userID, passwordHash, err := s.userRepo.RetrieveCredentials(user)
+	userID, passwordHash, err := s.userRepo.RetrieveCredentials(ctx, user)
 	if err != nil {
+		s.RecordAuthFailure(ctx, username, "credentials lookup failed")
 		return "", apperr.NewNotAuthorizedErr("Unauthorized", err)
 	}
 
 	if userID == "" || passwordHash == "" {
+		s.RecordAuthFailure(ctx, username, "unknown user")
 		return "", apperr.NewNotAuthorizedErr("Unauthorized", err)
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) != nil {
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password.Reveal())) != nil {
+		s.RecordAuthFailure(ctx, username, "invalid password")
 		return "", nil
 	}
 	return userID, nil
 }
+
+func (s *AuthenticationServiceImpl) CheckCertificateUser(ctx context.Context, username string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.check_certificate_user")
+	defer tracing.End(span, &err)
+
+	userID, _, err := s.userRepo.RetrieveCredentials(ctx, entity.User{Username: username})
+	if err != nil || userID == "" {
+		s.RecordAuthFailure(ctx, username, "unknown certificate user")
+		return "", apperr.NewNotAuthorizedErr("Unauthorized", err)
+	}
+	return userID, nil
+}
+
+// RecordAuthFailure audits the first failure for a username and source address in each
+// authFailureWindow. Later ones in the window are only counted, and the count is
+// reported as suppressed_failures on the next event audited for them.
+func (s *AuthenticationServiceImpl) RecordAuthFailure(ctx context.Context, username string, reason string) {
+	suppressed, ok := s.throttleFailure(username + "\x00" + util.RequestMetaFrom(ctx).SourceIP)
+	if !ok {
+		return
+	}
+	e := newAuditEvent(ctx, entity.AuditActionAuthFailure, entity.AuditResourceUser, "", nil)
+	e.Outcome = entity.AuditOutcomeFailure
+	e.ActorName = optionalString(username)
+	e.Details["reason"] = reason
+	if suppressed > 0 {
+		e.Details["suppressed_failures"] = suppressed
+	}
+	if err := s.auditRepo.CreateAuditEvent(ctx, e); err != nil {
+		s.log.WithContext(ctx).Error("audit_event.create failed", "action", e.Action, "error", err)
+	}
+}
+
+// throttleFailure reports whether a failure for key is to be audited and, if so, how
+// many failures for key were suppressed since the previous audited one.
+func (s *AuthenticationServiceImpl) throttleFailure(key string) (int, bool) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.failures[key]
+	if w != nil && now.Sub(w.start) < authFailureWindow {
+		w.suppressed++
+		return 0, false
+	}
+	s.evictExpiredFailures(now)
+	if w == nil && len(s.failures) >= maxAuthFailureWindows {
+		return 0, false
+	}
+	s.failures[key] = &failureWindow{start: now}
+	if w == nil {
+		return 0, true
+	}
+	return w.suppressed, true
+}
+
+// evictExpiredFailures drops windows that ended so idle addresses do not accumulate,
+// with the count of failures they suppressed. It sweeps at most once per window.
+// Callers must hold s.mu.
+func (s *AuthenticationServiceImpl) evictExpiredFailures(now time.Time) {
+	if now.Sub(s.lastSweep) < authFailureWindow {
+		return
+	}
+	s.lastSweep = now
+	for key, w := range s.failures {
+		if now.Sub(w.start) >= authFailureWindow {
+			delete(s.failures, key)
+		}
+	}
+}
+
+// principalFrom returns the authenticated caller of the request ctx belongs to.
+func principalFrom(ctx context.Context) (util.Principal, error) {
+	p, ok := util.PrincipalFrom(ctx)
+	if !ok {
+		return util.Principal{}, apperr.NewNotAuthorizedErr("NotAuthorizedErr", nil)
+	}
+	return p, nil
+}
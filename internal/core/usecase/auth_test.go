@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticationService_RecordAuthFailure_Throttled(t *testing.T) {
+	audit := &mockAuditRepo{}
+	svc := NewAuthenticationService(noopLogger{}, nil, audit)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	from := func(ip string) context.Context {
+		return util.WithRequestMeta(context.Background(), util.RequestMeta{SourceIP: ip})
+	}
+
+	for range 5 {
+		svc.RecordAuthFailure(from("10.0.0.1"), "alice", "invalid password")
+	}
+	svc.RecordAuthFailure(from("10.0.0.2"), "alice", "invalid password")
+	svc.RecordAuthFailure(from("10.0.0.1"), "bob", "unknown user")
+	require.Len(t, audit.events, 3, "one event per username and address in a window")
+	assert.NotContains(t, audit.events[0].Details, "suppressed_failures")
+
+	now = now.Add(authFailureWindow)
+	svc.RecordAuthFailure(from("10.0.0.1"), "alice", "invalid password")
+	require.Len(t, audit.events, 4)
+	last := audit.events[3]
+	assert.Equal(t, entity.AuditActionAuthFailure, last.Action)
+	assert.Equal(t, 4, last.Details["suppressed_failures"])
+}
@@ -3,10 +3,10 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 
@@ -14,7 +14,8 @@ import (
 	"zenrows-challenge/internal/core/port"
 	"zenrows-challenge/internal/pkg/apperr"
 	"zenrows-challenge/internal/pkg/applog"
-	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/tracing"
+	"zenrows-challenge/internal/pkg/validation"
 )
 
 // DeviceProfileServiceImpl provides application logic for device profiles.
@@ -22,96 +23,196 @@ type DeviceProfileServiceImpl struct {
 	log                applog.AppLogger
 	repo               port.DeviceProfileRepo
 	deviceTemplateRepo port.DeviceTemplateRepo
+	quota              port.QuotaService
+	uow                port.UnitOfWork
 	v                  *validator.Validate
 }
 
 // NewDeviceProfileServiceImpl constructs a new DeviceProfileServiceImpl with the provided logger and repository.
-func NewDeviceProfileServiceImpl(log applog.AppLogger, r port.DeviceProfileRepo, dtr port.DeviceTemplateRepo, v *validator.Validate) *DeviceProfileServiceImpl {
-	return &DeviceProfileServiceImpl{log: log, repo: r, deviceTemplateRepo: dtr, v: v}
+// Mutations go through uow so that each change and its audit event are committed together.
+func NewDeviceProfileServiceImpl(log applog.AppLogger, r port.DeviceProfileRepo, dtr port.DeviceTemplateRepo, q port.QuotaService, uow port.UnitOfWork, v *validator.Validate) *DeviceProfileServiceImpl {
+	return &DeviceProfileServiceImpl{log: log, repo: r, deviceTemplateRepo: dtr, quota: q, uow: uow, v: v}
 }
 
-func (s *DeviceProfileServiceImpl) CreateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) error {
-	s.log.Trace("device_profile.create", "user_id", dp.UserID.String(), "name", dp.Name)
+func (s *DeviceProfileServiceImpl) CreateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) (err error) {
+	ctx, span := tracing.Start(ctx, "device_profile.create")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("device_profile.create", "user_id", dp.UserID.String(), "name", dp.Name)
 	if err := s.v.Struct(dp); err != nil {
-		return apperr.NewInvalidArgErr("invalid payload", err)
+		return apperr.NewInvalidArgErr("invalid payload", err).WithDetails(validation.Violations(err)...)
 	}
 
-	userID := ctx.Value(middleware.AuthUserIDKey).(string)
+	p, err := principalFrom(ctx)
+	if err != nil {
+		return err
+	}
 	if dp.TemplateID != nil {
-		t, err := s.deviceTemplateRepo.GetDeviceTemplateByID(dp.TemplateID)
+		t, err := s.deviceTemplateRepo.GetDeviceTemplateByID(ctx, dp.TemplateID)
 		if err != nil {
 			return apperr.NewNotFoundErr("device template not found", err)
 		}
 
 		dp = s.createDeviceProfileByTemplate(t)
-		uid, err := uuid.Parse(userID)
-		if err != nil {
-			return apperr.NewInvalidArgErr("invalid user id", err)
-		}
-		dp.UserID = uid
+		dp.UserID = p.UserID
 	}
 
-	if err := s.repo.CreateDeviceProfile(dp); err != nil {
-		s.log.Error("device_profile.create failed: %v", err)
-		return mapRepoErr("create device profile", err)
+	err = s.uow.Do(ctx, func(tx port.TxRepos) error {
+		if err := s.quota.CheckDeviceProfileCreate(ctx, tx, dp.UserID.String(), dp); err != nil {
+			return err
+		}
+		if err := tx.DeviceProfiles().CreateDeviceProfile(ctx, dp); err != nil {
+			return err
+		}
+		return tx.AuditEvents().CreateAuditEvent(ctx, newAuditEvent(ctx, entity.AuditActionDeviceProfileCreate,
+			entity.AuditResourceDeviceProfile, dp.ID.String(), nil))
+	})
+	span.SetAttributes(tracing.ProfileID(dp.ID.String()))
+	if err != nil {
+		var qe *apperr.QuotaExceededErr
+		if !errors.As(err, &qe) {
+			s.log.WithContext(ctx).Error("device_profile.create failed", "error", err)
+		}
+		appErr := mapRepoErr("create device profile", err)
+		s.recordFailure(ctx, entity.AuditActionDeviceProfileCreate, "", appErr)
+		return appErr
 	}
 	return nil
 }
 
-func (s *DeviceProfileServiceImpl) ListDeviceProfilesByUserID(ctx context.Context, page, pageSize int) ([]entity.DeviceProfile, error) {
-	s.log.Trace("device_profile.list_by_user_id", "page", page, "page_size", pageSize)
+func (s *DeviceProfileServiceImpl) ListDeviceProfilesByUserID(ctx context.Context, page, pageSize int) (_ []entity.DeviceProfile, err error) {
+	ctx, span := tracing.Start(ctx, "device_profile.list_by_user_id")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("device_profile.list_by_user_id", "page", page, "page_size", pageSize)
 
-	userID := ctx.Value(middleware.AuthUserIDKey).(string)
+	p, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	items, err := s.repo.ListDeviceProfiles(userID, page, pageSize)
+	items, err := s.repo.ListDeviceProfiles(ctx, p.UserID.String(), page, pageSize)
 	if err != nil {
-		s.log.Error("device_profile.list failed: %v", err)
+		s.log.WithContext(ctx).Error("device_profile.list failed", "error", err)
 		return nil, mapRepoErr("list device profiles", err)
 	}
 	return items, nil
 }
 
-func (s *DeviceProfileServiceImpl) UpdateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) (*entity.DeviceProfile, error) {
-	s.log.Trace("device_profile.update", "id", dp.ID.String(), "name", dp.Name)
+func (s *DeviceProfileServiceImpl) GetDeviceProfile(ctx context.Context, id string) (_ *entity.DeviceProfile, err error) {
+	ctx, span := tracing.Start(ctx, "device_profile.get", tracing.ProfileID(id))
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("device_profile.get", "id", id)
 
-	userID := dp.UserID.String()
-	uid := ctx.Value(middleware.AuthUserIDKey).(string)
-	if userID == "" || uid != userID {
-		return nil, apperr.NewNotAuthorizedErr("NotAuthorizedErr", nil)
+	p, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.v.Var(id, "required,uuid4"); err != nil {
+		return nil, apperr.NewInvalidArgErr("invalid id", err).WithDetails(validation.VarViolations("id", err)...)
+	}
+
+	// Profiles of other users are reported as missing, so their identifiers do not leak.
+	dp, err := s.repo.GetDeviceProfile(ctx, p.UserID.String(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.NewNotFoundErr("device profile not found", err)
+		}
+		s.log.WithContext(ctx).Error("device_profile.get failed", "error", err)
+		return nil, mapRepoErr("get device profile", err)
+	}
+	return dp, nil
+}
+
+func (s *DeviceProfileServiceImpl) UpdateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) (_ *entity.DeviceProfile, err error) {
+	ctx, span := tracing.Start(ctx, "device_profile.update", tracing.ProfileID(dp.ID.String()))
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("device_profile.update", "id", dp.ID.String(), "name", dp.Name)
+
+	p, err := principalFrom(ctx)
+	if err == nil && dp.UserID != p.UserID {
+		err = apperr.NewNotAuthorizedErr("NotAuthorizedErr", nil)
+	}
+	if err != nil {
+		s.recordFailure(ctx, entity.AuditActionDeviceProfileUpdate, dp.ID.String(), err)
+		return nil, err
 	}
 
 	if err := s.v.Var(dp.ID, "required,uuid4"); err != nil {
-		return nil, apperr.NewInvalidArgErr("invalid id", err)
+		return nil, apperr.NewInvalidArgErr("invalid id", err).WithDetails(validation.VarViolations("id", err)...)
 	}
 	if err := s.v.Var(dp.UserID, "required,uuid4"); err != nil {
-		return nil, apperr.NewInvalidArgErr("invalid user id", err)
+		return nil, apperr.NewInvalidArgErr("invalid user id", err).WithDetails(validation.VarViolations("user_id", err)...)
 	}
 
-	if err := s.repo.UpdateDeviceProfile(dp); err != nil {
-		s.log.Error("device_profile.update failed: %v", err)
-		return nil, mapRepoErr("update device profile", err)
+	if dp.CustomHeaders != nil {
+		if err := s.quota.CheckCustomHeaders(ctx, p.UserID.String(), dp.CustomHeaders); err != nil {
+			s.recordFailure(ctx, entity.AuditActionDeviceProfileUpdate, dp.ID.String(), err)
+			return nil, err
+		}
+	}
+
+	err = s.uow.Do(ctx, func(tx port.TxRepos) error {
+		if err := tx.DeviceProfiles().UpdateDeviceProfile(ctx, dp); err != nil {
+			return err
+		}
+		return tx.AuditEvents().CreateAuditEvent(ctx, newAuditEvent(ctx, entity.AuditActionDeviceProfileUpdate,
+			entity.AuditResourceDeviceProfile, dp.ID.String(), nil))
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("device_profile.update failed", "error", err)
+		appErr := mapRepoErr("update device profile", err)
+		s.recordFailure(ctx, entity.AuditActionDeviceProfileUpdate, dp.ID.String(), appErr)
+		return nil, appErr
 	}
 	return dp, nil
 }
 
-func (s *DeviceProfileServiceImpl) DeleteDeviceProfile(ctx context.Context, id string) error {
-	s.log.Trace("device_profile.delete", "id", id)
-	userID := ctx.Value(middleware.AuthUserIDKey).(string)
+func (s *DeviceProfileServiceImpl) DeleteDeviceProfile(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "device_profile.delete", tracing.ProfileID(id))
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("device_profile.delete", "id", id)
+	p, err := principalFrom(ctx)
+	if err != nil {
+		return err
+	}
 
 	if err := s.v.Var(id, "required,uuid4"); err != nil {
-		return apperr.NewInvalidArgErr("invalid id", err)
+		return apperr.NewInvalidArgErr("invalid id", err).WithDetails(validation.VarViolations("id", err)...)
 	}
 
-	if err := s.repo.DeleteDeviceProfile(userID, id); err != nil {
-		s.log.Error("device_profile.delete failed: %v", err)
+	err = s.uow.Do(ctx, func(tx port.TxRepos) error {
+		if err := tx.DeviceProfiles().DeleteDeviceProfile(ctx, p.UserID.String(), id); err != nil {
+			return err
+		}
+		return tx.AuditEvents().CreateAuditEvent(ctx, newAuditEvent(ctx, entity.AuditActionDeviceProfileDelete,
+			entity.AuditResourceDeviceProfile, id, nil))
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("device_profile.delete failed", "error", err)
+		var appErr error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return apperr.NewNotFoundErr("device profile not found", err)
+			appErr = apperr.NewNotFoundErr("device profile not found", err)
+		} else {
+			appErr = mapRepoErr("delete device profile", err)
 		}
-		return mapRepoErr("delete device profile", err)
+		s.recordFailure(ctx, entity.AuditActionDeviceProfileDelete, id, appErr)
+		return appErr
 	}
 	return nil
 }
 
+// recordFailure audits a rejected mutation. The event is written in its own transaction
+// since the one of the failed change, if any, was rolled back. It is not tied to the
+// cancellation of ctx, so requests aborted by the client or a deadline are audited too.
+func (s *DeviceProfileServiceImpl) recordFailure(ctx context.Context, action, resourceID string, cause error) {
+	ctx = context.WithoutCancel(ctx)
+	err := s.uow.Do(ctx, func(tx port.TxRepos) error {
+		return tx.AuditEvents().CreateAuditEvent(ctx, newAuditEvent(ctx, action, entity.AuditResourceDeviceProfile, resourceID, cause))
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("audit_event.create failed", "action", action, "error", err)
+	}
+}
+
 func (s *DeviceProfileServiceImpl) createDeviceProfileByTemplate(t *entity.DeviceTemplate) *entity.DeviceProfile {
 	var dp entity.DeviceProfile
 	dp.Name = t.Name
@@ -126,22 +227,61 @@ func (s *DeviceProfileServiceImpl) createDeviceProfileByTemplate(t *entity.Devic
 	return &dp
 }
 
+// constraintViolations maps named database constraints to the request field they guard.
+// Check constraints not listed here follow Postgres' "<table>_<column>_check" naming.
+var constraintViolations = map[string]apperr.FieldViolation{
+	"device_profile_user_id_name_key": {Field: "name", Rule: "unique", Message: "a device profile with this name already exists"},
+	"device_profile_template_id_fkey": {Field: "template_id", Rule: "exists", Message: "device template does not exist"},
+}
+
 func mapRepoErr(action string, err error) error {
+	// Errors of use cases called within a transaction are mapped already.
+	var appErr apperr.BaseError
+	if errors.As(err, &appErr) {
+		return err
+	}
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return apperr.NewNotFoundErr(action, err)
 	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apperr.NewTimeoutErr(action, err)
+	}
+	if errors.Is(err, context.Canceled) {
+		return apperr.NewCanceledErr(action, err)
+	}
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
+		details := pgViolations(pgErr)
 		switch pgErr.Code {
 		case "23505":
-			return apperr.NewAlreadyExistsErr(action, err)
-		case "23503":
-			return apperr.NewInvalidArgErr(action, err)
-		case "23514":
-			return apperr.NewInvalidArgErr(action, err)
+			return apperr.NewAlreadyExistsErr(action, err).WithDetails(details...)
+		case "23502", "23503", "23514":
+			return apperr.NewInvalidArgErr(action, err).WithDetails(details...)
+		case "57014":
+			return apperr.NewTimeoutErr(action, err)
 		default:
 			return apperr.NewInternalErr(action, err)
 		}
 	}
 	return apperr.NewInternalErr(action, err)
 }
+
+// pgViolations derives the offending field of a constraint violation, if it can be identified.
+func pgViolations(pgErr *pgconn.PgError) []apperr.FieldViolation {
+	if v, ok := constraintViolations[pgErr.ConstraintName]; ok {
+		return []apperr.FieldViolation{v}
+	}
+	switch pgErr.Code {
+	case "23502":
+		if pgErr.ColumnName != "" {
+			return []apperr.FieldViolation{{Field: pgErr.ColumnName, Rule: "required", Message: "is required"}}
+		}
+	case "23514":
+		prefix := pgErr.TableName + "_"
+		if pgErr.TableName != "" && strings.HasPrefix(pgErr.ConstraintName, prefix) && strings.HasSuffix(pgErr.ConstraintName, "_check") {
+			field := strings.TrimSuffix(strings.TrimPrefix(pgErr.ConstraintName, prefix), "_check")
+			return []apperr.FieldViolation{{Field: field, Rule: "check", Message: "has an invalid value"}}
+		}
+	}
+	return nil
+}
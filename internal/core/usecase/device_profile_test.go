@@ -3,11 +3,13 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
 	"zenrows-challenge/internal/pkg/apperr"
-	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/util"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -21,57 +23,132 @@ import (
 type mockDeviceProfileRepo struct {
 	createFn func(*entity.DeviceProfile) error
 	listFn   func(string, int, int) ([]entity.DeviceProfile, error)
+	getFn    func(string, string) (*entity.DeviceProfile, error)
 	updateFn func(*entity.DeviceProfile) error
 	deleteFn func(string, string) error
+	countFn  func(string) (int64, error)
 }
 
-func (m *mockDeviceProfileRepo) CreateDeviceProfile(dp *entity.DeviceProfile) error {
+func (m *mockDeviceProfileRepo) CreateDeviceProfile(_ context.Context, dp *entity.DeviceProfile) error {
 	if m.createFn != nil {
 		return m.createFn(dp)
 	}
 	return nil
 }
 
-func (m *mockDeviceProfileRepo) ListDeviceProfiles(userID string, page, pageSize int) ([]entity.DeviceProfile, error) {
+func (m *mockDeviceProfileRepo) ListDeviceProfiles(_ context.Context, userID string, page, pageSize int) ([]entity.DeviceProfile, error) {
 	if m.listFn != nil {
 		return m.listFn(userID, page, pageSize)
 	}
 	return nil, nil
 }
 
-func (m *mockDeviceProfileRepo) UpdateDeviceProfile(dp *entity.DeviceProfile) error {
+func (m *mockDeviceProfileRepo) GetDeviceProfile(_ context.Context, userID, id string) (*entity.DeviceProfile, error) {
+	if m.getFn != nil {
+		return m.getFn(userID, id)
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *mockDeviceProfileRepo) UpdateDeviceProfile(_ context.Context, dp *entity.DeviceProfile) error {
 	if m.updateFn != nil {
 		return m.updateFn(dp)
 	}
 	return nil
 }
 
-func (m *mockDeviceProfileRepo) UpdateDeviceProfileSelective(dp *entity.DeviceProfile) error {
-	return m.UpdateDeviceProfile(dp)
+func (m *mockDeviceProfileRepo) UpdateDeviceProfileSelective(ctx context.Context, dp *entity.DeviceProfile) error {
+	return m.UpdateDeviceProfile(ctx, dp)
 }
 
-func (m *mockDeviceProfileRepo) DeleteDeviceProfile(userID, id string) error {
+func (m *mockDeviceProfileRepo) DeleteDeviceProfile(_ context.Context, userID, id string) error {
 	if m.deleteFn != nil {
 		return m.deleteFn(userID, id)
 	}
 	return nil
 }
 
+func (m *mockDeviceProfileRepo) CountDeviceProfiles(_ context.Context, userID string) (int64, error) {
+	if m.countFn != nil {
+		return m.countFn(userID)
+	}
+	return 0, nil
+}
+
+type mockAuditRepo struct {
+	events []entity.AuditEvent
+}
+
+func (m *mockAuditRepo) CreateAuditEvent(_ context.Context, e *entity.AuditEvent) error {
+	m.events = append(m.events, *e)
+	return nil
+}
+
+func (m *mockAuditRepo) ListAuditEvents(context.Context, entity.AuditEventFilter) ([]entity.AuditEvent, error) {
+	return m.events, nil
+}
+
+// mockUnitOfWork runs fn directly against the mocks, without transactional semantics.
+type mockUnitOfWork struct {
+	profiles  port.DeviceProfileRepo
+	users     port.UserRepo
+	templates port.DeviceTemplateRepo
+	quotas    port.QuotaRepo
+	audit     *mockAuditRepo
+}
+
+func newMockUnitOfWork(profiles port.DeviceProfileRepo) *mockUnitOfWork {
+	return &mockUnitOfWork{profiles: profiles, audit: &mockAuditRepo{}}
+}
+
+func (m *mockUnitOfWork) Do(_ context.Context, fn func(port.TxRepos) error) error { return fn(m) }
+
+func (m *mockUnitOfWork) DeviceProfiles() port.DeviceProfileRepo { return m.profiles }
+
+func (m *mockUnitOfWork) AuditEvents() port.AuditRepo { return m.audit }
+
+func (m *mockUnitOfWork) Users() port.UserRepo { return m.users }
+
+func (m *mockUnitOfWork) DeviceTemplates() port.DeviceTemplateRepo { return m.templates }
+
+func (m *mockUnitOfWork) Quotas() port.QuotaRepo { return m.quotas }
+
+type noopQuotaService struct{}
+
+func (noopQuotaService) ConsumeRequest(context.Context, string) error { return nil }
+func (noopQuotaService) CheckDeviceProfileCreate(context.Context, port.TxRepos, string, *entity.DeviceProfile) error {
+	return nil
+}
+func (noopQuotaService) CheckCustomHeaders(context.Context, string, datatypes.JSONMap) error {
+	return nil
+}
+func (noopQuotaService) GetUsage(context.Context) (*entity.QuotaUsage, error) {
+	return &entity.QuotaUsage{}, nil
+}
+
+func principalContext(userID uuid.UUID) context.Context {
+	return util.WithPrincipal(context.Background(), util.Principal{UserID: userID})
+}
+
 type mockDeviceTemplateRepo struct {
 	getFn func(*uuid.UUID) (*entity.DeviceTemplate, error)
 }
 
-func (m *mockDeviceTemplateRepo) GetDeviceTemplates() ([]entity.DeviceTemplate, error) {
+func (m *mockDeviceTemplateRepo) GetDeviceTemplates(context.Context) ([]entity.DeviceTemplate, error) {
 	return nil, errors.New("not implemented")
 }
 
-func (m *mockDeviceTemplateRepo) GetDeviceTemplateByID(id *uuid.UUID) (*entity.DeviceTemplate, error) {
+func (m *mockDeviceTemplateRepo) GetDeviceTemplateByID(_ context.Context, id *uuid.UUID) (*entity.DeviceTemplate, error) {
 	if m.getFn != nil {
 		return m.getFn(id)
 	}
 	return nil, nil
 }
 
+func (m *mockDeviceTemplateRepo) UpsertDeviceTemplates(context.Context, []entity.DeviceTemplate) (int, int, error) {
+	return 0, 0, errors.New("not implemented")
+}
+
 func TestDeviceProfileService_CreateDeviceProfile_Success(t *testing.T) {
 	repoCalled := false
 	repo := &mockDeviceProfileRepo{
@@ -81,8 +158,8 @@ func TestDeviceProfileService_CreateDeviceProfile_Success(t *testing.T) {
 			return nil
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, validator.New())
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, uuid.NewString())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
+	ctx := principalContext(uuid.New())
 
 	dp := &entity.DeviceProfile{
 		UserID:     uuid.New(),
@@ -96,8 +173,8 @@ func TestDeviceProfileService_CreateDeviceProfile_Success(t *testing.T) {
 }
 
 func TestDeviceProfileService_CreateDeviceProfile_InvalidPayload(t *testing.T) {
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, validator.New())
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, uuid.NewString())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(&mockDeviceProfileRepo{}), validator.New())
+	ctx := principalContext(uuid.New())
 	dp := &entity.DeviceProfile{DeviceType: "desktop"}
 
 	err := svc.CreateDeviceProfile(ctx, dp)
@@ -133,9 +210,9 @@ func TestDeviceProfileService_CreateDeviceProfile_UsesTemplate(t *testing.T) {
 			return nil
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, templateRepo, validator.New())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, templateRepo, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
 
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, userID.String())
+	ctx := principalContext(userID)
 	dp := &entity.DeviceProfile{
 		UserID:     uuid.New(),
 		Name:       "ignored",
@@ -155,8 +232,8 @@ func TestDeviceProfileService_CreateDeviceProfile_RepoError(t *testing.T) {
 			return &pgconn.PgError{Code: "23505"}
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, validator.New())
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, uuid.NewString())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
+	ctx := principalContext(uuid.New())
 
 	dp := &entity.DeviceProfile{
 		UserID:     uuid.New(),
@@ -170,26 +247,56 @@ func TestDeviceProfileService_CreateDeviceProfile_RepoError(t *testing.T) {
 	assert.ErrorAs(t, err, &ae)
 }
 
+func TestDeviceProfileService_CreateDeviceProfile_QuotaExceeded(t *testing.T) {
+	repo := &mockDeviceProfileRepo{
+		countFn: func(string) (int64, error) { return 2, nil },
+		createFn: func(*entity.DeviceProfile) error {
+			t.Fatal("profile created over the limit")
+			return nil
+		},
+	}
+	quotas := &mockQuotaRepo{plan: "free"}
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{},
+		NewQuotaServiceImpl(noopLogger{}, quotas, repo, testPlans, "free"), quotaTx(quotas, repo), validator.New())
+	userID := uuid.New()
+
+	err := svc.CreateDeviceProfile(principalContext(userID), &entity.DeviceProfile{UserID: userID, Name: "third", DeviceType: "desktop"})
+	var qe *apperr.QuotaExceededErr
+	require.ErrorAs(t, err, &qe)
+	assert.Equal(t, entity.QuotaMaxProfiles, qe.Resource())
+	assert.True(t, quotas.locked)
+}
+
 func TestDeviceProfileService_ListDeviceProfilesByUserID(t *testing.T) {
+	userID := uuid.New()
 	repo := &mockDeviceProfileRepo{
-		listFn: func(userID string, page, pageSize int) ([]entity.DeviceProfile, error) {
-			assert.Equal(t, "user", userID)
+		listFn: func(id string, page, pageSize int) ([]entity.DeviceProfile, error) {
+			assert.Equal(t, userID.String(), id)
 			assert.Equal(t, 1, page)
 			assert.Equal(t, 10, pageSize)
 			return []entity.DeviceProfile{{Name: "A"}}, nil
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, validator.New())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
 
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, "user")
+	ctx := principalContext(userID)
 	out, err := svc.ListDeviceProfilesByUserID(ctx, 1, 10)
 	require.NoError(t, err)
 	assert.Len(t, out, 1)
 }
 
+func TestDeviceProfileService_ListDeviceProfilesByUserID_NoPrincipal(t *testing.T) {
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(&mockDeviceProfileRepo{}), validator.New())
+
+	_, err := svc.ListDeviceProfilesByUserID(context.Background(), 1, 10)
+	require.Error(t, err)
+	var na *apperr.NotAuthorizedErr
+	assert.ErrorAs(t, err, &na)
+}
+
 func TestDeviceProfileService_UpdateDeviceProfile_NotAuthorized(t *testing.T) {
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, validator.New())
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, "some-user")
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(&mockDeviceProfileRepo{}), validator.New())
+	ctx := principalContext(uuid.New())
 	dp := &entity.DeviceProfile{
 		ID:         uuid.New(),
 		UserID:     uuid.New(),
@@ -212,10 +319,10 @@ func TestDeviceProfileService_UpdateDeviceProfile_Success(t *testing.T) {
 			return nil
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, validator.New())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
 
 	userID := uuid.New()
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, userID.String())
+	ctx := principalContext(userID)
 	dp := &entity.DeviceProfile{
 		ID:         uuid.New(),
 		UserID:     userID,
@@ -235,10 +342,10 @@ func TestDeviceProfileService_UpdateDeviceProfile_MapError(t *testing.T) {
 			return gorm.ErrRecordNotFound
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, validator.New())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
 
 	userID := uuid.New()
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, userID.String())
+	ctx := principalContext(userID)
 	dp := &entity.DeviceProfile{
 		ID:         uuid.New(),
 		UserID:     userID,
@@ -252,9 +359,39 @@ func TestDeviceProfileService_UpdateDeviceProfile_MapError(t *testing.T) {
 	assert.ErrorAs(t, err, &nf)
 }
 
+func TestDeviceProfileService_GetDeviceProfile_Success(t *testing.T) {
+	userID := uuid.New()
+	id := uuid.NewString()
+	repo := &mockDeviceProfileRepo{
+		getFn: func(uid, pid string) (*entity.DeviceProfile, error) {
+			assert.Equal(t, userID.String(), uid)
+			assert.Equal(t, id, pid)
+			return &entity.DeviceProfile{ID: uuid.MustParse(pid), UserID: userID, Name: "Profile"}, nil
+		},
+	}
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
+
+	dp, err := svc.GetDeviceProfile(principalContext(userID), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Profile", dp.Name)
+}
+
+func TestDeviceProfileService_GetDeviceProfile_Errors(t *testing.T) {
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(&mockDeviceProfileRepo{}), validator.New())
+	ctx := principalContext(uuid.New())
+
+	_, err := svc.GetDeviceProfile(ctx, "not-a-uuid")
+	var inv *apperr.InvalidArgErr
+	assert.ErrorAs(t, err, &inv)
+
+	_, err = svc.GetDeviceProfile(ctx, uuid.NewString())
+	var nf *apperr.NotFoundErr
+	assert.ErrorAs(t, err, &nf)
+}
+
 func TestDeviceProfileService_DeleteDeviceProfile_InvalidID(t *testing.T) {
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, validator.New())
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, uuid.NewString())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, &mockDeviceProfileRepo{}, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(&mockDeviceProfileRepo{}), validator.New())
+	ctx := principalContext(uuid.New())
 
 	err := svc.DeleteDeviceProfile(ctx, "not-a-uuid")
 	require.Error(t, err)
@@ -264,15 +401,16 @@ func TestDeviceProfileService_DeleteDeviceProfile_InvalidID(t *testing.T) {
 
 func TestDeviceProfileService_DeleteDeviceProfile_Success(t *testing.T) {
 	repoCalled := false
+	userID := uuid.New()
 	repo := &mockDeviceProfileRepo{
-		deleteFn: func(userID, id string) error {
+		deleteFn: func(uid, id string) error {
 			repoCalled = true
-			assert.Equal(t, "user", userID)
+			assert.Equal(t, userID.String(), uid)
 			return nil
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, validator.New())
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, "user")
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
+	ctx := principalContext(userID)
 
 	err := svc.DeleteDeviceProfile(ctx, uuid.NewString())
 	require.NoError(t, err)
@@ -285,11 +423,105 @@ func TestDeviceProfileService_DeleteDeviceProfile_NotFound(t *testing.T) {
 			return gorm.ErrRecordNotFound
 		},
 	}
-	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, validator.New())
-	ctx := context.WithValue(context.Background(), middleware.AuthUserIDKey, uuid.NewString())
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, newMockUnitOfWork(repo), validator.New())
+	ctx := principalContext(uuid.New())
 
 	err := svc.DeleteDeviceProfile(ctx, uuid.NewString())
 	require.Error(t, err)
 	var nf *apperr.NotFoundErr
 	assert.ErrorAs(t, err, &nf)
 }
+
+func TestDeviceProfileService_RecordsAuditEvents(t *testing.T) {
+	repo := &mockDeviceProfileRepo{
+		createFn: func(dp *entity.DeviceProfile) error {
+			dp.ID = uuid.New()
+			return nil
+		},
+		deleteFn: func(string, string) error {
+			return gorm.ErrRecordNotFound
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	svc := NewDeviceProfileServiceImpl(noopLogger{}, repo, &mockDeviceTemplateRepo{}, noopQuotaService{}, uow, validator.New())
+	userID := uuid.New()
+	ctx := principalContext(userID)
+
+	dp := &entity.DeviceProfile{UserID: userID, Name: "Profile", DeviceType: "desktop"}
+	require.NoError(t, svc.CreateDeviceProfile(ctx, dp))
+	require.Error(t, svc.DeleteDeviceProfile(ctx, uuid.NewString()))
+
+	require.Len(t, uow.audit.events, 2)
+	created := uow.audit.events[0]
+	assert.Equal(t, entity.AuditActionDeviceProfileCreate, created.Action)
+	assert.Equal(t, entity.AuditOutcomeSuccess, created.Outcome)
+	assert.Equal(t, userID, *created.ActorID)
+	assert.Equal(t, dp.ID.String(), *created.ResourceID)
+
+	deleted := uow.audit.events[1]
+	assert.Equal(t, entity.AuditActionDeviceProfileDelete, deleted.Action)
+	assert.Equal(t, entity.AuditOutcomeFailure, deleted.Outcome)
+	assert.Equal(t, "NOT_FOUND", deleted.Details["error_code"])
+}
+
+func TestMapRepoErr_ConstraintViolationDetails(t *testing.T) {
+	cases := []struct {
+		name  string
+		err   *pgconn.PgError
+		field string
+		rule  string
+	}{
+		{
+			name:  "unique name",
+			err:   &pgconn.PgError{Code: "23505", TableName: "device_profile", ConstraintName: "device_profile_user_id_name_key"},
+			field: "name",
+			rule:  "unique",
+		},
+		{
+			name:  "check constraint",
+			err:   &pgconn.PgError{Code: "23514", TableName: "device_profile", ConstraintName: "device_profile_country_code_check"},
+			field: "country_code",
+			rule:  "check",
+		},
+		{
+			name:  "not null",
+			err:   &pgconn.PgError{Code: "23502", TableName: "device_profile", ColumnName: "device_type"},
+			field: "device_type",
+			rule:  "required",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var be apperr.BaseError
+			require.ErrorAs(t, mapRepoErr("create device profile", tc.err), &be)
+			require.Len(t, be.Details(), 1)
+			assert.Equal(t, tc.field, be.Details()[0].Field)
+			assert.Equal(t, tc.rule, be.Details()[0].Rule)
+		})
+	}
+}
+
+func TestMapRepoErr_Timeouts(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		canceled bool
+	}{
+		{name: "context deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded)},
+		{name: "context cancelled", err: context.Canceled, canceled: true},
+		{name: "postgres statement timeout", err: &pgconn.PgError{Code: "57014"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := mapRepoErr("list device profiles", tc.err)
+			if tc.canceled {
+				var ce *apperr.CanceledErr
+				assert.ErrorAs(t, err, &ce)
+				return
+			}
+			var to *apperr.TimeoutErr
+			assert.ErrorAs(t, err, &to)
+		})
+	}
+}
@@ -1,21 +1,81 @@
 package usecase
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+
 	"zenrows-challenge/internal/core/entity"
 	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/apperr"
 	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/tracing"
+	"zenrows-challenge/internal/pkg/validation"
 )
 
 type DeviceTemplateServiceImpl struct {
 	repo port.DeviceTemplateRepo
 	log  applog.AppLogger
+	uow  port.UnitOfWork
+	v    *validator.Validate
+}
+
+// NewDeviceTemplateServiceImpl constructs a DeviceTemplateServiceImpl. Imports go through
+// uow so that the templates and their audit event are committed together.
+func NewDeviceTemplateServiceImpl(log applog.AppLogger, r port.DeviceTemplateRepo, uow port.UnitOfWork, v *validator.Validate) *DeviceTemplateServiceImpl {
+	return &DeviceTemplateServiceImpl{repo: r, log: log, uow: uow, v: v}
 }
 
-func NewDeviceTemplateServiceImpl(log applog.AppLogger, r port.DeviceTemplateRepo) *DeviceTemplateServiceImpl {
-	return &DeviceTemplateServiceImpl{repo: r, log: log}
+func (s *DeviceTemplateServiceImpl) RetrieveDeviceTemplates(ctx context.Context) (_ []entity.DeviceTemplate, err error) {
+	ctx, span := tracing.Start(ctx, "device_template.list")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("DeviceTemplateServiceImpl: RetrieveDeviceTemplates called")
+	items, err := s.repo.GetDeviceTemplates(ctx)
+	if err != nil {
+		return nil, mapRepoErr("list device templates", err)
+	}
+	return items, nil
 }
 
-func (s *DeviceTemplateServiceImpl) RetrieveDeviceTemplates() ([]entity.DeviceTemplate, error) {
-	s.log.Trace("DeviceTemplateServiceImpl: RetrieveDeviceTemplates called")
-	return s.repo.GetDeviceTemplates()
+func (s *DeviceTemplateServiceImpl) ImportDeviceTemplates(ctx context.Context, ts []entity.DeviceTemplate) (created, updated int, err error) {
+	ctx, span := tracing.Start(ctx, "device_template.import")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("device_template.import", "count", len(ts))
+
+	var violations []apperr.FieldViolation
+	seen := make(map[string]int, len(ts))
+	for i := range ts {
+		if err := s.v.Struct(&ts[i]); err != nil {
+			for _, fv := range validation.Violations(err) {
+				fv.Field = fmt.Sprintf("[%d].%s", i, fv.Field)
+				violations = append(violations, fv)
+			}
+		}
+		if j, ok := seen[ts[i].Name]; ok {
+			violations = append(violations, apperr.FieldViolation{
+				Field: fmt.Sprintf("[%d].name", i), Rule: "unique",
+				Message: fmt.Sprintf("duplicates the name of template %d", j),
+			})
+		}
+		seen[ts[i].Name] = i
+	}
+	if len(violations) > 0 {
+		return 0, 0, apperr.NewInvalidArgErr("invalid device templates", nil).WithDetails(violations...)
+	}
+
+	err = s.uow.Do(ctx, func(tx port.TxRepos) error {
+		created, updated, err = tx.DeviceTemplates().UpsertDeviceTemplates(ctx, ts)
+		if err != nil {
+			return err
+		}
+		e := newAuditEvent(ctx, entity.AuditActionTemplateImport, entity.AuditResourceDeviceTemplate, "", nil)
+		e.Details["created"], e.Details["updated"] = created, updated
+		return tx.AuditEvents().CreateAuditEvent(ctx, e)
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("device_template.import failed", "error", err)
+		return 0, 0, mapRepoErr("import device templates", err)
+	}
+	return created, updated, nil
 }
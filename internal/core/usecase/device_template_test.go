@@ -1,10 +1,14 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/validation"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -17,7 +21,7 @@ type deviceTemplateRepoMock struct {
 	called    bool
 }
 
-func (m *deviceTemplateRepoMock) GetDeviceTemplates() ([]entity.DeviceTemplate, error) {
+func (m *deviceTemplateRepoMock) GetDeviceTemplates(context.Context) ([]entity.DeviceTemplate, error) {
 	m.called = true
 	if m.err != nil {
 		return nil, m.err
@@ -25,14 +29,23 @@ func (m *deviceTemplateRepoMock) GetDeviceTemplates() ([]entity.DeviceTemplate,
 	return m.templates, nil
 }
 
-func (m *deviceTemplateRepoMock) GetDeviceTemplateByID(_ *uuid.UUID) (*entity.DeviceTemplate, error) {
+func (m *deviceTemplateRepoMock) GetDeviceTemplateByID(_ context.Context, _ *uuid.UUID) (*entity.DeviceTemplate, error) {
 	return nil, errors.New("not implemented")
 }
 
+func (m *deviceTemplateRepoMock) UpsertDeviceTemplates(_ context.Context, ts []entity.DeviceTemplate) (int, int, error) {
+	m.called = true
+	if m.err != nil {
+		return 0, 0, m.err
+	}
+	m.templates = append(m.templates, ts...)
+	return len(ts), 0, nil
+}
+
 // Ensure interface compliance.
 var _ interface {
-	GetDeviceTemplates() ([]entity.DeviceTemplate, error)
-	GetDeviceTemplateByID(*uuid.UUID) (*entity.DeviceTemplate, error)
+	GetDeviceTemplates(context.Context) ([]entity.DeviceTemplate, error)
+	GetDeviceTemplateByID(context.Context, *uuid.UUID) (*entity.DeviceTemplate, error)
 } = (*deviceTemplateRepoMock)(nil)
 
 type noopLogger struct{}
@@ -44,12 +57,23 @@ func (noopLogger) Debug(string, ...any) {}
 func (noopLogger) Trace(string, ...any) {}
 func (noopLogger) Fatal(string, ...any) {}
 
+func (noopLogger) Infof(string, ...any)  {}
+func (noopLogger) Warnf(string, ...any)  {}
+func (noopLogger) Errorf(string, ...any) {}
+func (noopLogger) Debugf(string, ...any) {}
+func (noopLogger) Tracef(string, ...any) {}
+func (noopLogger) Fatalf(string, ...any) {}
+
+func (l noopLogger) With(...any) applog.AppLogger { return l }
+
+func (l noopLogger) WithContext(context.Context) applog.AppLogger { return l }
+
 func TestDeviceTemplateService_RetrieveDeviceTemplates(t *testing.T) {
 	want := []entity.DeviceTemplate{{Name: "Desktop"}}
 	repo := &deviceTemplateRepoMock{templates: want}
-	svc := NewDeviceTemplateServiceImpl(noopLogger{}, repo)
+	svc := NewDeviceTemplateServiceImpl(noopLogger{}, repo, nil, validation.New())
 
-	got, err := svc.RetrieveDeviceTemplates()
+	got, err := svc.RetrieveDeviceTemplates(context.Background())
 	require.NoError(t, err)
 	assert.True(t, repo.called, "expected repo to be called")
 	assert.Equal(t, want, got)
@@ -57,9 +81,44 @@ func TestDeviceTemplateService_RetrieveDeviceTemplates(t *testing.T) {
 
 func TestDeviceTemplateService_RetrieveDeviceTemplatesError(t *testing.T) {
 	repo := &deviceTemplateRepoMock{err: errors.New("boom")}
-	svc := NewDeviceTemplateServiceImpl(noopLogger{}, repo)
+	svc := NewDeviceTemplateServiceImpl(noopLogger{}, repo, nil, validation.New())
 
-	_, err := svc.RetrieveDeviceTemplates()
+	_, err := svc.RetrieveDeviceTemplates(context.Background())
 	require.Error(t, err)
 	assert.True(t, repo.called, "expected repo to be called")
 }
+
+func TestDeviceTemplateService_ImportDeviceTemplates(t *testing.T) {
+	repo := &deviceTemplateRepoMock{}
+	uow := &mockUnitOfWork{templates: repo, audit: &mockAuditRepo{}}
+	svc := NewDeviceTemplateServiceImpl(noopLogger{}, repo, uow, validation.New())
+
+	created, updated, err := svc.ImportDeviceTemplates(context.Background(), []entity.DeviceTemplate{
+		{Name: "Desktop", DeviceType: "desktop", UserAgent: "Mozilla/5.0"},
+		{Name: "Mobile", DeviceType: "mobile", UserAgent: "Mozilla/5.0"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, created)
+	assert.Zero(t, updated)
+	require.Len(t, uow.audit.events, 1)
+	assert.Equal(t, entity.AuditActionTemplateImport, uow.audit.events[0].Action)
+}
+
+func TestDeviceTemplateService_ImportDeviceTemplatesInvalid(t *testing.T) {
+	repo := &deviceTemplateRepoMock{}
+	uow := &mockUnitOfWork{templates: repo, audit: &mockAuditRepo{}}
+	svc := NewDeviceTemplateServiceImpl(noopLogger{}, repo, uow, validation.New())
+
+	_, _, err := svc.ImportDeviceTemplates(context.Background(), []entity.DeviceTemplate{
+		{Name: "Desktop", DeviceType: "desktop", UserAgent: "Mozilla/5.0"},
+		{Name: "Desktop", DeviceType: "tablet", UserAgent: "Mozilla/5.0"},
+	})
+	var inv *apperr.InvalidArgErr
+	require.ErrorAs(t, err, &inv)
+	var fields []string
+	for _, v := range inv.Details() {
+		fields = append(fields, v.Field)
+	}
+	assert.ElementsMatch(t, []string{"[1].device_type", "[1].name"}, fields)
+	assert.False(t, repo.called, "expected nothing to be stored")
+}
@@ -0,0 +1,277 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/datatypes"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/tracing"
+)
+
+// quotaCacheTTL bounds how long resolved limits are reused, and so how long a per-user
+// override written to the database takes to apply.
+const quotaCacheTTL = 30 * time.Second
+
+// cachedQuota is a user's plan and effective limits as resolved at some point.
+type cachedQuota struct {
+	plan    string
+	quota   entity.Quota
+	expires time.Time
+}
+
+// requestWindow counts the requests a user issued during a one-minute window.
+type requestWindow struct {
+	start time.Time
+	count int
+}
+
+// QuotaServiceImpl enforces plan limits, optionally overridden per user.
+type QuotaServiceImpl struct {
+	log         applog.AppLogger
+	repo        port.QuotaRepo
+	profileRepo port.DeviceProfileRepo
+	now         func() time.Time
+
+	plansMu     sync.RWMutex
+	plans       map[string]entity.Quota
+	defaultPlan string
+
+	mu        sync.Mutex
+	windows   map[string]*requestWindow
+	lastSweep time.Time
+
+	cacheMu    sync.Mutex
+	cache      map[string]cachedQuota
+	cacheGen   uint64
+	cacheSweep time.Time
+}
+
+// NewQuotaServiceImpl constructs a QuotaServiceImpl with the configured plans. Users whose
+// plan is not listed get the limits of defaultPlan.
+func NewQuotaServiceImpl(log applog.AppLogger, qr port.QuotaRepo, dpr port.DeviceProfileRepo, plans map[string]entity.Quota, defaultPlan string) *QuotaServiceImpl {
+	return &QuotaServiceImpl{
+		log:         log,
+		repo:        qr,
+		profileRepo: dpr,
+		plans:       plans,
+		defaultPlan: defaultPlan,
+		now:         time.Now,
+		windows:     make(map[string]*requestWindow),
+		cache:       make(map[string]cachedQuota),
+	}
+}
+
+// SetPlans replaces the plans and the default plan, e.g. after a configuration reload.
+// Requests counted in the current window are kept; cached limits are dropped.
+func (s *QuotaServiceImpl) SetPlans(plans map[string]entity.Quota, defaultPlan string) {
+	s.plansMu.Lock()
+	s.plans, s.defaultPlan = plans, defaultPlan
+	s.plansMu.Unlock()
+
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	clear(s.cache)
+	s.cacheGen++
+}
+
+func (s *QuotaServiceImpl) ConsumeRequest(ctx context.Context, userID string) (err error) {
+	ctx, span := tracing.Start(ctx, "quota.consume_request")
+	defer tracing.End(span, &err)
+	_, q, err := s.resolveQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if q.RequestsPerMinute <= 0 {
+		return nil
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.windows[userID]
+	if w == nil || now.Sub(w.start) >= time.Minute {
+		s.evictExpiredWindows(now)
+		w = &requestWindow{start: now.Truncate(time.Minute)}
+		s.windows[userID] = w
+	}
+	if w.count >= q.RequestsPerMinute {
+		return apperr.NewQuotaExceededErr(entity.QuotaRequestsPerMinute,
+			fmt.Sprintf("request rate limit of %d per minute exceeded", q.RequestsPerMinute), nil).
+			WithRetryAfter(w.start.Add(time.Minute).Sub(now))
+	}
+	w.count++
+	return nil
+}
+
+func (s *QuotaServiceImpl) CheckDeviceProfileCreate(ctx context.Context, tx port.TxRepos, userID string, dp *entity.DeviceProfile) (err error) {
+	ctx, span := tracing.Start(ctx, "quota.check_device_profile_create")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("quota.check_device_profile_create", "user_id", userID)
+	// Locking the user before counting keeps a concurrent create from inserting between
+	// the count and the insert of this one.
+	gen := s.cacheGeneration()
+	plan, override, err := tx.Quotas().LockUserQuota(ctx, userID)
+	if err != nil {
+		return mapRepoErr("lock user quota", err)
+	}
+	q := s.limits(plan, override)
+	s.storeQuota(userID, plan, q, gen)
+
+	if q.MaxProfiles > 0 {
+		count, err := tx.DeviceProfiles().CountDeviceProfiles(ctx, userID)
+		if err != nil {
+			return mapRepoErr("count device profiles", err)
+		}
+		if count >= int64(q.MaxProfiles) {
+			return apperr.NewQuotaExceededErr(entity.QuotaMaxProfiles,
+				fmt.Sprintf("device profile limit of %d reached", q.MaxProfiles), nil)
+		}
+	}
+	return checkHeaders(q, dp.CustomHeaders)
+}
+
+func (s *QuotaServiceImpl) CheckCustomHeaders(ctx context.Context, userID string, headers datatypes.JSONMap) (err error) {
+	ctx, span := tracing.Start(ctx, "quota.check_custom_headers")
+	defer tracing.End(span, &err)
+	_, q, err := s.resolveQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return checkHeaders(q, headers)
+}
+
+func (s *QuotaServiceImpl) GetUsage(ctx context.Context) (_ *entity.QuotaUsage, err error) {
+	ctx, span := tracing.Start(ctx, "quota.get_usage")
+	defer tracing.End(span, &err)
+	p, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := p.UserID.String()
+	s.log.WithContext(ctx).Trace("quota.get_usage", "user_id", userID)
+
+	plan, q, err := s.resolveQuota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.profileRepo.CountDeviceProfiles(ctx, userID)
+	if err != nil {
+		return nil, mapRepoErr("count device profiles", err)
+	}
+
+	usage := &entity.QuotaUsage{Plan: plan, Limits: q, Profiles: count}
+	s.mu.Lock()
+	if w := s.windows[userID]; w != nil && s.now().Sub(w.start) < time.Minute {
+		usage.RequestsInCurrentMinute = w.count
+	}
+	s.mu.Unlock()
+	return usage, nil
+}
+
+// resolveQuota returns the user's plan name and the effective limits after applying
+// overrides. They are cached for quotaCacheTTL, so most requests skip the query.
+func (s *QuotaServiceImpl) resolveQuota(ctx context.Context, userID string) (string, entity.Quota, error) {
+	s.cacheMu.Lock()
+	c, ok := s.cache[userID]
+	gen := s.cacheGen
+	s.cacheMu.Unlock()
+	if ok && s.now().Before(c.expires) {
+		return c.plan, c.quota, nil
+	}
+
+	plan, override, err := s.repo.GetUserQuota(ctx, userID)
+	if err != nil {
+		return "", entity.Quota{}, mapRepoErr("get user quota", err)
+	}
+	q := s.limits(plan, override)
+	s.storeQuota(userID, plan, q, gen)
+	return plan, q, nil
+}
+
+func (s *QuotaServiceImpl) cacheGeneration() uint64 {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	return s.cacheGen
+}
+
+// storeQuota caches the limits resolved for the user, unless the cache was cleared since
+// generation gen was read, as they may predate the plans now in effect.
+func (s *QuotaServiceImpl) storeQuota(userID, plan string, q entity.Quota, gen uint64) {
+	now := s.now()
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if gen != s.cacheGen {
+		return
+	}
+	if now.Sub(s.cacheSweep) >= quotaCacheTTL {
+		s.cacheSweep = now
+		for id, c := range s.cache {
+			if !now.Before(c.expires) {
+				delete(s.cache, id)
+			}
+		}
+	}
+	s.cache[userID] = cachedQuota{plan: plan, quota: q, expires: now.Add(quotaCacheTTL)}
+}
+
+// limits returns the limits of plan, falling back to the default plan, with override applied.
+func (s *QuotaServiceImpl) limits(plan string, override *entity.UserQuota) entity.Quota {
+	s.plansMu.RLock()
+	q, ok := s.plans[plan]
+	if !ok {
+		q = s.plans[s.defaultPlan]
+	}
+	s.plansMu.RUnlock()
+
+	if override != nil {
+		applyOverride(&q.MaxProfiles, override.MaxProfiles)
+		applyOverride(&q.MaxCustomHeaders, override.MaxCustomHeaders)
+		applyOverride(&q.MaxHeaderValueSize, override.MaxHeaderValueSize)
+		applyOverride(&q.RequestsPerMinute, override.RequestsPerMinute)
+	}
+	return q
+}
+
+// evictExpiredWindows drops windows older than a minute so idle users do not accumulate.
+// It sweeps at most once per minute. Callers must hold s.mu.
+func (s *QuotaServiceImpl) evictExpiredWindows(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for id, w := range s.windows {
+		if now.Sub(w.start) >= time.Minute {
+			delete(s.windows, id)
+		}
+	}
+}
+
+func applyOverride(dst *int, v *int) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func checkHeaders(q entity.Quota, headers datatypes.JSONMap) error {
+	if q.MaxCustomHeaders > 0 && len(headers) > q.MaxCustomHeaders {
+		return apperr.NewQuotaExceededErr(entity.QuotaMaxCustomHeaders,
+			fmt.Sprintf("custom header limit of %d exceeded", q.MaxCustomHeaders), nil)
+	}
+	if q.MaxHeaderValueSize > 0 {
+		for k, v := range headers {
+			if str, ok := v.(string); ok && len(str) > q.MaxHeaderValueSize {
+				return apperr.NewQuotaExceededErr(entity.QuotaMaxHeaderValueSize,
+					fmt.Sprintf("value of header %q exceeds %d bytes", k, q.MaxHeaderValueSize), nil)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/apperr"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+type mockQuotaRepo struct {
+	plan     string
+	override *entity.UserQuota
+	locked   bool
+	gets     int
+}
+
+func (m *mockQuotaRepo) GetUserQuota(context.Context, string) (string, *entity.UserQuota, error) {
+	m.gets++
+	return m.plan, m.override, nil
+}
+
+func (m *mockQuotaRepo) LockUserQuota(context.Context, string) (string, *entity.UserQuota, error) {
+	m.locked = true
+	return m.plan, m.override, nil
+}
+
+// quotaTx returns the repositories of a transaction holding profiles and the quota of repo.
+func quotaTx(repo *mockQuotaRepo, profiles *mockDeviceProfileRepo) *mockUnitOfWork {
+	tx := newMockUnitOfWork(profiles)
+	tx.quotas = repo
+	return tx
+}
+
+var testPlans = map[string]entity.Quota{
+	"free": {MaxProfiles: 2, MaxCustomHeaders: 2, MaxHeaderValueSize: 8, RequestsPerMinute: 2},
+	"pro":  {MaxProfiles: 10},
+}
+
+func TestQuotaService_CheckDeviceProfileCreate_MaxProfiles(t *testing.T) {
+	profiles := &mockDeviceProfileRepo{
+		countFn: func(string) (int64, error) { return 2, nil },
+	}
+	repo := &mockQuotaRepo{plan: "free"}
+	svc := NewQuotaServiceImpl(noopLogger{}, repo, &mockDeviceProfileRepo{}, testPlans, "free")
+
+	err := svc.CheckDeviceProfileCreate(context.Background(), quotaTx(repo, profiles), uuid.NewString(), &entity.DeviceProfile{})
+	require.Error(t, err)
+	var qe *apperr.QuotaExceededErr
+	require.ErrorAs(t, err, &qe)
+	assert.Equal(t, entity.QuotaMaxProfiles, qe.Resource())
+	assert.True(t, repo.locked, "the user must be locked before counting its profiles")
+}
+
+func TestQuotaService_CheckDeviceProfileCreate_UserOverride(t *testing.T) {
+	profiles := &mockDeviceProfileRepo{
+		countFn: func(string) (int64, error) { return 2, nil },
+	}
+	limit := 5
+	repo := &mockQuotaRepo{plan: "free", override: &entity.UserQuota{MaxProfiles: &limit}}
+	svc := NewQuotaServiceImpl(noopLogger{}, repo, &mockDeviceProfileRepo{}, testPlans, "free")
+
+	require.NoError(t, svc.CheckDeviceProfileCreate(context.Background(), quotaTx(repo, profiles), uuid.NewString(), &entity.DeviceProfile{}))
+}
+
+func TestQuotaService_CheckCustomHeaders(t *testing.T) {
+	svc := NewQuotaServiceImpl(noopLogger{}, &mockQuotaRepo{plan: "unknown"}, &mockDeviceProfileRepo{}, testPlans, "free")
+
+	cases := []struct {
+		name     string
+		headers  datatypes.JSONMap
+		resource string
+	}{
+		{name: "within limits", headers: datatypes.JSONMap{"A": "1"}},
+		{name: "too many headers", headers: datatypes.JSONMap{"A": "1", "B": "2", "C": "3"}, resource: entity.QuotaMaxCustomHeaders},
+		{name: "value too large", headers: datatypes.JSONMap{"A": "0123456789"}, resource: entity.QuotaMaxHeaderValueSize},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := svc.CheckCustomHeaders(context.Background(), uuid.NewString(), tc.headers)
+			if tc.resource == "" {
+				require.NoError(t, err)
+				return
+			}
+			var qe *apperr.QuotaExceededErr
+			require.ErrorAs(t, err, &qe)
+			assert.Equal(t, tc.resource, qe.Resource())
+		})
+	}
+}
+
+func TestQuotaService_ConsumeRequest(t *testing.T) {
+	svc := NewQuotaServiceImpl(noopLogger{}, &mockQuotaRepo{plan: "free"}, &mockDeviceProfileRepo{}, testPlans, "free")
+	now := time.Date(2025, 1, 1, 10, 0, 5, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	userID := uuid.New()
+
+	require.NoError(t, svc.ConsumeRequest(context.Background(), userID.String()))
+	require.NoError(t, svc.ConsumeRequest(context.Background(), userID.String()))
+
+	err := svc.ConsumeRequest(context.Background(), userID.String())
+	var qe *apperr.QuotaExceededErr
+	require.ErrorAs(t, err, &qe)
+	assert.Equal(t, entity.QuotaRequestsPerMinute, qe.Resource())
+	assert.Equal(t, 55*time.Second, qe.RetryAfter(), "the window started at 10:00:00")
+
+	now = now.Add(time.Minute)
+	require.NoError(t, svc.ConsumeRequest(context.Background(), userID.String()))
+}
+
+func TestQuotaService_GetUsage(t *testing.T) {
+	profiles := &mockDeviceProfileRepo{
+		countFn: func(string) (int64, error) { return 1, nil },
+	}
+	svc := NewQuotaServiceImpl(noopLogger{}, &mockQuotaRepo{plan: "pro"}, profiles, testPlans, "free")
+	userID := uuid.New()
+	require.NoError(t, svc.ConsumeRequest(context.Background(), userID.String()))
+
+	usage, err := svc.GetUsage(principalContext(userID))
+	require.NoError(t, err)
+	assert.Equal(t, "pro", usage.Plan)
+	assert.Equal(t, 10, usage.Limits.MaxProfiles)
+	assert.Equal(t, int64(1), usage.Profiles)
+	assert.Equal(t, 0, usage.RequestsInCurrentMinute, "pro plan has no request limit so nothing is counted")
+}
+
+func TestQuotaService_SetPlans(t *testing.T) {
+	profiles := &mockDeviceProfileRepo{
+		countFn: func(string) (int64, error) { return 2, nil },
+	}
+	repo := &mockQuotaRepo{plan: "free"}
+	svc := NewQuotaServiceImpl(noopLogger{}, repo, profiles, testPlans, "free")
+	tx := quotaTx(repo, profiles)
+	require.Error(t, svc.CheckDeviceProfileCreate(context.Background(), tx, uuid.NewString(), &entity.DeviceProfile{}))
+
+	svc.SetPlans(map[string]entity.Quota{"free": {MaxProfiles: 3}}, "free")
+	assert.NoError(t, svc.CheckDeviceProfileCreate(context.Background(), tx, uuid.NewString(), &entity.DeviceProfile{}))
+}
+
+func TestQuotaService_CachesResolvedQuota(t *testing.T) {
+	repo := &mockQuotaRepo{plan: "free"}
+	svc := NewQuotaServiceImpl(noopLogger{}, repo, &mockDeviceProfileRepo{}, testPlans, "free")
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	userID := uuid.NewString()
+	headers := datatypes.JSONMap{"A": "1", "B": "2", "C": "3"}
+
+	require.Error(t, svc.CheckCustomHeaders(context.Background(), userID, headers))
+	require.Error(t, svc.CheckCustomHeaders(context.Background(), userID, headers))
+	assert.Equal(t, 1, repo.gets, "the second check is served from the cache")
+
+	svc.SetPlans(map[string]entity.Quota{"free": {MaxCustomHeaders: 3}}, "free")
+	require.NoError(t, svc.CheckCustomHeaders(context.Background(), userID, headers))
+	assert.Equal(t, 2, repo.gets, "new plans drop the cache")
+
+	limit := 1
+	repo.override = &entity.UserQuota{MaxCustomHeaders: &limit}
+	require.NoError(t, svc.CheckCustomHeaders(context.Background(), userID, headers))
+	now = now.Add(quotaCacheTTL)
+	require.Error(t, svc.CheckCustomHeaders(context.Background(), userID, headers), "the override applies once the entry expires")
+	assert.Equal(t, 3, repo.gets)
+}
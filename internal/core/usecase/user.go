@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/tracing"
+	"zenrows-challenge/internal/pkg/validation"
+)
+
+// Password length bounds. bcrypt ignores everything past 72 bytes, so longer passwords
+// are refused rather than silently truncated.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// UserAdminServiceImpl manages user accounts on behalf of operators. Every change is
+// audited together with the change itself.
+type UserAdminServiceImpl struct {
+	log  applog.AppLogger
+	repo port.UserRepo
+	uow  port.UnitOfWork
+	v    *validator.Validate
+}
+
+// NewUserAdminServiceImpl constructs a UserAdminServiceImpl.
+func NewUserAdminServiceImpl(log applog.AppLogger, r port.UserRepo, uow port.UnitOfWork, v *validator.Validate) *UserAdminServiceImpl {
+	return &UserAdminServiceImpl{log: log, repo: r, uow: uow, v: v}
+}
+
+func (s *UserAdminServiceImpl) CreateUser(ctx context.Context, u *entity.User, password redact.Secret) (err error) {
+	ctx, span := tracing.Start(ctx, "user.create")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("user.create", "username", u.Username, "role", u.Role)
+
+	var violations []apperr.FieldViolation
+	if err := s.v.Var(u.Username, "required,min=3,max=64"); err != nil {
+		violations = append(violations, validation.VarViolations("username", err)...)
+	}
+	if err := s.v.Var(u.Role, "omitempty,oneof="+entity.UserRoleUser+" "+entity.UserRoleAdmin); err != nil {
+		violations = append(violations, validation.VarViolations("role", err)...)
+	}
+	violations = append(violations, passwordViolations(password)...)
+	if len(violations) > 0 {
+		return apperr.NewInvalidArgErr("invalid user", nil).WithDetails(violations...)
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+
+	err = s.uow.Do(ctx, func(tx port.TxRepos) error {
+		if err := tx.Users().CreateUser(ctx, u); err != nil {
+			return err
+		}
+		return tx.AuditEvents().CreateAuditEvent(ctx, newAuditEvent(ctx, entity.AuditActionUserCreate,
+			entity.AuditResourceUser, u.ID.String(), nil))
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("user.create failed", "error", err)
+		return mapRepoErr("create user", err)
+	}
+	return nil
+}
+
+func (s *UserAdminServiceImpl) ChangePassword(ctx context.Context, username string, password redact.Secret) (err error) {
+	ctx, span := tracing.Start(ctx, "user.change_password")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("user.change_password", "username", username)
+
+	if violations := passwordViolations(password); len(violations) > 0 {
+		return apperr.NewInvalidArgErr("invalid password", nil).WithDetails(violations...)
+	}
+	u, err := s.repo.GetUserByUsername(ctx, username)
+	if err != nil {
+		return mapRepoErr("get user", err)
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.uow.Do(ctx, func(tx port.TxRepos) error {
+		if err := tx.Users().UpdatePasswordHash(ctx, u.ID, hash); err != nil {
+			return err
+		}
+		return tx.AuditEvents().CreateAuditEvent(ctx, newAuditEvent(ctx, entity.AuditActionUserPasswordChange,
+			entity.AuditResourceUser, u.ID.String(), nil))
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("user.change_password failed", "error", err)
+		return mapRepoErr("change password", err)
+	}
+	return nil
+}
+
+func (s *UserAdminServiceImpl) DisableUser(ctx context.Context, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "user.disable")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("user.disable", "username", username)
+
+	u, err := s.repo.GetUserByUsername(ctx, username)
+	if err != nil {
+		return mapRepoErr("get user", err)
+	}
+	if u.DisabledAt != nil {
+		// Disabling is idempotent; the original disable was audited already.
+		return nil
+	}
+
+	err = s.uow.Do(ctx, func(tx port.TxRepos) error {
+		if err := tx.Users().DisableUser(ctx, u.ID, time.Now()); err != nil {
+			return err
+		}
+		return tx.AuditEvents().CreateAuditEvent(ctx, newAuditEvent(ctx, entity.AuditActionUserDisable,
+			entity.AuditResourceUser, u.ID.String(), nil))
+	})
+	if err != nil {
+		s.log.WithContext(ctx).Error("user.disable failed", "error", err)
+		return mapRepoErr("disable user", err)
+	}
+	return nil
+}
+
+func (s *UserAdminServiceImpl) ListUsers(ctx context.Context) (_ []entity.User, err error) {
+	ctx, span := tracing.Start(ctx, "user.list")
+	defer tracing.End(span, &err)
+	s.log.WithContext(ctx).Trace("user.list")
+	items, err := s.repo.ListUsers(ctx)
+	if err != nil {
+		return nil, mapRepoErr("list users", err)
+	}
+	return items, nil
+}
+
+func passwordViolations(password redact.Secret) []apperr.FieldViolation {
+	switch n := len(password.Reveal()); {
+	case n < minPasswordLength:
+		return []apperr.FieldViolation{{Field: "password", Rule: "min", Param: "8", Message: "must be at least 8 characters long"}}
+	case n > maxPasswordLength:
+		return []apperr.FieldViolation{{Field: "password", Rule: "max", Param: "72", Message: "must be at most 72 bytes long"}}
+	}
+	return nil
+}
+
+func hashPassword(password redact.Secret) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password.Reveal()), bcrypt.DefaultCost)
+	if err != nil {
+		return "", apperr.NewInternalErr("hash password", err)
+	}
+	return string(hash), nil
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/validation"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type mockUserRepo struct {
+	users map[string]*entity.User
+}
+
+func newMockUserRepo(users ...entity.User) *mockUserRepo {
+	m := &mockUserRepo{users: map[string]*entity.User{}}
+	for i := range users {
+		m.users[users[i].Username] = &users[i]
+	}
+	return m
+}
+
+func (m *mockUserRepo) RetrieveCredentials(context.Context, entity.User) (string, string, error) {
+	return "", "", errors.New("not implemented")
+}
+
+func (m *mockUserRepo) GetUserByID(context.Context, string) (*entity.User, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUserRepo) GetUserByUsername(_ context.Context, username string) (*entity.User, error) {
+	if u, ok := m.users[username]; ok {
+		return u, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *mockUserRepo) ListUsers(context.Context) ([]entity.User, error) {
+	out := make([]entity.User, 0, len(m.users))
+	for _, u := range m.users {
+		out = append(out, *u)
+	}
+	return out, nil
+}
+
+func (m *mockUserRepo) CreateUser(_ context.Context, u *entity.User) error {
+	u.ID = uuid.New()
+	m.users[u.Username] = u
+	return nil
+}
+
+func (m *mockUserRepo) UpdatePasswordHash(_ context.Context, id uuid.UUID, hash string) error {
+	for _, u := range m.users {
+		if u.ID == id {
+			u.PasswordHash = hash
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *mockUserRepo) DisableUser(_ context.Context, id uuid.UUID, at time.Time) error {
+	for _, u := range m.users {
+		if u.ID == id {
+			u.DisabledAt = &at
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func newUserAdminService(users *mockUserRepo) (*UserAdminServiceImpl, *mockUnitOfWork) {
+	uow := &mockUnitOfWork{users: users, audit: &mockAuditRepo{}}
+	return NewUserAdminServiceImpl(noopLogger{}, users, uow, validation.New()), uow
+}
+
+func TestUserAdminService_CreateUser(t *testing.T) {
+	users := newMockUserRepo()
+	svc, uow := newUserAdminService(users)
+
+	u := &entity.User{Username: "carol", Role: entity.UserRoleAdmin}
+	require.NoError(t, svc.CreateUser(context.Background(), u, redact.Secret("correct horse")))
+
+	stored := users.users["carol"]
+	require.NotNil(t, stored)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("correct horse")))
+	require.Len(t, uow.audit.events, 1)
+	assert.Equal(t, entity.AuditActionUserCreate, uow.audit.events[0].Action)
+	assert.Equal(t, u.ID.String(), *uow.audit.events[0].ResourceID)
+}
+
+func TestUserAdminService_CreateUserInvalid(t *testing.T) {
+	cases := []struct {
+		name     string
+		user     entity.User
+		password string
+		field    string
+	}{
+		{name: "short username", user: entity.User{Username: "ab"}, password: "long enough", field: "username"},
+		{name: "unknown role", user: entity.User{Username: "carol", Role: "root"}, password: "long enough", field: "role"},
+		{name: "short password", user: entity.User{Username: "carol"}, password: "short", field: "password"},
+		{name: "password past the bcrypt limit", user: entity.User{Username: "carol"}, password: strings.Repeat("x", 73), field: "password"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := newMockUserRepo()
+			svc, _ := newUserAdminService(users)
+
+			err := svc.CreateUser(context.Background(), &tc.user, redact.Secret(tc.password))
+			var inv *apperr.InvalidArgErr
+			require.ErrorAs(t, err, &inv)
+			require.Len(t, inv.Details(), 1)
+			assert.Equal(t, tc.field, inv.Details()[0].Field)
+			assert.Empty(t, users.users)
+		})
+	}
+}
+
+func TestUserAdminService_ChangePassword(t *testing.T) {
+	users := newMockUserRepo(entity.User{ID: uuid.New(), Username: "alice", PasswordHash: "old"})
+	svc, uow := newUserAdminService(users)
+
+	require.NoError(t, svc.ChangePassword(context.Background(), "alice", redact.Secret("new password")))
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(users.users["alice"].PasswordHash), []byte("new password")))
+	require.Len(t, uow.audit.events, 1)
+	assert.Equal(t, entity.AuditActionUserPasswordChange, uow.audit.events[0].Action)
+
+	var nf *apperr.NotFoundErr
+	assert.ErrorAs(t, svc.ChangePassword(context.Background(), "nobody", redact.Secret("new password")), &nf)
+}
+
+func TestUserAdminService_DisableUser(t *testing.T) {
+	users := newMockUserRepo(entity.User{ID: uuid.New(), Username: "alice"})
+	svc, uow := newUserAdminService(users)
+
+	require.NoError(t, svc.DisableUser(context.Background(), "alice"))
+	assert.NotNil(t, users.users["alice"].DisabledAt)
+	require.Len(t, uow.audit.events, 1)
+	assert.Equal(t, entity.AuditActionUserDisable, uow.audit.events[0].Action)
+
+	disabledAt := *users.users["alice"].DisabledAt
+	require.NoError(t, svc.DisableUser(context.Background(), "alice"), "disabling again is a no-op")
+	assert.Equal(t, disabledAt, *users.users["alice"].DisabledAt)
+	assert.Len(t, uow.audit.events, 1)
+}
@@ -2,10 +2,18 @@ package infra
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/ratelimit"
+	"zenrows-challenge/internal/pkg/redact"
 
 	"github.com/spf13/viper"
 )
@@ -15,6 +23,21 @@ const (
 	defaultConfigName = "local"
 	envConfigNameKey  = "CONFIG_NAME"
 	envPrefix         = "ZENROWS"
+
+	defaultQuotaPlan = "free"
+
+	defaultDisconnectCheck = time.Second
+
+	defaultIdempotencyTTL   = 24 * time.Hour
+	defaultIdempotencyLease = 30 * time.Second
+	// idempotencyLeaseMargin is added to the request timeout so a lease outlives the
+	// request holding it.
+	idempotencyLeaseMargin = 5 * time.Second
+
+	// RateLimitStoreMemory keeps rate limit buckets in process memory.
+	RateLimitStoreMemory = "memory"
+	// RateLimitStorePostgres shares rate limit buckets between instances through Postgres.
+	RateLimitStorePostgres = "postgres"
 )
 
 var (
@@ -22,7 +45,10 @@ var (
 	loadConfigErr  error
 )
 
-// LoadConfig makes Viper read the desired configuration file and wire env overrides.
+// LoadConfig makes Viper read the desired configuration file and wire env overrides,
+// including <VAR>_FILE variables naming files holding a value, then decodes and
+// validates the result into the Config returned by Current. Unknown keys, in the file
+// or as ZENROWS_* variables, and invalid values fail loading.
 func LoadConfig() error {
 	loadConfigOnce.Do(func() {
 		configName := strings.TrimSpace(os.Getenv(envConfigNameKey))
@@ -37,11 +63,22 @@ func LoadConfig() error {
 		viper.SetEnvPrefix(envPrefix)
 		viper.AutomaticEnv()
 		viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+		registerDefaults(viper.GetViper())
 
 		if err := viper.ReadInConfig(); err != nil {
 			loadConfigErr = fmt.Errorf("infra: failed to read config %q: %w", configName, err)
 			return
 		}
+		if err := applySecretFiles(viper.GetViper()); err != nil {
+			loadConfigErr = fmt.Errorf("infra: failed to load secrets: %w", err)
+			return
+		}
+		cfg, err := decodeConfig(viper.GetViper())
+		if err != nil {
+			loadConfigErr = fmt.Errorf("infra: invalid config %q: %w", viper.ConfigFileUsed(), err)
+			return
+		}
+		current.Store(cfg)
 	})
 
 	return loadConfigErr
@@ -59,3 +96,204 @@ func registerConfigPaths() {
 		viper.AddConfigPath(p)
 	}
 }
+
+// QuotaPlans returns the quota limits configured under quota.plans keyed by plan
+// name, together with the plan applied to users whose plan is not configured. Plans
+// are reloadable; see OnReload.
+func QuotaPlans() (map[string]entity.Quota, string) {
+	cfg := Current().Quota
+	plans := make(map[string]entity.Quota, len(cfg.Plans))
+	for name, p := range cfg.Plans {
+		plans[name] = entity.Quota{
+			MaxProfiles:        p.MaxProfiles,
+			MaxCustomHeaders:   p.MaxCustomHeaders,
+			MaxHeaderValueSize: p.MaxHeaderValueSize,
+			RequestsPerMinute:  p.RequestsPerMinute,
+		}
+	}
+	return plans, cfg.DefaultPlan
+}
+
+// RateLimitEnabled reports whether the rate limiting middleware should be installed.
+func RateLimitEnabled() bool {
+	return Current().RateLimit.Enabled
+}
+
+// RateLimitStore returns the configured bucket store kind, defaulting to memory.
+func RateLimitStore() string {
+	return Current().RateLimit.Store
+}
+
+// RateLimitPolicy returns the read and write limits configured under rate_limit.groups.<group>.
+// The groups are reloadable, so callers should not keep the result.
+func RateLimitPolicy(group string) ratelimit.Policy {
+	g := Current().RateLimit.Groups[group]
+	return ratelimit.Policy{
+		Name:  group,
+		Read:  ratelimit.Limit{Rate: g.Read.Rate, Burst: g.Read.Burst},
+		Write: ratelimit.Limit{Rate: g.Write.Rate, Burst: g.Write.Burst},
+	}
+}
+
+// ValidateRequests reports whether requests are checked against the OpenAPI document
+// before reaching the handlers.
+func ValidateRequests() bool {
+	return Current().OpenAPI.ValidateRequests
+}
+
+// ValidateResponses reports whether responses are checked against the OpenAPI document,
+// replacing mismatching ones with a 500. Meant for development and tests.
+func ValidateResponses() bool {
+	return Current().OpenAPI.ValidateResponses
+}
+
+// IdempotencyTTL returns how long responses stored for an Idempotency-Key are replayed.
+func IdempotencyTTL() time.Duration {
+	if ttl := Current().Idempotency.TTL; ttl > 0 {
+		return ttl
+	}
+	return defaultIdempotencyTTL
+}
+
+// IdempotencyLease returns how long a request holds its Idempotency-Key before a retry
+// may take it over. It defaults to a little more than server.request_timeout.
+func IdempotencyLease() time.Duration {
+	if lease := Current().Idempotency.Lease; lease > 0 {
+		return lease
+	}
+	if timeout := RequestTimeout(); timeout > 0 {
+		return timeout + idempotencyLeaseMargin
+	}
+	return defaultIdempotencyLease
+}
+
+// AdminPort returns the port of the admin listener, or "" to serve the admin endpoints
+// on the API port.
+func AdminPort() string {
+	if p := Current().Server.AdminPort; p > 0 {
+		return strconv.Itoa(p)
+	}
+	return ""
+}
+
+// AdminToken returns the bearer token required by the admin endpoints other than /metrics.
+// When empty, they are only served on a separate admin listener.
+func AdminToken() string {
+	return Current().Server.AdminToken
+}
+
+// MaskResponses reports whether sensitive custom header values are masked in list responses.
+func MaskResponses() bool {
+	return Current().Redaction.MaskResponses
+}
+
+// Redactor returns the redactor configured under redaction, failing on an invalid pattern.
+func Redactor() (*redact.Redactor, error) {
+	cfg := Current().Redaction
+	return redact.New(cfg.Headers, cfg.LogKeys, cfg.Patterns)
+}
+
+// NewLogger returns the logger configured under log, writing to w and masking secrets as
+// configured under redaction. It panics on an invalid redaction pattern, which
+// LoadConfig rejects.
+func NewLogger(w io.Writer) *applog.DefaultLogger {
+	r, err := Redactor()
+	if err != nil {
+		panic(err)
+	}
+	cfg := Current().Log
+	return applog.New(w, applog.Options{
+		Level:  cfg.Level,
+		Format: cfg.Format,
+		TraceSampling: applog.SamplingOptions{
+			Initial:    cfg.TraceSampling.Initial,
+			Thereafter: cfg.TraceSampling.Thereafter,
+			Tick:       cfg.TraceSampling.Tick,
+		},
+		Redactor: r,
+	})
+}
+
+// MigrateOnStart reports whether the service applies pending schema migrations before
+// serving requests.
+func MigrateOnStart() bool {
+	return Current().Database.Migrations.RunOnStart
+}
+
+// SeedOnStart reports whether the service runs the seed scripts after migrating.
+func SeedOnStart() bool {
+	return Current().Database.Migrations.Seed
+}
+
+// HealthCheckTimeout returns the time each readiness check may take.
+func HealthCheckTimeout() time.Duration {
+	return Current().Health.CheckTimeout
+}
+
+// MaxPoolUtilization returns the share of pool connections in use above which the
+// instance reports itself not ready.
+func MaxPoolUtilization() float64 {
+	return Current().Health.MaxPoolUtilization
+}
+
+// DrainDelay returns how long readiness fails before the server stops accepting
+// requests on shutdown.
+func DrainDelay() time.Duration {
+	return Current().Server.DrainDelay
+}
+
+// ShutdownTimeout returns how long shutdown may take in total, drain delay included,
+// before the remaining components are abandoned.
+func ShutdownTimeout() time.Duration {
+	return Current().Server.ShutdownTimeout
+}
+
+// MetricsEnabled reports whether /metrics is served.
+func MetricsEnabled() bool {
+	return Current().Metrics.Enabled
+}
+
+// MetricsToken returns the bearer token required to scrape /metrics; empty leaves it open.
+func MetricsToken() string {
+	return Current().Metrics.Token
+}
+
+// RequestTimeout returns how long a request may run before it is answered with 504.
+// Zero disables the limit.
+func RequestTimeout() time.Duration {
+	return Current().Server.RequestTimeout
+}
+
+// DisconnectCheck returns how often running requests check whether the client closed the
+// connection. Zero disables the check.
+func DisconnectCheck() time.Duration {
+	return Current().Server.DisconnectCheck
+}
+
+// FeatureEnabled reports whether the feature flag name is set under features. Unknown
+// flags are off. Flags are reloadable.
+func FeatureEnabled(name string) bool {
+	return Current().Features[name]
+}
+
+// CORSOriginAllowed reports whether browsers may call the API from origin, which is
+// listed, or * is, under cors.allowed_origins. The list is reloadable.
+func CORSOriginAllowed(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, o := range Current().CORS.AllowedOrigins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSAllowCredentials reports whether cross-origin requests may carry credentials.
+func CORSAllowCredentials() bool {
+	return Current().CORS.AllowCredentials
+}
+
+// CORSMaxAge returns how long browsers may cache the result of a preflight request.
+func CORSMaxAge() time.Duration {
+	return Current().CORS.MaxAge
+}
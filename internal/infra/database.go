@@ -1,26 +1,198 @@
 package infra
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
-	"github.com/spf13/viper"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
+
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	defaultConnectTimeout     = 30 * time.Second
+	defaultConnectBackoff     = 500 * time.Millisecond
+	defaultConnectMaxBackoff  = 5 * time.Second
+	defaultSlowQueryThreshold = 200 * time.Millisecond
+
+	// pgInvalidCatalogName is raised by Postgres when the database does not exist.
+	pgInvalidCatalogName = "3D000"
 )
 
-func ConnectToDatabase() *gorm.DB {
-	host := viper.GetString("database.host")
-	port := viper.GetInt("database.port")
-	name := viper.GetString("database.database")
-	user := viper.GetString("database.user")
-	pass := viper.GetString("database.password")
-	ssl := viper.GetString("database.sslmode")
+// replicatedTables are the tables whose reads outside transactions go to the replica.
+// Their list and get endpoints tolerate replication lag; reads deciding on a write, such
+// as quota checks, must ask for the primary with dbresolver.Write.
+var replicatedTables = []string{"zenrows.device_profile", "zenrows.device_template"}
+
+// ConnectToDatabase opens the primary database and waits for it to accept connections,
+// retrying with backoff for up to database.connect.timeout so that the service can start
+// before Postgres does. It panics once the deadline has passed or on errors retrying
+// cannot fix, such as rejected credentials.
+func ConnectToDatabase(log applog.AppLogger) *gorm.DB {
+	cfg := Current().Database
 
-	dsn := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s", host, port, name, user, pass, ssl)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn(cfg, cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.SSLMode)), &gorm.Config{
+		Logger:               newGormLogger(log, cfg.Logger),
+		PrepareStmt:          cfg.PrepareStmt,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		panic("failed to open database: " + err.Error())
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
+		panic("failed to access connection pool: " + err.Error())
+	}
+	configurePool(sqlDB, cfg.Pool)
+	if err := waitForDatabase(log, "primary", sqlDB, cfg.Connect); err != nil {
 		panic("failed to connect database: " + err.Error())
 	}
 
+	err = db.Use(statementTimeouts{
+		read:  cfg.QueryTimeout.Read,
+		write: cfg.QueryTimeout.Write,
+	})
+	if err != nil {
+		panic("failed to register statement timeouts: " + err.Error())
+	}
+	if err := db.Use(statementTracing{}); err != nil {
+		panic("failed to register statement tracing: " + err.Error())
+	}
+	if err := metrics.RegisterDBStats(sqlDB, cfg.Database); err != nil {
+		panic("failed to register connection pool metrics: " + err.Error())
+	}
+
 	return db
 }
+
+// ConnectToReplica opens the read replica configured under database.replica, waiting
+// for it like ConnectToDatabase, and routes reads of the replicated tables on db to it.
+// It returns the replica pool, or nil when no replica is configured.
+func ConnectToReplica(log applog.AppLogger, db *gorm.DB) *sql.DB {
+	cfg := Current().Database
+	r := cfg.Replica
+	if r.Host == "" {
+		return nil
+	}
+	port, user, password, sslmode := r.Port, r.User, r.Password, r.SSLMode
+	if port == 0 {
+		port = cfg.Port
+	}
+	if user == "" {
+		user, password = cfg.User, cfg.Password
+	}
+	if sslmode == "" {
+		sslmode = cfg.SSLMode
+	}
+
+	sqlDB, err := sql.Open("pgx", dsn(cfg, r.Host, port, user, password, sslmode))
+	if err != nil {
+		panic("failed to open database replica: " + err.Error())
+	}
+	configurePool(sqlDB, cfg.Pool)
+	if err := waitForDatabase(log, "replica", sqlDB, cfg.Connect); err != nil {
+		panic("failed to connect database replica: " + err.Error())
+	}
+
+	tables := make([]any, len(replicatedTables))
+	for i, t := range replicatedTables {
+		tables[i] = t
+	}
+	err = db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{postgres.New(postgres.Config{Conn: sqlDB})},
+	}, tables...))
+	if err != nil {
+		panic("failed to register database replica: " + err.Error())
+	}
+	if err := metrics.RegisterDBStats(sqlDB, cfg.Database+"_replica"); err != nil {
+		panic("failed to register replica connection pool metrics: " + err.Error())
+	}
+	return sqlDB
+}
+
+func dsn(cfg DatabaseConfig, host string, port int, user, password, sslmode string) string {
+	s := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s", host, port, cfg.Database, user, password, sslmode)
+	// statement_timeout is passed to Postgres as a session parameter and bounds every
+	// statement, including those the per-operation deadlines below do not cover.
+	if st := cfg.StatementTimeout; st > 0 {
+		s += fmt.Sprintf(" statement_timeout=%d", st.Milliseconds())
+	}
+	return s
+}
+
+// configurePool applies the pool settings; zero values keep the database/sql defaults.
+func configurePool(db *sql.DB, cfg PoolConfig) {
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// waitForDatabase pings db until it answers, sleeping between attempts for cfg.Backoff,
+// doubled after each failure up to cfg.MaxBackoff, and gives up after cfg.Timeout. A
+// zero timeout means a single attempt.
+func waitForDatabase(log applog.AppLogger, name string, db *sql.DB, cfg ConnectConfig) error {
+	ctx := context.Background()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	return retryConnect(ctx, log, name, cfg, db.PingContext)
+}
+
+func retryConnect(ctx context.Context, log applog.AppLogger, name string, cfg ConnectConfig, ping func(context.Context) error) error {
+	delay := cfg.Backoff
+	if delay <= 0 {
+		delay = defaultConnectBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Info("Database is up", "database", name, "attempts", attempt)
+			}
+			return nil
+		}
+		if cfg.Timeout <= 0 || permanentConnectErr(err) {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ctx.Err() != nil || ok && time.Until(deadline) < delay {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+		log.Warn("Database not reachable, retrying", "database", name, "attempt", attempt, "retry_in", delay.String(), "error", err)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-t.C:
+		}
+		if delay *= 2; cfg.MaxBackoff > 0 && delay > cfg.MaxBackoff {
+			delay = cfg.MaxBackoff
+		}
+	}
+}
+
+// permanentConnectErr reports whether Postgres rejected the connection for a reason
+// waiting does not fix: bad credentials or a missing database.
+func permanentConnectErr(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "28") || pgErr.Code == pgInvalidCatalogName
+}
@@ -0,0 +1,91 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRetryConnect(t *testing.T) {
+	log := applog.NewAppLoggerTo(&bytes.Buffer{})
+	cfg := ConnectConfig{Timeout: time.Second, Backoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+	refused := errors.New("connection refused")
+
+	t.Run("succeeds once the database is up", func(t *testing.T) {
+		attempts := 0
+		err := retryConnect(context.Background(), log, "primary", cfg, func(context.Context) error {
+			if attempts++; attempts < 4 {
+				return refused
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 4, attempts)
+	})
+
+	t.Run("gives up at the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := retryConnect(ctx, log, "primary", cfg, func(context.Context) error { return refused })
+		assert.ErrorIs(t, err, refused)
+		assert.Contains(t, err.Error(), "gave up after")
+	})
+
+	t.Run("does not retry rejected credentials", func(t *testing.T) {
+		attempts := 0
+		auth := fmt.Errorf("failed to connect: %w", &pgconn.PgError{Code: "28P01"})
+		err := retryConnect(context.Background(), log, "primary", cfg, func(context.Context) error {
+			attempts++
+			return auth
+		})
+		assert.ErrorIs(t, err, auth)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("a zero timeout means a single attempt", func(t *testing.T) {
+		attempts := 0
+		err := retryConnect(context.Background(), log, "primary", ConnectConfig{}, func(context.Context) error {
+			attempts++
+			return refused
+		})
+		assert.ErrorIs(t, err, refused)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestGormLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := newGormLogger(applog.NewAppLoggerTo(&buf), DBLoggerConfig{Level: "warn", SlowThreshold: time.Second})
+	stmt := func() (string, int64) { return `SELECT * FROM "zenrows"."users" WHERE username = $1`, 0 }
+
+	l.Trace(context.Background(), time.Now(), stmt, gorm.ErrRecordNotFound)
+	l.Trace(context.Background(), time.Now(), stmt, nil)
+	assert.Empty(t, buf.String())
+
+	l.Trace(context.Background(), time.Now(), stmt, errors.New("boom"))
+	assert.Contains(t, buf.String(), "gorm: statement failed")
+	assert.Contains(t, buf.String(), "username = $1")
+
+	buf.Reset()
+	l.Trace(context.Background(), time.Now().Add(-2*time.Second), stmt, nil)
+	assert.Contains(t, buf.String(), "gorm: slow statement")
+
+	buf.Reset()
+	l.LogMode(logger.Silent).Trace(context.Background(), time.Now(), stmt, errors.New("boom"))
+	assert.Empty(t, buf.String())
+
+	sql, params := l.ParamsFilter(context.Background(), "SELECT $1", "secret")
+	assert.Equal(t, "SELECT $1", sql)
+	assert.Nil(t, params)
+}
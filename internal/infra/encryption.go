@@ -0,0 +1,47 @@
+package infra
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"zenrows-challenge/internal/pkg/envelope"
+
+	"github.com/spf13/viper"
+)
+
+// EncryptionKeyring returns the master keys encrypting custom header values, or nil when
+// encryption.enabled is off. The keys are read from encryption.key_file when it is set,
+// and from encryption.master_keys otherwise; both map key IDs to base64-encoded 32-byte
+// keys. Key IDs are case-insensitive.
+func EncryptionKeyring() (*envelope.Keyring, error) {
+	cfg := Current().Encryption
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	encoded := cfg.MasterKeys
+	if path := cfg.KeyFile; path != "" {
+		kf := viper.New()
+		kf.SetConfigFile(path)
+		kf.SetConfigType("yml")
+		if err := kf.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("infra: failed to read key file: %w", err)
+		}
+		encoded = kf.GetStringMapString("master_keys")
+	}
+
+	keys := make(map[string][]byte, len(encoded))
+	for id, s := range encoded {
+		k, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("infra: master key %q is not valid base64: %w", id, err)
+		}
+		keys[strings.ToLower(id)] = k
+	}
+	kr, err := envelope.NewKeyring(strings.ToLower(cfg.ActiveKeyID), keys)
+	if err != nil {
+		return nil, fmt.Errorf("infra: %w", err)
+	}
+	return kr, nil
+}
@@ -0,0 +1,87 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var gormLogLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+// gormLogger writes GORM records through the application logger, so that they carry the
+// request and trace IDs and go through redaction. Failed statements are logged at Error,
+// statements slower than slowThreshold at Warn and, at the info level, every statement
+// at Debug. Statements are logged with placeholders: parameter values, which may hold
+// secrets, are never written.
+type gormLogger struct {
+	log           applog.AppLogger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+func newGormLogger(log applog.AppLogger, cfg DBLoggerConfig) *gormLogger {
+	level, ok := gormLogLevels[cfg.Level]
+	if !ok {
+		level = logger.Warn
+	}
+	return &gormLogger{log: log, level: level, slowThreshold: cfg.SlowThreshold}
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	child := *l
+	child.level = level
+	return &child
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Info {
+		l.log.WithContext(ctx).Infof("gorm: "+msg, args...)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Warn {
+		l.log.WithContext(ctx).Warnf("gorm: "+msg, args...)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Error {
+		l.log.WithContext(ctx).Errorf("gorm: "+msg, args...)
+	}
+}
+
+// Trace logs a finished statement. Missing records and statements cancelled with their
+// request are expected outcomes reported by the callers, not failures.
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, context.Canceled):
+		sql, rows := fc()
+		l.log.WithContext(ctx).Error("gorm: statement failed", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds(), "error", err)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.log.WithContext(ctx).Warn("gorm: slow statement", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds(), "threshold", l.slowThreshold.String())
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		l.log.WithContext(ctx).Debug("gorm: statement", "sql", sql, "rows", rows, "duration_ms", elapsed.Milliseconds())
+	}
+}
+
+// ParamsFilter drops the parameters of logged statements.
+func (l *gormLogger) ParamsFilter(_ context.Context, sql string, _ ...any) (string, []any) {
+	return sql, nil
+}
@@ -0,0 +1,198 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// Health check statuses.
+const (
+	HealthOK       = "ok"
+	HealthFailing  = "failing"
+	HealthDraining = "draining"
+)
+
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthChecker checks one dependency of the service for /readyz.
+type HealthChecker interface {
+	// Name identifies the check in the readiness report.
+	Name() string
+	// Check returns an error when the dependency is unusable. It must honour ctx.
+	Check(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one HealthChecker. The probes are unauthenticated, so
+// why a check failed is logged rather than reported.
+type CheckResult struct {
+	Status string `json:"status"`
+}
+
+// HealthReport is the body of /livez and /readyz.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Health runs the registered checkers and serves the liveness and readiness probes.
+type Health struct {
+	log      applog.AppLogger
+	timeout  time.Duration
+	draining atomic.Bool
+
+	mu       sync.RWMutex
+	checkers []HealthChecker
+}
+
+// NewHealth returns a Health bounding each check by timeout and logging failed checks.
+func NewHealth(log applog.AppLogger, timeout time.Duration, checkers ...HealthChecker) *Health {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	return &Health{log: log, timeout: timeout, checkers: checkers}
+}
+
+// Register adds a checker to the readiness checks.
+func (h *Health) Register(c HealthChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers = append(h.checkers, c)
+}
+
+// Drain makes readiness fail from now on, so load balancers stop routing requests
+// to the instance before it shuts down.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Ready runs every checker concurrently and reports whether all of them passed and the
+// instance is not draining.
+func (h *Health) Ready(ctx context.Context) (HealthReport, bool) {
+	h.mu.RLock()
+	checkers := append([]HealthChecker(nil), h.checkers...)
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: HealthOK, Checks: make(map[string]CheckResult, len(checkers))}
+	for i, c := range checkers {
+		report.Checks[c.Name()] = results[i]
+		if results[i].Status != HealthOK {
+			report.Status = HealthFailing
+		}
+	}
+	if h.draining.Load() {
+		report.Status = HealthDraining
+	}
+	return report, report.Status == HealthOK
+}
+
+func (h *Health) run(ctx context.Context, c HealthChecker) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+	if err := c.Check(ctx); err != nil {
+		h.log.WithContext(ctx).Warn("Readiness check failed", "check", c.Name(),
+			"duration_ms", time.Since(start).Milliseconds(), "error", err)
+		return CheckResult{Status: HealthFailing}
+	}
+	return CheckResult{Status: HealthOK}
+}
+
+// Livez answers the liveness probe: the process is up and serving requests.
+func (h *Health) Livez(c fiber.Ctx) error {
+	return c.JSON(HealthReport{Status: HealthOK})
+}
+
+// Readyz answers the readiness probe with the report of every check, and 503 when the
+// instance should not receive traffic.
+func (h *Health) Readyz(c fiber.Ctx) error {
+	report, ok := h.Ready(c.Context())
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
+
+// DatabaseChecker pings the database.
+type DatabaseChecker struct {
+	DB *sql.DB
+}
+
+func (DatabaseChecker) Name() string { return "database" }
+
+func (d DatabaseChecker) Check(ctx context.Context) error {
+	return d.DB.PingContext(ctx)
+}
+
+// ReplicaChecker pings the read replica.
+type ReplicaChecker struct {
+	DB *sql.DB
+}
+
+func (ReplicaChecker) Name() string { return "database_replica" }
+
+func (r ReplicaChecker) Check(ctx context.Context) error {
+	return r.DB.PingContext(ctx)
+}
+
+// MigrationChecker verifies that the database has at least the migrations the code
+// expects. A newer schema passes, so instances still running the previous release stay
+// ready while a rollout migrates ahead of them; migrations must stay compatible with it.
+type MigrationChecker struct {
+	DB       *gorm.DB
+	Expected int64
+}
+
+func (MigrationChecker) Name() string { return "migrations" }
+
+func (m MigrationChecker) Check(ctx context.Context) error {
+	var version sql.NullInt64
+	if err := m.DB.WithContext(ctx).Raw("SELECT MAX(version) FROM zenrows.schema_migrations").Scan(&version).Error; err != nil {
+		return err
+	}
+	if version.Int64 < m.Expected {
+		return fmt.Errorf("schema version %d, expected at least %d", version.Int64, m.Expected)
+	}
+	return nil
+}
+
+// PoolChecker fails when the share of open connections in use reaches MaxUtilization,
+// which means requests are about to queue for a connection. Unbounded pools always pass.
+type PoolChecker struct {
+	DB             *sql.DB
+	MaxUtilization float64
+}
+
+func (PoolChecker) Name() string { return "database_pool" }
+
+func (p PoolChecker) Check(context.Context) error {
+	stats := p.DB.Stats()
+	if stats.MaxOpenConnections <= 0 || p.MaxUtilization <= 0 {
+		return nil
+	}
+	if u := float64(stats.InUse) / float64(stats.MaxOpenConnections); u >= p.MaxUtilization {
+		return fmt.Errorf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+)
+
+const defaultShutdownTimeout = 15 * time.Second
+
+// Component is a part of the process with hooks run by a Lifecycle. Either hook may be
+// nil.
+type Component struct {
+	Name string
+	// Start returns once the component is running; work outliving it goes through
+	// Lifecycle.Go.
+	Start func(ctx context.Context) error
+	// Stop releases the component, giving up when ctx is done.
+	Stop func(ctx context.Context) error
+}
+
+// Lifecycle starts components in the order they were added and stops them in reverse
+// order, so a component can rely on the ones added before it for its whole life.
+type Lifecycle struct {
+	log             applog.AppLogger
+	shutdownTimeout time.Duration
+
+	components []Component
+	started    int
+	wg         sync.WaitGroup
+	errs       chan error
+}
+
+// NewLifecycle creates a Lifecycle whose stop hooks share shutdownTimeout.
+func NewLifecycle(log applog.AppLogger, shutdownTimeout time.Duration) *Lifecycle {
+	return &Lifecycle{log: log, shutdownTimeout: shutdownTimeout, errs: make(chan error, 1)}
+}
+
+// Add appends c to the components. It must not be called once Run has started.
+func (l *Lifecycle) Add(c Component) {
+	l.components = append(l.components, c)
+}
+
+// Go runs fn in its own goroutine, such as the accept loop of a server. An error
+// returned by fn stops the Lifecycle and is returned by Run; Run waits for fn to
+// return, within the shutdown timeout, before returning.
+func (l *Lifecycle) Go(name string, fn func() error) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		if err := fn(); err != nil {
+			select {
+			case l.errs <- fmt.Errorf("%s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// Run starts every component, then blocks until ctx is done or a component fails, and
+// stops the components started so far. It returns the error that ended the run, if any,
+// joined with the errors of the stop hooks.
+func (l *Lifecycle) Run(ctx context.Context) error {
+	err := l.start(ctx)
+	if err == nil {
+		select {
+		case <-ctx.Done():
+			l.log.Info("Shutdown signal received, stopping...")
+		case err = <-l.errs:
+			l.log.Error("Component failed, stopping...", "error", err)
+		}
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.shutdownTimeout)
+	defer cancel()
+	err = errors.Join(err, l.stop(stopCtx))
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-stopCtx.Done():
+		err = errors.Join(err, errors.New("background work still running after the shutdown timeout"))
+	}
+	// Failures of background work after the stop signal, such as a server that could not
+	// close its listener, are reported too.
+	select {
+	case e := <-l.errs:
+		err = errors.Join(err, e)
+	default:
+	}
+
+	if err != nil {
+		return err
+	}
+	l.log.Info("Shutdown complete")
+	return nil
+}
+
+func (l *Lifecycle) start(ctx context.Context) error {
+	for _, c := range l.components {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				return fmt.Errorf("start %s: %w", c.Name, err)
+			}
+		}
+		l.started++
+		l.log.Debug("Component started", "component", c.Name)
+	}
+	return nil
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []error
+	for i := l.started - 1; i >= 0; i-- {
+		c := l.components[i]
+		if c.Stop == nil {
+			continue
+		}
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", c.Name, err))
+			continue
+		}
+		l.log.Debug("Component stopped", "component", c.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// PeriodicComponent returns the component running fn every interval, such as a cleanup
+// of expired rows, from its start until its stop. Failures are logged and retried at the
+// next tick.
+func PeriodicComponent(lc *Lifecycle, name string, interval time.Duration, fn func(ctx context.Context) error) Component {
+	var cancel context.CancelFunc
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			ctx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			lc.Go(name, func() error {
+				t := time.NewTicker(interval)
+				defer t.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-t.C:
+						if err := fn(ctx); err != nil && ctx.Err() == nil {
+							lc.log.Warn("Periodic task failed", "component", name, "error", err)
+						}
+					}
+				}
+			})
+			return nil
+		},
+		Stop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	}
+}
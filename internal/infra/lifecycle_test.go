@@ -0,0 +1,154 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycle(t *testing.T) {
+	log := applog.NewAppLoggerTo(&bytes.Buffer{})
+
+	// recorder returns a component appending its start and stop to calls.
+	recorder := func(calls *[]string, name string, startErr error) Component {
+		return Component{
+			Name:  name,
+			Start: func(context.Context) error { *calls = append(*calls, "start "+name); return startErr },
+			Stop:  func(context.Context) error { *calls = append(*calls, "stop "+name); return nil },
+		}
+	}
+
+	t.Run("stops components in reverse order on signal", func(t *testing.T) {
+		var calls []string
+		lc := NewLifecycle(log, time.Second)
+		lc.Add(recorder(&calls, "db", nil))
+		lc.Add(recorder(&calls, "server", nil))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.NoError(t, lc.Run(ctx))
+		assert.Equal(t, []string{"start db", "start server", "stop server", "stop db"}, calls)
+	})
+
+	t.Run("start failure stops the components already started", func(t *testing.T) {
+		var calls []string
+		lc := NewLifecycle(log, time.Second)
+		lc.Add(recorder(&calls, "db", nil))
+		lc.Add(recorder(&calls, "server", errors.New("address in use")))
+		lc.Add(recorder(&calls, "worker", nil))
+
+		err := lc.Run(context.Background())
+		require.ErrorContains(t, err, "start server: address in use")
+		assert.Equal(t, []string{"start db", "start server", "stop db"}, calls)
+	})
+
+	t.Run("background failure ends the run", func(t *testing.T) {
+		var calls []string
+		lc := NewLifecycle(log, time.Second)
+		lc.Add(recorder(&calls, "db", nil))
+		lc.Go("worker", func() error { return errors.New("boom") })
+
+		err := lc.Run(context.Background())
+		require.ErrorContains(t, err, "worker: boom")
+		assert.Equal(t, []string{"start db", "stop db"}, calls)
+	})
+
+	t.Run("stop hooks share the shutdown timeout", func(t *testing.T) {
+		lc := NewLifecycle(log, 10*time.Millisecond)
+		lc.Add(Component{Name: "slow", Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		err := lc.Run(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestPeriodicComponent(t *testing.T) {
+	log := applog.NewAppLoggerTo(&bytes.Buffer{})
+	lc := NewLifecycle(log, time.Second)
+	var runs atomic.Int32
+	lc.Add(PeriodicComponent(lc, "pruner", time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("failures do not stop the component")
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- lc.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-stopped)
+	n := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, runs.Load(), "no run after stop")
+}
+
+func TestServerComponent(t *testing.T) {
+	log := applog.NewAppLoggerTo(&bytes.Buffer{})
+	busy, err := net.Listen(fiber.NetworkTCP4, ":0")
+	require.NoError(t, err)
+	defer busy.Close()
+	port := strconv.Itoa(busy.Addr().(*net.TCPAddr).Port)
+
+	t.Run("listen error is returned", func(t *testing.T) {
+		lc := NewLifecycle(log, time.Second)
+		lc.Add(ServerComponent(lc, "api_server", fiber.New(), port, nil))
+		assert.ErrorContains(t, lc.Run(context.Background()), "start api_server")
+	})
+
+	t.Run("in-flight requests complete before the server stops", func(t *testing.T) {
+		require.NoError(t, busy.Close())
+		app := fiber.New()
+		started := make(chan struct{})
+		app.Get("/slow", func(c fiber.Ctx) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return c.SendString("done")
+		})
+		lc := NewLifecycle(log, time.Second)
+		lc.Add(ServerComponent(lc, "api_server", app, port, nil))
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error)
+		go func() { stopped <- lc.Run(ctx) }()
+
+		require.Eventually(t, func() bool {
+			c, err := net.Dial("tcp", "127.0.0.1:"+port)
+			if err == nil {
+				c.Close()
+			}
+			return err == nil
+		}, time.Second, 5*time.Millisecond)
+		responses := make(chan *http.Response, 1)
+		go func() {
+			resp, err := http.Get("http://127.0.0.1:" + port + "/slow")
+			assert.NoError(t, err)
+			responses <- resp
+		}()
+		<-started
+		cancel()
+
+		require.NoError(t, <-stopped)
+		resp := <-responses
+		require.NotNil(t, resp)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,380 @@
+// Package migrate applies the SQL schema migrations embedded in the binary and records
+// them in zenrows.schema_migrations.
+//
+// Migrations live in schema/ as NNN_name.up.sql with an optional NNN_name.down.sql; a
+// migration without a down file cannot be rolled back. Seed data lives in seed/ and is
+// never recorded: seed scripts must be idempotent and only run when asked for.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+)
+
+//go:embed schema/*.sql seed/*.sql
+var files embed.FS
+
+// versionTable creates the table recording applied migrations.
+//
+//go:embed schema_migrations.sql
+var versionTable string
+
+// lockID is the key of the session advisory lock held while migrating, so instances
+// starting together apply each migration once.
+const lockID int64 = 0x7a656e726f7773 // "zenrows"
+
+// ErrIrreversible is returned by Down when a migration to roll back has no down script.
+var ErrIrreversible = errors.New("migration has no down script")
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Seed is one seed data script.
+type Seed struct {
+	Name string
+	SQL  string
+}
+
+// State is the status of one migration against a database.
+type State struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+var migrations, seeds = mustLoad()
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() []Migration { return append([]Migration(nil), migrations...) }
+
+// Seeds returns the embedded seed scripts in the order they run.
+func Seeds() []Seed { return append([]Seed(nil), seeds...) }
+
+// Latest returns the version of the newest embedded migration.
+func Latest() int64 { return migrations[len(migrations)-1].Version }
+
+func mustLoad() ([]Migration, []Seed) {
+	m, err := loadMigrations(files)
+	if err != nil {
+		panic("migrate: " + err.Error())
+	}
+	s, err := loadSeeds(files)
+	if err != nil {
+		panic("migrate: " + err.Error())
+	}
+	return m, s
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "schema/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, p := range names {
+		base := path.Base(p)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("%s: expected NNN_name.up.sql or NNN_name.down.sql", p)
+		}
+		version, name, err := splitName(strings.TrimSuffix(base, "."+direction+".sql"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		body, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("version %d is used by both %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+			m.Checksum = checksum(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no migrations embedded")
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func loadSeeds(fsys fs.FS) ([]Seed, error) {
+	names, err := fs.Glob(fsys, "seed/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	out := make([]Seed, 0, len(names))
+	for _, p := range names {
+		_, name, err := splitName(strings.TrimSuffix(path.Base(p), ".sql"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		body, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Seed{Name: name, SQL: string(body)})
+	}
+	return out, nil
+}
+
+// splitName splits "005_user_quotas" into 5 and "user_quotas".
+func splitName(s string) (int64, string, error) {
+	prefix, name, ok := strings.Cut(s, "_")
+	if !ok || name == "" {
+		return 0, "", errors.New("expected a NNN_name file name")
+	}
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("invalid version %q", prefix)
+	}
+	return version, name, nil
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrator applies the embedded migrations and seeds to one database.
+type Migrator struct {
+	log applog.AppLogger
+	db  *sql.DB
+}
+
+func New(log applog.AppLogger, db *sql.DB) *Migrator {
+	return &Migrator{log: log, db: db}
+}
+
+// Up applies every pending migration in version order, each in its own transaction, and
+// returns how many were applied. It fails without applying anything when an applied
+// migration was edited or is unknown to this binary.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			m.log.WithContext(ctx).Info("migrate: applying", "version", mig.Version, "name", mig.Name)
+			err := inTx(ctx, conn, mig.Up,
+				`INSERT INTO zenrows.schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return fmt.Errorf("apply %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down rolls back the steps most recently applied migrations, newest first, and returns
+// how many were rolled back. It stops at the first migration without a down script.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && n < steps; i-- {
+			mig := migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("roll back %d_%s: %w", mig.Version, mig.Name, ErrIrreversible)
+			}
+			m.log.WithContext(ctx).Info("migrate: rolling back", "version", mig.Version, "name", mig.Name)
+			err := inTx(ctx, conn, mig.Down, `DELETE FROM zenrows.schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("roll back %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Status reports every embedded migration and whether it is applied.
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	var out []State
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			s := State{Version: mig.Version, Name: mig.Name}
+			if at, ok := applied[mig.Version]; ok {
+				s.Applied, s.AppliedAt = true, &at
+			}
+			out = append(out, s)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Seed runs the named seed scripts, or all of them when names is empty, in order.
+func (m *Migrator) Seed(ctx context.Context, names ...string) error {
+	selected := seeds
+	if len(names) > 0 {
+		selected = nil
+		for _, name := range names {
+			s, ok := seedByName(name)
+			if !ok {
+				return fmt.Errorf("unknown seed %q", name)
+			}
+			selected = append(selected, s)
+		}
+	}
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		for _, s := range selected {
+			m.log.WithContext(ctx).Info("migrate: seeding", "name", s.Name)
+			if _, err := conn.ExecContext(ctx, s.SQL); err != nil {
+				return fmt.Errorf("seed %s: %w", s.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func seedByName(name string) (Seed, bool) {
+	for _, s := range seeds {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Seed{}, false
+}
+
+// locked runs fn on one connection holding the migration advisory lock. The lock is
+// session scoped, so it is released when the connection is, even if the unlock fails.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, uerr := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockID); uerr != nil {
+			// Drop the connection rather than return it to the pool still holding the lock.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			m.log.WithContext(ctx).Warn("migrate: failed to release lock", "error", uerr)
+		}
+	}()
+	return fn(conn)
+}
+
+// verify creates the version table if needed and returns the applied versions with the
+// time they were applied. It fails when an applied migration is unknown to this build or
+// its up script changed since it was applied.
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	if _, err := conn.ExecContext(ctx, versionTable); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM zenrows.schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[int64]Migration, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = mig
+	}
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var (
+			version   int64
+			sum       string
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &sum, &appliedAt); err != nil {
+			return nil, err
+		}
+		mig, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d is unknown to this build", version)
+		}
+		if sum != mig.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was modified after it was applied", mig.Version, mig.Name)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// inTx runs script and then record with args in one transaction on conn.
+func inTx(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all := Migrations()
+	require.NotEmpty(t, all)
+	for i, m := range all {
+		assert.NotEmpty(t, m.Up, "migration %d", m.Version)
+		assert.Len(t, m.Checksum, 64, "migration %d", m.Version)
+		if i > 0 {
+			assert.Greater(t, m.Version, all[i-1].Version)
+		}
+	}
+	assert.Equal(t, all[len(all)-1].Version, Latest())
+
+	var names []string
+	for _, s := range Seeds() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"device_templates", "users"}, names)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("pairs up and down scripts in version order", func(t *testing.T) {
+		got, err := loadMigrations(fstest.MapFS{
+			"schema/010_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+			"schema/002_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+			"schema/002_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		})
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		assert.Equal(t, Migration{
+			Version:  2,
+			Name:     "a",
+			Up:       "CREATE TABLE a ();",
+			Down:     "DROP TABLE a;",
+			Checksum: checksum([]byte("CREATE TABLE a ();")),
+		}, got[0])
+		assert.Equal(t, int64(10), got[1].Version)
+		assert.Empty(t, got[1].Down)
+	})
+
+	t.Run("checksum changes with the up script only", func(t *testing.T) {
+		a, err := loadMigrations(fstest.MapFS{
+			"schema/001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"schema/001_a.down.sql": {Data: []byte("SELECT 2;")},
+		})
+		require.NoError(t, err)
+		b, err := loadMigrations(fstest.MapFS{"schema/001_a.up.sql": {Data: []byte("SELECT 1;")}})
+		require.NoError(t, err)
+		c, err := loadMigrations(fstest.MapFS{"schema/001_a.up.sql": {Data: []byte("SELECT 1; ")}})
+		require.NoError(t, err)
+		assert.Equal(t, a[0].Checksum, b[0].Checksum)
+		assert.NotEqual(t, a[0].Checksum, c[0].Checksum)
+	})
+
+	invalid := map[string]fstest.MapFS{
+		"missing up script":  {"schema/001_a.down.sql": {Data: []byte("SELECT 1;")}},
+		"duplicate version":  {"schema/001_a.up.sql": {}, "schema/001_b.up.sql": {}},
+		"missing direction":  {"schema/001_a.sql": {}},
+		"non-numeric prefix": {"schema/first_a.up.sql": {}},
+		"zero version":       {"schema/000_a.up.sql": {}},
+		"no migrations":      {},
+	}
+	for name, fsys := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadMigrations(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
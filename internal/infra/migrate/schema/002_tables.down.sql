@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS zenrows.device_profile;
+DROP TABLE IF EXISTS zenrows.device_template;
+DROP TABLE IF EXISTS zenrows."user";
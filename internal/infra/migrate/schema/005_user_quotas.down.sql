@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS zenrows.user_quota;
+
+ALTER TABLE zenrows."user"
+    DROP COLUMN IF EXISTS plan;
@@ -0,0 +1,11 @@
+ALTER TABLE zenrows."user"
+    ADD COLUMN IF NOT EXISTS plan TEXT NOT NULL DEFAULT 'free' CHECK (char_length(trim(plan)) > 0);
+
+CREATE TABLE IF NOT EXISTS zenrows.user_quota
+(
+    user_id               UUID PRIMARY KEY REFERENCES zenrows."user" (id) ON DELETE CASCADE,
+    max_profiles          INT,
+    max_custom_headers    INT,
+    max_header_value_size INT,
+    requests_per_minute   INT
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS zenrows.rate_limit_bucket;
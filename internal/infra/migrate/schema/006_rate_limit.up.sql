@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS zenrows.rate_limit_bucket
+(
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
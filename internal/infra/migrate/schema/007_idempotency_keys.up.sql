@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS zenrows.idempotency_key
+(
+    scope           TEXT        NOT NULL,
+    key             TEXT        NOT NULL CHECK (char_length(key) BETWEEN 1 AND 255),
+    fingerprint     TEXT        NOT NULL,
+    status          TEXT        NOT NULL CHECK (status IN ('in_progress', 'completed')),
+    response_status INT,
+    response_body   BYTEA,
+    content_type    TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
+);
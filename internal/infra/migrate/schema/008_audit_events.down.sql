@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS zenrows.audit_event;
+
+ALTER TABLE zenrows."user"
+    DROP COLUMN IF EXISTS role;
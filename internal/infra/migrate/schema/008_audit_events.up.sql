@@ -0,0 +1,22 @@
+ALTER TABLE zenrows."user"
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+CREATE TABLE IF NOT EXISTS zenrows.audit_event
+(
+    id            UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    occurred_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor_id      UUID,
+    actor_name    TEXT,
+    action        TEXT        NOT NULL,
+    resource_type TEXT        NOT NULL,
+    resource_id   TEXT,
+    outcome       TEXT        NOT NULL CHECK (outcome IN ('success', 'failure')),
+    source_ip     TEXT,
+    user_agent    TEXT,
+    request_id    TEXT,
+    details       JSONB CHECK (details IS NULL OR jsonb_typeof(details) = 'object')
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_occurred_at_idx ON zenrows.audit_event (occurred_at DESC);
+CREATE INDEX IF NOT EXISTS audit_event_actor_idx ON zenrows.audit_event (actor_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS audit_event_resource_idx ON zenrows.audit_event (resource_type, resource_id);
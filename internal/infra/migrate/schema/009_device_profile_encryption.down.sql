@@ -0,0 +1,16 @@
+-- Dropping the key columns would leave custom headers that nothing can decrypt.
+DO
+$$
+    BEGIN
+        IF EXISTS (SELECT 1 FROM zenrows.device_profile WHERE encryption_key_id IS NOT NULL) THEN
+            RAISE EXCEPTION 'zenrows.device_profile holds encrypted custom headers; decrypt them before reverting';
+        END IF;
+    END
+$$;
+
+DROP INDEX IF EXISTS zenrows.device_profile_encryption_key_idx;
+
+ALTER TABLE zenrows.device_profile
+    DROP CONSTRAINT IF EXISTS device_profile_encryption_key_check,
+    DROP COLUMN IF EXISTS wrapped_data_key,
+    DROP COLUMN IF EXISTS encryption_key_id;
@@ -0,0 +1,8 @@
+ALTER TABLE zenrows.device_profile
+    ADD COLUMN IF NOT EXISTS encryption_key_id TEXT,
+    ADD COLUMN IF NOT EXISTS wrapped_data_key  BYTEA,
+    DROP CONSTRAINT IF EXISTS device_profile_encryption_key_check,
+    ADD CONSTRAINT device_profile_encryption_key_check
+        CHECK ((encryption_key_id IS NULL) = (wrapped_data_key IS NULL));
+
+CREATE INDEX IF NOT EXISTS device_profile_encryption_key_idx ON zenrows.device_profile (encryption_key_id);
@@ -0,0 +1,2 @@
+ALTER TABLE zenrows."user"
+    DROP COLUMN IF EXISTS disabled_at;
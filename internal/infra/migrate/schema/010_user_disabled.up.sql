@@ -0,0 +1,2 @@
+ALTER TABLE zenrows."user"
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS zenrows.rate_limit_bucket_full_at_idx;
+
+ALTER TABLE zenrows.rate_limit_bucket
+    DROP COLUMN IF EXISTS full_at;
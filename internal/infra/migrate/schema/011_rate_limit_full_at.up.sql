@@ -0,0 +1,4 @@
+ALTER TABLE zenrows.rate_limit_bucket
+    ADD COLUMN IF NOT EXISTS full_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS rate_limit_bucket_full_at_idx ON zenrows.rate_limit_bucket (full_at);
@@ -0,0 +1,17 @@
+-- Encrypted responses cannot be replayed once their key columns are gone, and dropping them
+-- would let a retry run its request twice. They expire after idempotency.ttl.
+DO
+$$
+    BEGIN
+        IF EXISTS (SELECT 1 FROM zenrows.idempotency_key WHERE encryption_key_id IS NOT NULL) THEN
+            RAISE EXCEPTION 'zenrows.idempotency_key holds encrypted responses; wait for them to expire before reverting';
+        END IF;
+    END
+$$;
+
+DROP INDEX IF EXISTS zenrows.idempotency_key_expires_at_idx;
+
+ALTER TABLE zenrows.idempotency_key
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS wrapped_data_key,
+    DROP COLUMN IF EXISTS encryption_key_id;
@@ -0,0 +1,13 @@
+-- Responses are stored encrypted under a data key wrapped by the envelope keyring, as they
+-- may echo custom header values.
+ALTER TABLE zenrows.idempotency_key
+    ADD COLUMN IF NOT EXISTS encryption_key_id TEXT,
+    ADD COLUMN IF NOT EXISTS wrapped_data_key  BYTEA,
+    ADD COLUMN IF NOT EXISTS locked_until      TIMESTAMPTZ;
+
+-- Reservations made before the lease existed may be taken over by a retry right away.
+UPDATE zenrows.idempotency_key
+SET locked_until = NOW()
+WHERE status = 'in_progress';
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON zenrows.idempotency_key (expires_at);
@@ -0,0 +1,9 @@
+CREATE SCHEMA IF NOT EXISTS zenrows;
+
+CREATE TABLE IF NOT EXISTS zenrows.schema_migrations
+(
+    version    BIGINT PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    checksum   TEXT        NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,228 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/metrics"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadableKeys are the settings ReloadConfig applies to the running process; a key
+// covers the keys below it. Changes to any other key are reported as requiring a
+// restart. applyReloadable must copy exactly these.
+var reloadableKeys = []string{
+	"log.level",
+	"rate_limit.groups",
+	"quota",
+	"cors.allowed_origins",
+	"features",
+}
+
+func applyReloadable(dst, src *Config) {
+	dst.Log.Level = src.Log.Level
+	dst.RateLimit.Groups = src.RateLimit.Groups
+	dst.Quota = src.Quota
+	dst.CORS.AllowedOrigins = src.CORS.AllowedOrigins
+	dst.Features = src.Features
+}
+
+func isReloadable(key string) bool {
+	for _, k := range reloadableKeys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReloadResult lists the settings changed since the running configuration was loaded.
+type ReloadResult struct {
+	// Applied are the reloadable keys whose value changed; they are now in effect.
+	Applied []string
+	// RestartRequired are the other changed keys, which take effect on the next start.
+	RestartRequired []string
+}
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func(old, cur *Config)
+)
+
+// OnReload registers fn to run after each reload that applied changes, with the
+// configuration before and after it. Settings read through Current need no hook.
+func OnReload(fn func(old, cur *Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// ReloadConfig reads the configuration file loaded by LoadConfig again, with the same
+// environment overrides and secret files, and validates it. The reloadable settings of
+// the result are then swapped into the configuration returned by Current; on error the
+// running configuration is kept as is. The global Viper instance is left untouched.
+func ReloadConfig() (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return ReloadResult{}, errors.New("infra: no configuration loaded")
+	}
+	v := viper.New()
+	v.SetConfigFile(file)
+	v.SetConfigType("yml")
+	v.SetEnvPrefix(envPrefix)
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	registerDefaults(v)
+
+	if err := v.ReadInConfig(); err != nil {
+		return ReloadResult{}, fmt.Errorf("infra: failed to read config %q: %w", file, err)
+	}
+	if err := applySecretFiles(v); err != nil {
+		return ReloadResult{}, fmt.Errorf("infra: failed to load secrets: %w", err)
+	}
+	next, err := decodeConfig(v)
+	if err != nil {
+		return ReloadResult{}, fmt.Errorf("infra: invalid config %q: %w", file, err)
+	}
+
+	old := Current()
+	cur := *old
+	applyReloadable(&cur, next)
+	if !old.RateLimit.Enabled {
+		// The limiter is only installed at startup, so new groups would not be enforced.
+		cur.RateLimit.Groups = old.RateLimit.Groups
+	}
+	res := ReloadResult{Applied: diffConfig(old, &cur), RestartRequired: diffConfig(&cur, next)}
+	if len(res.Applied) > 0 {
+		current.Store(&cur)
+		for _, fn := range reloadHooks {
+			fn(old, &cur)
+		}
+	}
+	return res, nil
+}
+
+// diffConfig returns the keys whose value differs between a and b.
+func diffConfig(a, b *Config) []string {
+	before := make(map[string]any)
+	walkConfig(reflect.ValueOf(*a), "", func(key string, v reflect.Value) {
+		before[key] = v.Interface()
+	})
+	var changed []string
+	walkConfig(reflect.ValueOf(*b), "", func(key string, v reflect.Value) {
+		if !reflect.DeepEqual(before[key], v.Interface()) {
+			changed = append(changed, key)
+		}
+	})
+	return changed
+}
+
+// WatchConfig reloads the configuration on SIGHUP and, with reload.watch, whenever the
+// configuration file changes, logging the outcome. It returns a func that stops both.
+func WatchConfig(log applog.AppLogger) (stop func()) {
+	stopFile := func() {}
+	if Current().Reload.Watch {
+		var err error
+		if stopFile, err = watchFile(viper.ConfigFileUsed(), func() { reloadAndReport(log, "file") }, log); err != nil {
+			log.Error("Failed to watch the configuration file, reload it with SIGHUP", "error", err)
+			stopFile = func() {}
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				reloadAndReport(log, "sighup")
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(done)
+		stopFile()
+	}
+}
+
+// watchFile calls onChange whenever the file at path is written or replaced, including
+// through a symlink swap as done for mounted Kubernetes ConfigMaps. The directory is
+// watched rather than the file, as editors save by renaming a new file over the old one.
+// The returned func stops watching and waits for a running onChange to return.
+func watchFile(path string, onChange func(), log applog.AppLogger) (stop func(), err error) {
+	path = filepath.Clean(path)
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		real, _ := filepath.EvalSymlinks(path)
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				cur, _ := filepath.EvalSymlinks(path)
+				written := filepath.Clean(ev.Name) == path && ev.Has(fsnotify.Write|fsnotify.Create)
+				if written || (cur != "" && cur != real) {
+					real = cur
+					onChange()
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Warn("Configuration file watcher error", "error", err)
+			}
+		}
+	}()
+	return func() {
+		_ = w.Close()
+		<-done
+	}, nil
+}
+
+func reloadAndReport(log applog.AppLogger, trigger string) {
+	res, err := ReloadConfig()
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues(metrics.ReloadRejected).Inc()
+		log.Error("Configuration reload rejected, keeping the running configuration", "trigger", trigger, "error", err)
+		return
+	}
+	metrics.ConfigRestartRequired.Set(float64(len(res.RestartRequired)))
+	if len(res.RestartRequired) > 0 {
+		log.Warn("Configuration changes require a restart", "trigger", trigger, "keys", res.RestartRequired)
+	}
+	if len(res.Applied) == 0 {
+		metrics.ConfigReloads.WithLabelValues(metrics.ReloadUnchanged).Inc()
+		log.Debug("Configuration reloaded, nothing to apply", "trigger", trigger)
+		return
+	}
+	metrics.ConfigReloads.WithLabelValues(metrics.ReloadApplied).Inc()
+	log.Info("Configuration reloaded", "trigger", trigger, "changed", res.Applied)
+}
@@ -0,0 +1,164 @@
+package infra
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadTestConfig = `
+server:
+  port: 8080
+log:
+  level: %s
+database:
+  database: zenrows
+  user: app
+quota:
+  default_plan: free
+  plans:
+    free:
+      max_profiles: %d
+cors:
+  allowed_origins: [%s]
+`
+
+func writeReloadTestConfig(t *testing.T, path, level string, maxProfiles int, origin string) {
+	t.Helper()
+	body := []byte(fmt.Sprintf(reloadTestConfig, level, maxProfiles, origin))
+	require.NoError(t, os.WriteFile(path, body, 0o600))
+}
+
+func loadReloadTestConfig(t *testing.T, path string) {
+	t.Helper()
+	viper.Reset()
+	viper.SetConfigFile(path)
+	v := viper.New()
+	v.SetConfigFile(path)
+	registerDefaults(v)
+	require.NoError(t, v.ReadInConfig())
+	cfg, err := decodeConfig(v)
+	require.NoError(t, err)
+	current.Store(cfg)
+	t.Cleanup(func() {
+		current.Store(nil)
+		viper.Reset()
+	})
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeReloadTestConfig(t, path, "info", 10, "")
+	loadReloadTestConfig(t, path)
+
+	var hooked bool
+	reloadHooks = nil
+	OnReload(func(old, cur *Config) {
+		hooked = true
+		assert.Equal(t, "info", old.Log.Level)
+		assert.Equal(t, "debug", cur.Log.Level)
+	})
+	t.Cleanup(func() { reloadHooks = nil })
+
+	t.Run("unchanged", func(t *testing.T) {
+		res, err := ReloadConfig()
+		require.NoError(t, err)
+		assert.Empty(t, res.Applied)
+		assert.Empty(t, res.RestartRequired)
+		assert.False(t, hooked)
+	})
+
+	t.Run("applies reloadable settings and reports the others", func(t *testing.T) {
+		writeReloadTestConfig(t, path, "debug", 20, "https://app.example.com")
+		body, _ := os.ReadFile(path)
+		require.NoError(t, os.WriteFile(path, append(body, []byte("  allow_credentials: true\n")...), 0o600))
+
+		res, err := ReloadConfig()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"log.level", "quota.plans", "cors.allowed_origins"}, res.Applied)
+		assert.Equal(t, []string{"cors.allow_credentials"}, res.RestartRequired)
+		assert.True(t, hooked)
+
+		assert.Equal(t, "debug", Current().Log.Level)
+		assert.Equal(t, 20, Current().Quota.Plans["free"].MaxProfiles)
+		assert.True(t, CORSOriginAllowed("https://app.example.com"))
+		assert.False(t, Current().CORS.AllowCredentials)
+	})
+
+	t.Run("reports rate limit groups as requiring a restart while rate limiting is off", func(t *testing.T) {
+		body, _ := os.ReadFile(path)
+		require.NoError(t, os.WriteFile(path, append(body, []byte("rate_limit:\n  groups:\n    api:\n      read: {rate: 5, burst: 5}\n")...), 0o600))
+
+		res, err := ReloadConfig()
+		require.NoError(t, err)
+		assert.Empty(t, res.Applied)
+		assert.Contains(t, res.RestartRequired, "rate_limit.groups")
+		assert.Empty(t, Current().RateLimit.Groups)
+	})
+
+	t.Run("rejects an invalid configuration", func(t *testing.T) {
+		writeReloadTestConfig(t, path, "verbose", 30, "")
+
+		_, err := ReloadConfig()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "log.level")
+		assert.Equal(t, "debug", Current().Log.Level)
+		assert.Equal(t, 20, Current().Quota.Plans["free"].MaxProfiles)
+	})
+}
+
+func TestApplyReloadableCopiesReloadableKeysOnly(t *testing.T) {
+	a, b := validConfig(), validConfig()
+	b.Log.Level, b.Log.Format = "debug", "json"
+	b.RateLimit.Groups = map[string]RateLimitGroup{"api": {Read: LimitConfig{Rate: 1, Burst: 1}}}
+	b.RateLimit.Enabled = true
+	b.Quota = QuotaConfig{DefaultPlan: "pro", Plans: map[string]PlanConfig{"pro": {MaxProfiles: 1}}}
+	b.CORS = CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: 1}
+	b.Features = map[string]bool{"beta": true}
+	b.Server.Port = 9090
+
+	applied := a
+	applyReloadable(&applied, &b)
+	for _, key := range diffConfig(&a, &applied) {
+		assert.True(t, isReloadable(key), "%s was applied but is not reloadable", key)
+	}
+	for _, key := range diffConfig(&applied, &b) {
+		assert.False(t, isReloadable(key), "%s is reloadable but was not applied", key)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("a: 1\n"), 0o600))
+
+	changed := make(chan struct{}, 16)
+	stop, err := watchFile(path, func() { changed <- struct{}{} }, applog.NewAppLoggerTo(io.Discard))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("a: 2\n"), 0o600))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported after writing the file")
+	}
+
+	stop()
+	for len(changed) > 0 {
+		<-changed
+	}
+	require.NoError(t, os.WriteFile(path, []byte("a: 3\n"), 0o600))
+	select {
+	case <-changed:
+		t.Fatal("change reported after stop")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -2,55 +2,54 @@ package infra
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-	"time"
-	"zenrows-challenge/internal/pkg/applog"
+	"net"
+	"zenrows-challenge/internal/pkg/problem"
 
 	"github.com/gofiber/fiber/v3"
-
-	"github.com/spf13/viper"
+	"github.com/gofiber/fiber/v3/middleware/recover"
 )
 
-func StartServer(logger applog.AppLogger, wg *sync.WaitGroup) *fiber.App {
-	an := viper.GetString("server.name")
-	app := fiber.New(fiber.Config{AppName: an})
-	port := viper.GetString("server.port")
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := app.Listen(fmt.Sprintf(":%s", port)); err != nil {
-			logger.Fatal("Failed to start server: %v", err.Error())
-		}
-	}()
+// NewServer creates the API app. It is served by the component returned by
+// ServerComponent, over TLS when server.tls is enabled. Errors are rendered as set by
+// server.error_format and server.problem_type_base_uri.
+func NewServer() *fiber.App {
+	cfg := Current().Server
+	problem.Configure(problem.Options{Format: cfg.ErrorFormat, TypeBaseURI: cfg.ProblemTypeBaseURI})
+	app := fiber.New(fiber.Config{AppName: Current().Server.Name, ErrorHandler: problem.ErrorHandler})
+	app.Use(recover.New())
 	return app
 }
 
-func GracefulShutdownServer(logger applog.AppLogger, wg *sync.WaitGroup, server *fiber.App, processTerminationCallBack func() error) {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(quit)
-
-	<-quit
-	logger.Info("Shutdown signal received, stopping server...")
-
-	if processTerminationCallBack != nil {
-		if err := processTerminationCallBack(); err != nil {
-			logger.Error("Server shutdown failed: %v", err)
-		}
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// NewAdminServer creates the app serving operational endpoints such as /metrics on their
+// own port, so they can be kept off the public listener.
+func NewAdminServer() *fiber.App {
+	app := fiber.New(fiber.Config{AppName: Current().Server.Name + "-admin", ErrorHandler: problem.ErrorHandler})
+	app.Use(recover.New())
+	return app
+}
 
-	if err := server.ShutdownWithContext(ctx); err != nil {
-		logger.Error("Server shutdown failed: %v", err)
+// ServerComponent returns the component serving app on port, wrapping connections in
+// TLS when tlsConfig is not nil. Start binds the port, so a port already in use fails
+// the start; errors of the accept loop stop lc. Stop waits for in-flight requests until
+// the shutdown context is done.
+func ServerComponent(lc *Lifecycle, name string, app *fiber.App, port string, tlsConfig *tls.Config) Component {
+	return Component{
+		Name: name,
+		Start: func(context.Context) error {
+			ln, err := net.Listen(fiber.NetworkTCP4, fmt.Sprintf(":%s", port))
+			if err != nil {
+				return err
+			}
+			if tlsConfig != nil {
+				ln = tls.NewListener(ln, tlsConfig)
+			}
+			lc.Go(name, func() error {
+				return app.Listener(ln)
+			})
+			return nil
+		},
+		Stop: app.ShutdownWithContext,
 	}
-
-	wg.Wait()
-	logger.Info("Shutdown complete")
 }
@@ -0,0 +1,438 @@
+package infra
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+// Config is the typed form of the configuration file and its ZENROWS_* environment
+// overrides. Field names follow the mapstructure tags, which are the configuration keys.
+type Config struct {
+	Server      ServerConfig      `mapstructure:"server"`
+	Log         LogConfig         `mapstructure:"log"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redaction   RedactionConfig   `mapstructure:"redaction"`
+	Encryption  EncryptionConfig  `mapstructure:"encryption"`
+	Health      HealthConfig      `mapstructure:"health"`
+	Metrics     MetricsConfig     `mapstructure:"metrics"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+	Quota       QuotaConfig       `mapstructure:"quota"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	CORS        CORSConfig        `mapstructure:"cors"`
+	OpenAPI     OpenAPIConfig     `mapstructure:"openapi"`
+	Features    map[string]bool   `mapstructure:"features"`
+	Reload      ReloadSettings    `mapstructure:"reload"`
+}
+
+type ServerConfig struct {
+	Name               string        `mapstructure:"name" validate:"required"`
+	Port               int           `mapstructure:"port" validate:"min=1,max=65535"`
+	Host               string        `mapstructure:"host"`
+	ErrorFormat        string        `mapstructure:"error_format" validate:"oneof=problem legacy"`
+	ProblemTypeBaseURI string        `mapstructure:"problem_type_base_uri" validate:"omitempty,uri"`
+	RequestTimeout     time.Duration `mapstructure:"request_timeout" validate:"gte=0"`
+	DisconnectCheck    time.Duration `mapstructure:"disconnect_check" validate:"gte=0"`
+	AdminPort          int           `mapstructure:"admin_port" validate:"omitempty,min=1,max=65535,nefield=Port"`
+	AdminToken         string        `mapstructure:"admin_token"`
+	DrainDelay         time.Duration `mapstructure:"drain_delay" validate:"gte=0"`
+	ShutdownTimeout    time.Duration `mapstructure:"shutdown_timeout" validate:"gtfield=DrainDelay"`
+	TLS                TLSConfig     `mapstructure:"tls"`
+}
+
+type TLSConfig struct {
+	Enabled        bool             `mapstructure:"enabled"`
+	CertFile       string           `mapstructure:"cert_file" validate:"omitempty,file"`
+	KeyFile        string           `mapstructure:"key_file" validate:"omitempty,file"`
+	MinVersion     string           `mapstructure:"min_version" validate:"oneof=1.2 1.3"`
+	CipherSuites   []string         `mapstructure:"cipher_suites"`
+	ReloadInterval time.Duration    `mapstructure:"reload_interval" validate:"gte=0"`
+	ClientAuth     ClientAuthConfig `mapstructure:"client_auth"`
+}
+
+type ClientAuthConfig struct {
+	Mode     string `mapstructure:"mode" validate:"oneof=none optional require"`
+	CAFile   string `mapstructure:"ca_file" validate:"omitempty,file"`
+	Identity string `mapstructure:"identity" validate:"oneof=cn san_dns san_email"`
+}
+
+type LogConfig struct {
+	Level         string              `mapstructure:"level" validate:"oneof=trace debug info warn warning error"`
+	Format        string              `mapstructure:"format" validate:"oneof=text json"`
+	TraceSampling TraceSamplingConfig `mapstructure:"trace_sampling"`
+}
+
+type TraceSamplingConfig struct {
+	Initial    int           `mapstructure:"initial" validate:"gte=0"`
+	Thereafter int           `mapstructure:"thereafter" validate:"gte=0"`
+	Tick       time.Duration `mapstructure:"tick" validate:"gte=0"`
+}
+
+type DatabaseConfig struct {
+	Host             string             `mapstructure:"host" validate:"required"`
+	Port             int                `mapstructure:"port" validate:"min=1,max=65535"`
+	Database         string             `mapstructure:"database" validate:"required"`
+	User             string             `mapstructure:"user" validate:"required"`
+	Password         string             `mapstructure:"password"`
+	SSLMode          string             `mapstructure:"sslmode" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	StatementTimeout time.Duration      `mapstructure:"statement_timeout" validate:"gte=0"`
+	QueryTimeout     QueryTimeoutConfig `mapstructure:"query_timeout"`
+	Migrations       MigrationsConfig   `mapstructure:"migrations"`
+	Pool             PoolConfig         `mapstructure:"pool"`
+	Connect          ConnectConfig      `mapstructure:"connect"`
+	Logger           DBLoggerConfig     `mapstructure:"logger"`
+	PrepareStmt      bool               `mapstructure:"prepare_stmt"`
+	Replica          ReplicaConfig      `mapstructure:"replica"`
+}
+
+type QueryTimeoutConfig struct {
+	Read  time.Duration `mapstructure:"read" validate:"gte=0"`
+	Write time.Duration `mapstructure:"write" validate:"gte=0"`
+}
+
+type MigrationsConfig struct {
+	RunOnStart bool `mapstructure:"run_on_start"`
+	Seed       bool `mapstructure:"seed"`
+}
+
+type PoolConfig struct {
+	MaxOpenConns    int           `mapstructure:"max_open_conns" validate:"gte=0"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" validate:"gte=0"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" validate:"gte=0"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" validate:"gte=0"`
+}
+
+type ConnectConfig struct {
+	Timeout    time.Duration `mapstructure:"timeout" validate:"gte=0"`
+	Backoff    time.Duration `mapstructure:"backoff" validate:"gte=0"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff" validate:"gte=0"`
+}
+
+type DBLoggerConfig struct {
+	Level         string        `mapstructure:"level" validate:"oneof=silent error warn info"`
+	SlowThreshold time.Duration `mapstructure:"slow_threshold" validate:"gte=0"`
+}
+
+// ReplicaConfig points at a read replica of the database; empty fields other than host
+// take the value of the primary.
+type ReplicaConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port" validate:"omitempty,min=1,max=65535"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	SSLMode  string `mapstructure:"sslmode" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+}
+
+type RedactionConfig struct {
+	MaskResponses bool     `mapstructure:"mask_responses"`
+	Headers       []string `mapstructure:"headers"`
+	LogKeys       []string `mapstructure:"log_keys"`
+	Patterns      []string `mapstructure:"patterns" validate:"dive,regexp"`
+}
+
+type EncryptionConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	ActiveKeyID string            `mapstructure:"active_key_id" validate:"required_if=Enabled true"`
+	KeyFile     string            `mapstructure:"key_file" validate:"omitempty,file"`
+	MasterKeys  map[string]string `mapstructure:"master_keys" validate:"dive,base64"`
+}
+
+type HealthConfig struct {
+	CheckTimeout       time.Duration `mapstructure:"check_timeout" validate:"gte=0"`
+	MaxPoolUtilization float64       `mapstructure:"max_pool_utilization" validate:"gte=0,lte=1"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Token   string `mapstructure:"token"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter" validate:"oneof=none stdout otlp"`
+	Endpoint    string  `mapstructure:"endpoint" validate:"omitempty,url"`
+	SampleRatio float64 `mapstructure:"sample_ratio" validate:"gte=0,lte=1"`
+}
+
+type QuotaConfig struct {
+	DefaultPlan string                `mapstructure:"default_plan" validate:"required"`
+	Plans       map[string]PlanConfig `mapstructure:"plans" validate:"dive"`
+}
+
+type PlanConfig struct {
+	MaxProfiles        int `mapstructure:"max_profiles" validate:"gte=0"`
+	MaxCustomHeaders   int `mapstructure:"max_custom_headers" validate:"gte=0"`
+	MaxHeaderValueSize int `mapstructure:"max_header_value_size" validate:"gte=0"`
+	RequestsPerMinute  int `mapstructure:"requests_per_minute" validate:"gte=0"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool                      `mapstructure:"enabled"`
+	Store   string                    `mapstructure:"store" validate:"oneof=memory postgres"`
+	Groups  map[string]RateLimitGroup `mapstructure:"groups" validate:"dive"`
+}
+
+type RateLimitGroup struct {
+	Read  LimitConfig `mapstructure:"read"`
+	Write LimitConfig `mapstructure:"write"`
+}
+
+type LimitConfig struct {
+	Rate  float64 `mapstructure:"rate" validate:"gte=0"`
+	Burst int     `mapstructure:"burst" validate:"gte=0"`
+}
+
+type IdempotencyConfig struct {
+	TTL   time.Duration `mapstructure:"ttl" validate:"gte=0"`
+	Lease time.Duration `mapstructure:"lease" validate:"gte=0"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string      `mapstructure:"allowed_origins" validate:"dive,eq=*|url"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age" validate:"gte=0"`
+}
+
+type OpenAPIConfig struct {
+	ValidateRequests  bool `mapstructure:"validate_requests"`
+	ValidateResponses bool `mapstructure:"validate_responses"`
+}
+
+type ReloadSettings struct {
+	Watch bool `mapstructure:"watch"`
+}
+
+// defaultConfig holds the values of the keys missing from the configuration file.
+func defaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Name:            "zenrows-service",
+			Port:            8080,
+			ErrorFormat:     "problem",
+			DisconnectCheck: defaultDisconnectCheck,
+			ShutdownTimeout: defaultShutdownTimeout,
+			TLS: TLSConfig{
+				MinVersion:     "1.2",
+				ReloadInterval: defaultCertReloadInterval,
+				ClientAuth:     ClientAuthConfig{Mode: ClientAuthNone, Identity: CertIdentityCN},
+			},
+		},
+		Log: LogConfig{Level: "info", Format: "text"},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    5432,
+			SSLMode: "prefer",
+			Connect: ConnectConfig{
+				Timeout:    defaultConnectTimeout,
+				Backoff:    defaultConnectBackoff,
+				MaxBackoff: defaultConnectMaxBackoff,
+			},
+			Logger: DBLoggerConfig{Level: "warn", SlowThreshold: defaultSlowQueryThreshold},
+		},
+		Redaction: RedactionConfig{
+			Headers:  redact.DefaultHeaders,
+			LogKeys:  redact.DefaultLogKeys,
+			Patterns: redact.DefaultPatterns,
+		},
+		Health:      HealthConfig{CheckTimeout: defaultHealthCheckTimeout},
+		Tracing:     TracingConfig{Exporter: TracingExporterNone, SampleRatio: 1},
+		Quota:       QuotaConfig{DefaultPlan: defaultQuotaPlan},
+		RateLimit:   RateLimitConfig{Store: RateLimitStoreMemory},
+		Idempotency: IdempotencyConfig{TTL: defaultIdempotencyTTL},
+		OpenAPI:     OpenAPIConfig{ValidateRequests: true},
+	}
+}
+
+// sensitiveConfigKeys are the substrings of configuration keys whose values are masked
+// in RedactedSettings. Every value under encryption.master_keys is masked too.
+var sensitiveConfigKeys = []string{"password", "token", "secret"}
+
+var current atomic.Pointer[Config]
+
+// Current returns the configuration validated by LoadConfig. Before LoadConfig has run,
+// it decodes the Viper settings as they are, without validating them.
+func Current() *Config {
+	if c := current.Load(); c != nil {
+		return c
+	}
+	c := defaultConfig()
+	_ = viper.Unmarshal(&c)
+	return &c
+}
+
+// registerDefaults makes v aware of every key of Config, so that environment variables
+// can override keys absent from the file and Unmarshal sees the defaults.
+func registerDefaults(v *viper.Viper) {
+	walkConfig(reflect.ValueOf(defaultConfig()), "", func(key string, f reflect.Value) {
+		if f.Kind() == reflect.Map && f.IsNil() {
+			v.SetDefault(key, map[string]any{})
+			return
+		}
+		v.SetDefault(key, f.Interface())
+	})
+}
+
+// walkConfig calls fn for every leaf of v, a Config or one of its sections, with its
+// dotted key. Maps are leaves.
+func walkConfig(v reflect.Value, prefix string, fn func(key string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if f := v.Field(i); f.Kind() == reflect.Struct && f.Type() != reflect.TypeOf(time.Duration(0)) {
+			walkConfig(f, key, fn)
+		} else {
+			fn(key, f)
+		}
+	}
+}
+
+// envName returns the environment variable overriding key.
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// applySecretFiles sets each key whose <VAR>_FILE environment variable names a file,
+// e.g. ZENROWS_DATABASE_PASSWORD_FILE, to the content of that file without its trailing
+// newline. This suits secrets mounted as files by Docker or Kubernetes.
+func applySecretFiles(v *viper.Viper) error {
+	for _, key := range v.AllKeys() {
+		path := os.Getenv(envName(key) + "_FILE")
+		if path == "" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s_FILE: %w", envName(key), err)
+		}
+		v.Set(key, strings.TrimRight(string(b), "\r\n"))
+	}
+	return nil
+}
+
+// unknownEnv returns the ZENROWS_* environment variables that override no known key,
+// which usually are typos.
+func unknownEnv(v *viper.Viper) []string {
+	// ZENROWS_CONFIG_NAME is documented as the profile selector; accept it alongside
+	// CONFIG_NAME.
+	known := map[string]struct{}{envPrefix + "_" + envConfigNameKey: {}}
+	for _, key := range v.AllKeys() {
+		known[envName(key)] = struct{}{}
+		known[envName(key)+"_FILE"] = struct{}{}
+	}
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, envPrefix+"_") {
+			continue
+		}
+		if _, ok := known[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// decodeConfig decodes the settings of v into a Config, failing on keys Config does not
+// declare, and validates it.
+func decodeConfig(v *viper.Viper) (*Config, error) {
+	if unknown := unknownEnv(v); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown environment variables: %s", strings.Join(unknown, ", "))
+	}
+	c := defaultConfig()
+	if err := v.UnmarshalExact(&c); err != nil {
+		return nil, err
+	}
+	if err := validateConfig(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func validateConfig(c *Config) error {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string { return f.Tag.Get("mapstructure") })
+	_ = v.RegisterValidation("regexp", func(fl validator.FieldLevel) bool {
+		_, err := regexp.Compile(fl.Field().String())
+		return err == nil
+	})
+
+	var problems []string
+	for _, fv := range validation.Violations(v.Struct(c)) {
+		problems = append(problems, fv.Field+" "+fv.Message)
+	}
+	if len(c.Quota.Plans) > 0 {
+		if _, ok := c.Quota.Plans[c.Quota.DefaultPlan]; !ok {
+			problems = append(problems, fmt.Sprintf("quota.default_plan %q is not one of quota.plans", c.Quota.DefaultPlan))
+		}
+	}
+	problems = append(problems, tlsProblems(c.Server.TLS)...)
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		problems = append(problems, "cors.allowed_origins cannot contain * when cors.allow_credentials is set")
+	}
+	if c.Encryption.Enabled && c.Encryption.KeyFile == "" {
+		if _, ok := c.Encryption.MasterKeys[strings.ToLower(c.Encryption.ActiveKeyID)]; !ok {
+			problems = append(problems, fmt.Sprintf("encryption.active_key_id %q is not one of encryption.master_keys", c.Encryption.ActiveKeyID))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// RedactedSettings returns the effective settings with secrets masked, for display.
+// Empty values are kept so unset secrets show as such.
+func RedactedSettings() map[string]any {
+	return maskSettings(viper.AllSettings(), false)
+}
+
+// maskSettings masks the sensitive values of settings; all masks every leaf value.
+func maskSettings(settings map[string]any, all bool) map[string]any {
+	out := make(map[string]any, len(settings))
+	for k, v := range settings {
+		switch x := v.(type) {
+		case map[string]any:
+			out[k] = maskSettings(x, all || k == "master_keys")
+		case string:
+			if x != "" && (all || isSensitiveConfigKey(k)) {
+				out[k] = redact.Mask
+			} else {
+				out[k] = x
+			}
+		default:
+			if all || isSensitiveConfigKey(k) {
+				out[k] = redact.Mask
+			} else {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+func isSensitiveConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveConfigKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package infra
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/redact"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() Config {
+	c := defaultConfig()
+	c.Database.Database, c.Database.User = "zenrows", "app"
+	return c
+}
+
+func TestValidateConfig(t *testing.T) {
+	require.NoError(t, validateConfig(ptr(validConfig())))
+
+	cases := []struct {
+		name   string
+		mutate func(c *Config)
+		want   string
+	}{
+		{"port out of range", func(c *Config) { c.Server.Port = 70000 }, "server.port"},
+		{"admin port equal to the API port", func(c *Config) { c.Server.AdminPort = c.Server.Port }, "server.admin_port"},
+		{"shutdown timeout shorter than the drain delay", func(c *Config) {
+			c.Server.DrainDelay, c.Server.ShutdownTimeout = 10*time.Second, 5*time.Second
+		}, "server.shutdown_timeout"},
+		{"unknown log level", func(c *Config) { c.Log.Level = "verbose" }, "log.level"},
+		{"unknown sslmode", func(c *Config) { c.Database.SSLMode = "on" }, "database.sslmode"},
+		{"missing database user", func(c *Config) { c.Database.User = "" }, "database.user"},
+		{"negative timeout", func(c *Config) { c.Database.QueryTimeout.Read = -1 }, "database.query_timeout.read"},
+		{"invalid redaction pattern", func(c *Config) { c.Redaction.Patterns = []string{"("} }, "redaction.patterns[0]"},
+		{"sample ratio above one", func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio"},
+		{"negative plan limit", func(c *Config) {
+			c.Quota.Plans = map[string]PlanConfig{"free": {MaxProfiles: -1}}
+		}, "quota.plans[free].max_profiles"},
+		{"default plan not configured", func(c *Config) {
+			c.Quota.Plans = map[string]PlanConfig{"pro": {}}
+		}, "quota.default_plan"},
+		{"tls without a certificate", func(c *Config) { c.Server.TLS.Enabled = true }, "server.tls.cert_file"},
+		{"unknown cipher suite", func(c *Config) {
+			c.Server.TLS.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+		}, "server.tls.cipher_suites"},
+		{"client certificates without tls", func(c *Config) {
+			c.Server.TLS.ClientAuth.Mode = ClientAuthOptional
+		}, "server.tls.client_auth.mode"},
+		{"cors credentials with any origin", func(c *Config) {
+			c.CORS = CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+		}, "cors.allowed_origins"},
+		{"active key missing", func(c *Config) {
+			c.Encryption = EncryptionConfig{Enabled: true, ActiveKeyID: "k2", MasterKeys: map[string]string{"k1": "AAAA"}}
+		}, "encryption.active_key_id"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := validConfig()
+			tc.mutate(&c)
+			err := validateConfig(&c)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
+
+func TestWalkConfigCoversEveryKey(t *testing.T) {
+	var keys []string
+	walkConfig(reflect.ValueOf(defaultConfig()), "", func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	assert.Contains(t, keys, "server.port")
+	assert.Contains(t, keys, "database.migrations.run_on_start")
+	assert.Contains(t, keys, "log.trace_sampling.tick")
+	assert.Contains(t, keys, "quota.plans")
+	assert.NotContains(t, keys, "database.query_timeout")
+}
+
+func TestMaskSettings(t *testing.T) {
+	got := maskSettings(map[string]any{
+		"database":   map[string]any{"host": "db", "password": "app"},
+		"metrics":    map[string]any{"token": ""},
+		"encryption": map[string]any{"master_keys": map[string]any{"k1": "c2VjcmV0"}},
+	}, false)
+
+	assert.Equal(t, map[string]any{
+		"database":   map[string]any{"host": "db", "password": redact.Mask},
+		"metrics":    map[string]any{"token": ""},
+		"encryption": map[string]any{"master_keys": map[string]any{"k1": redact.Mask}},
+	}, got)
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,87 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"zenrows-challenge/internal/pkg/metrics"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	statementCancelKey = "statement_timeout:cancel"
+	statementParentKey = "statement_timeout:parent"
+
+	// pgQueryCanceled is raised by Postgres when statement_timeout fires.
+	pgQueryCanceled = "57014"
+)
+
+// statementTimeouts is a GORM plugin bounding each statement by a context deadline:
+// queries get read, inserts, updates, deletes and raw statements get write. Statements
+// run through Row/Rows/Scan are left to the request deadline, as their rows are read
+// after the callbacks return.
+type statementTimeouts struct {
+	read  time.Duration
+	write time.Duration
+}
+
+func (statementTimeouts) Name() string { return "statement_timeouts" }
+
+func (p statementTimeouts) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	var errs []error
+	if p.read > 0 {
+		errs = append(errs,
+			cb.Query().Before("gorm:query").Register("statement_timeout:before_query", startStatementTimeout(p.read)),
+			cb.Query().After("gorm:after_query").Register("statement_timeout:after_query", stopStatementTimeout),
+		)
+	}
+	if p.write > 0 {
+		// The deadline must outlive the implicit transaction, which database/sql rolls
+		// back as soon as its context is done.
+		errs = append(errs,
+			cb.Create().Before("gorm:begin_transaction").Register("statement_timeout:before_create", startStatementTimeout(p.write)),
+			cb.Create().After("gorm:commit_or_rollback_transaction").Register("statement_timeout:after_create", stopStatementTimeout),
+			cb.Update().Before("gorm:begin_transaction").Register("statement_timeout:before_update", startStatementTimeout(p.write)),
+			cb.Update().After("gorm:commit_or_rollback_transaction").Register("statement_timeout:after_update", stopStatementTimeout),
+			cb.Delete().Before("gorm:begin_transaction").Register("statement_timeout:before_delete", startStatementTimeout(p.write)),
+			cb.Delete().After("gorm:commit_or_rollback_transaction").Register("statement_timeout:after_delete", stopStatementTimeout),
+			cb.Raw().Before("gorm:raw").Register("statement_timeout:before_raw", startStatementTimeout(p.write)),
+			cb.Raw().After("gorm:raw").Register("statement_timeout:after_raw", stopStatementTimeout),
+		)
+	}
+	return errors.Join(errs...)
+}
+
+func startStatementTimeout(d time.Duration) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		ctx, cancel := context.WithTimeout(parent, d)
+		db.Statement.Context = ctx
+		db.InstanceSet(statementParentKey, parent)
+		db.InstanceSet(statementCancelKey, cancel)
+	}
+}
+
+// stopStatementTimeout releases the deadline and counts statements it aborted. Timeouts of
+// the surrounding request are counted by the request timeout middleware instead.
+func stopStatementTimeout(db *gorm.DB) {
+	if cancel, ok := db.InstanceGet(statementCancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+	parent, _ := db.InstanceGet(statementParentKey)
+	if parentCtx, ok := parent.(context.Context); ok {
+		db.Statement.Context = parentCtx
+		if parentCtx.Err() != nil {
+			return
+		}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.Is(db.Error, context.DeadlineExceeded) || (errors.As(db.Error, &pgErr) && pgErr.Code == pgQueryCanceled) {
+		metrics.Timeouts.WithLabelValues(metrics.ScopeDatabase).Inc()
+	}
+}
@@ -0,0 +1,67 @@
+package infra
+
+import (
+	"errors"
+
+	"zenrows-challenge/internal/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const statementSpanKey = "statement_tracing:span"
+
+// statementTracing is a GORM plugin recording a client span per statement, as a child of
+// the span in the statement's context. The SQL is recorded with its placeholders, never
+// with the bound values.
+type statementTracing struct{}
+
+func (statementTracing) Name() string { return "statement_tracing" }
+
+func (statementTracing) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Query().Before("gorm:query").Register("statement_tracing:before_query", startStatementSpan("select")),
+		cb.Query().After("gorm:after_query").Register("statement_tracing:after_query", endStatementSpan),
+		cb.Create().Before("gorm:begin_transaction").Register("statement_tracing:before_create", startStatementSpan("insert")),
+		cb.Create().After("gorm:commit_or_rollback_transaction").Register("statement_tracing:after_create", endStatementSpan),
+		cb.Update().Before("gorm:begin_transaction").Register("statement_tracing:before_update", startStatementSpan("update")),
+		cb.Update().After("gorm:commit_or_rollback_transaction").Register("statement_tracing:after_update", endStatementSpan),
+		cb.Delete().Before("gorm:begin_transaction").Register("statement_tracing:before_delete", startStatementSpan("delete")),
+		cb.Delete().After("gorm:commit_or_rollback_transaction").Register("statement_tracing:after_delete", endStatementSpan),
+		cb.Raw().Before("gorm:raw").Register("statement_tracing:before_raw", startStatementSpan("raw")),
+		cb.Raw().After("gorm:raw").Register("statement_tracing:after_raw", endStatementSpan),
+		cb.Row().Before("gorm:row").Register("statement_tracing:before_row", startStatementSpan("select")),
+		cb.Row().After("gorm:row").Register("statement_tracing:after_row", endStatementSpan),
+	)
+}
+
+func startStatementSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := tracing.Tracer().Start(db.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "postgresql"),
+				attribute.String("db.operation.name", operation),
+			))
+		db.InstanceSet(statementSpanKey, span)
+	}
+}
+
+func endStatementSpan(db *gorm.DB) {
+	v, ok := db.InstanceGet(statementSpanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	span.SetAttributes(
+		attribute.String("db.collection.name", db.Statement.Table),
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		tracing.RecordError(span, db.Error)
+	}
+	span.End()
+}
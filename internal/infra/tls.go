@@ -0,0 +1,214 @@
+package infra
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+)
+
+// Client certificate modes of server.tls.client_auth.mode.
+const (
+	// ClientAuthNone does not ask clients for a certificate.
+	ClientAuthNone = "none"
+	// ClientAuthOptional verifies the certificate of clients presenting one; the others
+	// authenticate with Basic credentials.
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire rejects connections without a valid client certificate.
+	ClientAuthRequire = "require"
+)
+
+// Certificate fields mapped to usernames, for server.tls.client_auth.identity.
+const (
+	CertIdentityCN       = "cn"
+	CertIdentitySANDNS   = "san_dns"
+	CertIdentitySANEmail = "san_email"
+)
+
+const defaultCertReloadInterval = 30 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ServerTLSConfig returns the TLS settings of the API listener from server.tls, or nil
+// when TLS is disabled. The certificate and key files are checked for changes at most
+// once per reload_interval during handshakes, so rotated certificates are picked up
+// without a restart.
+func ServerTLSConfig(log applog.AppLogger) (*tls.Config, error) {
+	cfg := Current().Server.TLS
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	certs, err := newCertReloader(log, cfg.CertFile, cfg.KeyFile, cfg.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := cipherSuiteIDs(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	tc := &tls.Config{
+		MinVersion:     tlsVersions[cfg.MinVersion],
+		CipherSuites:   suites,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	switch cfg.ClientAuth.Mode {
+	case ClientAuthOptional:
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return tc, nil
+	}
+	pem, err := os.ReadFile(cfg.ClientAuth.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA file: %w", err)
+	}
+	tc.ClientCAs = x509.NewCertPool()
+	if !tc.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in client CA file %q", cfg.ClientAuth.CAFile)
+	}
+	return tc, nil
+}
+
+// CertificateIdentity returns the func mapping a verified client certificate to a
+// username according to server.tls.client_auth.identity, or nil when client
+// certificates are not requested.
+func CertificateIdentity() func(*x509.Certificate) string {
+	cfg := Current().Server.TLS
+	if !cfg.Enabled || cfg.ClientAuth.Mode == ClientAuthNone {
+		return nil
+	}
+	switch cfg.ClientAuth.Identity {
+	case CertIdentitySANDNS:
+		return func(c *x509.Certificate) string { return firstOf(c.DNSNames) }
+	case CertIdentitySANEmail:
+		return func(c *x509.Certificate) string { return firstOf(c.EmailAddresses) }
+	default:
+		return func(c *x509.Certificate) string { return c.Subject.CommonName }
+	}
+}
+
+func firstOf(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
+
+// cipherSuiteIDs resolves the names of secure TLS 1.2 cipher suites, as listed by
+// tls.CipherSuites. TLS 1.3 suites are not configurable.
+func cipherSuiteIDs(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, n := range names {
+		id, ok := known[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", n)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// tlsProblems returns the violations of server.tls the validation tags cannot express.
+func tlsProblems(cfg TLSConfig) []string {
+	var problems []string
+	if cfg.Enabled && (cfg.CertFile == "" || cfg.KeyFile == "") {
+		problems = append(problems, "server.tls.cert_file and server.tls.key_file are required when server.tls.enabled is set")
+	}
+	if _, err := cipherSuiteIDs(cfg.CipherSuites); err != nil {
+		problems = append(problems, "server.tls.cipher_suites "+err.Error())
+	}
+	if cfg.ClientAuth.Mode != ClientAuthNone {
+		if !cfg.Enabled {
+			problems = append(problems, "server.tls.client_auth.mode requires server.tls.enabled")
+		}
+		if cfg.ClientAuth.CAFile == "" {
+			problems = append(problems, "server.tls.client_auth.ca_file is required when client certificates are requested")
+		}
+	}
+	return problems
+}
+
+// certReloader serves a certificate loaded from files, re-reading them when their
+// modification time changes. A failed reload keeps the previous certificate.
+type certReloader struct {
+	log      applog.AppLogger
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(log applog.AppLogger, certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{log: log, certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.interval > 0 && time.Since(r.checked) >= r.interval {
+		if err := r.reload(); err != nil {
+			r.log.Error("Failed to reload TLS certificate, serving the previous one", "cert_file", r.certFile, "error", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// reload loads the key pair when either file changed since the last load. Callers must
+// hold r.mu, except during construction.
+func (r *certReloader) reload() error {
+	r.checked = time.Now()
+	var modTime time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if r.cert != nil && modTime.Equal(r.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if len(cert.Certificate) == 0 {
+		return errors.New("no certificate in " + r.certFile)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	cert.Leaf = leaf
+	r.cert, r.modTime = &cert, modTime
+	r.log.Info("TLS certificate loaded", "cert_file", r.certFile, "subject", leaf.Subject.String(), "not_after", leaf.NotAfter)
+	return nil
+}
@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Span exporters selectable through tracing.exporter.
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// InitTracing installs W3C trace context propagation and the global tracer provider.
+// Spans are sent over OTLP/HTTP to tracing.endpoint, printed to stdout, or, with the
+// "none" exporter, not recorded at all; incoming trace IDs are propagated either way.
+// The returned function flushes the spans still buffered.
+func InitTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	cfg := Current().Tracing
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch kind := strings.ToLower(strings.TrimSpace(cfg.Exporter)); kind {
+	case TracingExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case TracingExporterOTLP:
+		var opts []otlptracehttp.Option
+		// Without an endpoint the exporter honours the OTEL_EXPORTER_OTLP_* variables.
+		if endpoint := cfg.Endpoint; endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create span exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", Current().Server.Name))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
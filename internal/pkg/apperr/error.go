@@ -7,4 +7,18 @@ type BaseError interface {
 	Message() string
 	// CauseError returns the underlying cause, if any.
 	CauseError() error
+	// Details returns the field-level violations behind the error, if any.
+	Details() []FieldViolation
+}
+
+// FieldViolation describes why a single request field was rejected.
+type FieldViolation struct {
+	// Field is the JSON path of the offending field, e.g. "name" or "custom_headers[X-Token]".
+	Field string `json:"field"`
+	// Rule is the violated validation rule, e.g. "required" or "max".
+	Rule string `json:"rule"`
+	// Param is the rule parameter, e.g. "100" for max=100.
+	Param string `json:"param,omitempty"`
+	// Message is a human readable explanation.
+	Message string `json:"message"`
 }
@@ -1,11 +1,15 @@
 package apperr
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type appError struct {
-	code  string
-	msg   string
-	cause error
+	code    string
+	msg     string
+	cause   error
+	details []FieldViolation
 }
 
 func newAppError(code, msg string, cause error) appError {
@@ -24,6 +28,8 @@ func (e appError) Message() string   { return e.msg }
 func (e appError) CauseError() error { return e.cause }
 func (e appError) Unwrap() error     { return e.cause }
 
+func (e appError) Details() []FieldViolation { return e.details }
+
 type InvalidArgErr struct{ appError }
 
 // NewInvalidArgErr builds an INVALID_ARGUMENT Error for bad client input.
@@ -34,6 +40,12 @@ func NewInvalidArgErr(msg string, cause error) *InvalidArgErr {
 // Error renders the InvalidArgErr as a string.
 func (e *InvalidArgErr) Error() string { return e.appError.Error() }
 
+// WithDetails attaches field violations to the InvalidArgErr.
+func (e *InvalidArgErr) WithDetails(details ...FieldViolation) *InvalidArgErr {
+	e.details = append(e.details, details...)
+	return e
+}
+
 type NotFoundErr struct{ appError }
 
 // NewNotFoundErr builds a NOT_FOUND Error when a resource is missing.
@@ -54,6 +66,12 @@ func NewAlreadyExistsErr(msg string, cause error) *AlreadyExistsErr {
 // Error renders the AlreadyExistsErr as a string.
 func (e *AlreadyExistsErr) Error() string { return e.appError.Error() }
 
+// WithDetails attaches the fields holding the duplicate values to the AlreadyExistsErr.
+func (e *AlreadyExistsErr) WithDetails(details ...FieldViolation) *AlreadyExistsErr {
+	e.details = append(e.details, details...)
+	return e
+}
+
 type NotAuthorizedErr struct{ appError }
 
 // NewNotAuthorizedErr builds a NOT_AUTHORIZED Error for failed authz/authn.
@@ -64,6 +82,16 @@ func NewNotAuthorizedErr(msg string, cause error) *NotAuthorizedErr {
 // Error renders the NotAuthorizedErr as a string.
 func (e *NotAuthorizedErr) Error() string { return e.appError.Error() }
 
+type PermissionDeniedErr struct{ appError }
+
+// NewPermissionDeniedErr builds a PERMISSION_DENIED Error when an authenticated caller lacks rights.
+func NewPermissionDeniedErr(msg string, cause error) *PermissionDeniedErr {
+	return &PermissionDeniedErr{appError: newAppError("PERMISSION_DENIED", msg, cause)}
+}
+
+// Error renders the PermissionDeniedErr as a string.
+func (e *PermissionDeniedErr) Error() string { return e.appError.Error() }
+
 type InternalErr struct{ appError }
 
 // NewInternalErr builds an INTERNAL_ERROR for unexpected failures.
@@ -73,3 +101,50 @@ func NewInternalErr(msg string, cause error) *InternalErr {
 
 // Error renders the InternalErr as a string.
 func (e *InternalErr) Error() string { return e.appError.Error() }
+
+type QuotaExceededErr struct {
+	appError
+	resource   string
+	retryAfter time.Duration
+}
+
+// NewQuotaExceededErr builds a QUOTA_EXCEEDED Error when a user limit on resource is hit.
+func NewQuotaExceededErr(resource, msg string, cause error) *QuotaExceededErr {
+	return &QuotaExceededErr{appError: newAppError("QUOTA_EXCEEDED", msg, cause), resource: resource}
+}
+
+// Error renders the QuotaExceededErr as a string.
+func (e *QuotaExceededErr) Error() string { return e.appError.Error() }
+
+// Resource returns the name of the exhausted quota.
+func (e *QuotaExceededErr) Resource() string { return e.resource }
+
+// WithRetryAfter records when the exhausted quota is replenished, for quotas that are.
+func (e *QuotaExceededErr) WithRetryAfter(d time.Duration) *QuotaExceededErr {
+	e.retryAfter = d
+	return e
+}
+
+// RetryAfter returns how long until the quota is replenished, or 0 when it is not on its own.
+func (e *QuotaExceededErr) RetryAfter() time.Duration { return e.retryAfter }
+
+type TimeoutErr struct{ appError }
+
+// NewTimeoutErr builds a TIMEOUT Error when an operation was aborted by a deadline.
+func NewTimeoutErr(msg string, cause error) *TimeoutErr {
+	return &TimeoutErr{appError: newAppError("TIMEOUT", msg, cause)}
+}
+
+// Error renders the TimeoutErr as a string.
+func (e *TimeoutErr) Error() string { return e.appError.Error() }
+
+type CanceledErr struct{ appError }
+
+// NewCanceledErr builds a CANCELED Error when an operation was abandoned because its
+// request was cancelled, typically by the client closing the connection.
+func NewCanceledErr(msg string, cause error) *CanceledErr {
+	return &CanceledErr{appError: newAppError("CANCELED", msg, cause)}
+}
+
+// Error renders the CanceledErr as a string.
+func (e *CanceledErr) Error() string { return e.appError.Error() }
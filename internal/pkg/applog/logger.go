@@ -1,6 +1,10 @@
 package applog
 
-// AppLogger describes the logging contract used throughout the application.
+import "context"
+
+// AppLogger describes the logging contract used throughout the application. The plain
+// methods take a message followed by alternating attribute keys and values; the methods
+// ending in f take a printf-style format and its arguments.
 type AppLogger interface {
 	// Info logs informational events.
 	Info(msg string, args ...any)
@@ -14,4 +18,31 @@ type AppLogger interface {
 	Trace(msg string, args ...any)
 	// Fatal logs a critical error and terminates the process.
 	Fatal(msg string, args ...any)
+
+	// Infof logs a formatted informational message.
+	Infof(format string, args ...any)
+	// Warnf logs a formatted warning.
+	Warnf(format string, args ...any)
+	// Errorf logs a formatted error.
+	Errorf(format string, args ...any)
+	// Debugf logs a formatted debugging message.
+	Debugf(format string, args ...any)
+	// Tracef logs a formatted trace message.
+	Tracef(format string, args ...any)
+	// Fatalf logs a formatted critical error and terminates the process.
+	Fatalf(format string, args ...any)
+
+	// With returns a child logger adding the given attributes to every record.
+	With(args ...any) AppLogger
+	// WithContext returns a logger whose records carry the request ID and the trace and
+	// span IDs found in ctx.
+	WithContext(ctx context.Context) AppLogger
+}
+
+// LevelController reads and changes the minimum level of a logger at runtime.
+type LevelController interface {
+	// Level returns the name of the current minimum level.
+	Level() string
+	// SetLevel changes the minimum level; it fails for unknown level names.
+	SetLevel(level string) error
 }
@@ -2,72 +2,315 @@ package applog
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/spf13/viper"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/util"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-// DefaultLogger wraps slog.Logger and implements AppLogger.
+const levelTrace = slog.Level(-8)
+
+// DefaultLogger wraps slog.Logger and implements AppLogger and LevelController. Child
+// loggers share the level and the Trace sampler of the logger they derive from.
 type DefaultLogger struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	ctx     context.Context
+	level   *slog.LevelVar
+	sampler *sampler
+}
+
+// Options configure a DefaultLogger.
+type Options struct {
+	// Level is the initial minimum level, info when empty.
+	Level string
+	// Format selects between text (the default) and json output.
+	Format string
+	// TraceSampling bounds the volume of Trace records; an Initial of 0 disables it.
+	TraceSampling SamplingOptions
+	// Redactor masks secrets in every record; none are masked when nil.
+	Redactor *redact.Redactor
+}
+
+// SamplingOptions keep, per Tick, the first Initial Trace records with the same message
+// and then every Thereafter-th one.
+type SamplingOptions struct {
+	Initial    int
+	Thereafter int
+	Tick       time.Duration
+}
+
+// New creates a DefaultLogger writing to w.
+func New(w io.Writer, o Options) *DefaultLogger {
+	return newLogger(w, o.Level, o.Format,
+		newSampler(o.TraceSampling.Initial, o.TraceSampling.Thereafter, o.TraceSampling.Tick), o.Redactor)
 }
 
-// NewAppDefaultLogger creates a new DefaultLogger configured from application settings.
+// NewAppDefaultLogger creates a DefaultLogger writing text records at info level to
+// stdout, masking the default sensitive headers, log keys and patterns. The service
+// builds its logger from the configuration instead; see infra.NewLogger.
 func NewAppDefaultLogger() *DefaultLogger {
-	levelStr := viper.GetString("log.level")
-	level := parseLogLevel(levelStr)
+	return NewAppLoggerTo(os.Stdout)
+}
+
+// NewAppLoggerTo is NewAppDefaultLogger writing to w.
+func NewAppLoggerTo(w io.Writer) *DefaultLogger {
+	r, err := redact.New(redact.DefaultHeaders, redact.DefaultLogKeys, redact.DefaultPatterns)
+	if err != nil {
+		panic(err)
+	}
+	return New(w, Options{Redactor: r})
+}
+
+func newLogger(w io.Writer, level, format string, s *sampler, r *redact.Redactor) *DefaultLogger {
+	lv := new(slog.LevelVar)
+	lv.Set(parseLogLevel(level))
+	opts := &slog.HandlerOptions{Level: lv, ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		a = replaceLevelName(groups, a)
+		if r != nil {
+			a = r.Attr(groups, a)
+		}
+		return a
+	}}
+	var handler slog.Handler
+	if strings.EqualFold(strings.TrimSpace(format), "json") {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
 	return &DefaultLogger{
-		logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})),
+		logger:  slog.New(contextHandler{handler}),
+		ctx:     context.Background(),
+		level:   lv,
+		sampler: s,
+	}
+}
+
+// With returns a child logger adding args to every record.
+func (l *DefaultLogger) With(args ...any) AppLogger {
+	child := *l
+	child.logger = l.logger.With(args...)
+	return &child
+}
+
+// WithContext returns a logger bound to ctx, so its records carry the request and trace
+// of ctx.
+func (l *DefaultLogger) WithContext(ctx context.Context) AppLogger {
+	child := *l
+	child.ctx = ctx
+	return &child
+}
+
+// Level returns the name of the current minimum level.
+func (l *DefaultLogger) Level() string {
+	return levelName(l.level.Level())
+}
+
+// SetLevel changes the minimum level of the logger and of every logger derived from it.
+func (l *DefaultLogger) SetLevel(level string) error {
+	lv, ok := lookupLogLevel(level)
+	if !ok {
+		return fmt.Errorf("unknown log level %q", level)
 	}
+	l.level.Set(lv)
+	return nil
 }
 
 // Info proxies structured info-level logs to slog.
 func (l *DefaultLogger) Info(msg string, args ...any) {
-	l.logger.Info(msg, args...)
+	l.logger.Log(l.ctx, slog.LevelInfo, msg, args...)
 }
 
 // Warn emits warning-level logs.
 func (l *DefaultLogger) Warn(msg string, args ...any) {
-	l.logger.Warn(msg, args...)
+	l.logger.Log(l.ctx, slog.LevelWarn, msg, args...)
 }
 
 // Error reports failures with error severity.
 func (l *DefaultLogger) Error(msg string, args ...any) {
-	l.logger.Error(msg, args...)
+	l.logger.Log(l.ctx, slog.LevelError, msg, args...)
 }
 
 // Debug records verbose debugging information.
 func (l *DefaultLogger) Debug(msg string, args ...any) {
-	l.logger.Debug(msg, args...)
+	l.logger.Log(l.ctx, slog.LevelDebug, msg, args...)
 }
 
-// Trace logs extremely low-level traces using a custom slog level.
+// Trace logs extremely low-level traces using a custom slog level. Records are subject
+// to sampling, keyed by msg.
 func (l *DefaultLogger) Trace(msg string, args ...any) {
-	l.logger.Log(context.Background(), slog.Level(-8), msg, args...)
+	if !l.logger.Enabled(l.ctx, levelTrace) || !l.sampler.allow(msg) {
+		return
+	}
+	l.logger.Log(l.ctx, levelTrace, msg, args...)
 }
 
 // Fatal logs an error and terminates the process with exit code 1.
 func (l *DefaultLogger) Fatal(msg string, args ...any) {
-	l.logger.Error(msg, args...)
+	l.logger.Log(l.ctx, slog.LevelError, msg, args...)
 	os.Exit(1)
 }
 
-func parseLogLevel(s string) slog.Level {
-	s = strings.TrimSpace(strings.ToLower(s))
-	switch s {
+// Infof logs a formatted info-level message.
+func (l *DefaultLogger) Infof(format string, args ...any) {
+	l.logf(slog.LevelInfo, format, args...)
+}
+
+// Warnf logs a formatted warning.
+func (l *DefaultLogger) Warnf(format string, args ...any) {
+	l.logf(slog.LevelWarn, format, args...)
+}
+
+// Errorf logs a formatted error.
+func (l *DefaultLogger) Errorf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args...)
+}
+
+// Debugf logs a formatted debugging message.
+func (l *DefaultLogger) Debugf(format string, args ...any) {
+	l.logf(slog.LevelDebug, format, args...)
+}
+
+// Tracef logs a formatted trace message. Records are sampled by format.
+func (l *DefaultLogger) Tracef(format string, args ...any) {
+	if !l.logger.Enabled(l.ctx, levelTrace) || !l.sampler.allow(format) {
+		return
+	}
+	l.logf(levelTrace, format, args...)
+}
+
+// Fatalf logs a formatted error and terminates the process with exit code 1.
+func (l *DefaultLogger) Fatalf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args...)
+	os.Exit(1)
+}
+
+func (l *DefaultLogger) logf(level slog.Level, format string, args ...any) {
+	if !l.logger.Enabled(l.ctx, level) {
+		return
+	}
+	l.logger.Log(l.ctx, level, fmt.Sprintf(format, args...))
+}
+
+// contextHandler adds the request ID and the trace and span IDs found in the record's
+// context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := util.RequestMetaFrom(ctx).RequestID; id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// sampler lets through the first initial records with a given message in each tick, then
+// every thereafter-th one. A nil sampler lets every record through.
+type sampler struct {
+	initial    int
+	thereafter int
+	tick       time.Duration
+	now        func() time.Time
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+// newSampler returns a sampler, or nil when initial is not positive.
+func newSampler(initial, thereafter int, tick time.Duration) *sampler {
+	if initial <= 0 {
+		return nil
+	}
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return &sampler{initial: initial, thereafter: thereafter, tick: tick, now: time.Now, counts: make(map[string]int)}
+}
+
+func (s *sampler) allow(msg string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.start) >= s.tick {
+		s.start = now
+		clear(s.counts)
+	}
+	s.counts[msg]++
+	n := s.counts[msg]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
+
+// replaceLevelName renders the custom trace level as TRACE rather than DEBUG-4.
+func replaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.LevelKey {
+		if lv, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(strings.ToUpper(levelName(lv)))
+		}
+	}
+	return a
+}
+
+func levelName(lv slog.Level) string {
+	switch {
+	case lv <= levelTrace:
+		return "trace"
+	case lv <= slog.LevelDebug:
+		return "debug"
+	case lv <= slog.LevelInfo:
+		return "info"
+	case lv <= slog.LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+func lookupLogLevel(s string) (slog.Level, bool) {
+	switch strings.TrimSpace(strings.ToLower(s)) {
 	case "trace":
-		return slog.Level(-8)
+		return levelTrace, true
 	case "debug":
-		return slog.LevelDebug
+		return slog.LevelDebug, true
+	case "info":
+		return slog.LevelInfo, true
 	case "warn", "warning":
-		return slog.LevelWarn
+		return slog.LevelWarn, true
 	case "error":
-		return slog.LevelError
-	case "info", "":
-		return slog.LevelInfo
+		return slog.LevelError, true
 	default:
-		return slog.LevelInfo
+		return 0, false
+	}
+}
+
+func parseLogLevel(s string) slog.Level {
+	if lv, ok := lookupLogLevel(s); ok {
+		return lv
 	}
+	return slog.LevelInfo
 }
@@ -0,0 +1,84 @@
+package applog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestDefaultLogger_FormattedAndStructured(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf, "info", "json", nil, nil)
+
+	l.Errorf("device_profile.create failed: %v", errors.New("boom"))
+	l.With("component", "repo").Info("device_profile.list", "page", 2)
+	ctx := util.WithRequestMeta(context.Background(), util.RequestMeta{RequestID: "req-1"})
+	l.WithContext(ctx).Warn("slow query")
+
+	recs := decodeLines(t, &buf)
+	require.Len(t, recs, 3)
+	assert.Equal(t, "device_profile.create failed: boom", recs[0]["msg"])
+	assert.NotContains(t, buf.String(), "!BADKEY")
+	assert.Equal(t, "repo", recs[1]["component"])
+	assert.EqualValues(t, 2, recs[1]["page"])
+	assert.Equal(t, "req-1", recs[2]["request_id"])
+}
+
+func TestDefaultLogger_SetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf, "info", "json", nil, nil)
+	child := l.With("component", "repo")
+
+	child.Debug("hidden")
+	require.NoError(t, l.SetLevel("trace"))
+	assert.Equal(t, "trace", l.Level())
+	child.Trace("shown")
+	assert.Error(t, l.SetLevel("verbose"))
+
+	recs := decodeLines(t, &buf)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "shown", recs[0]["msg"])
+	assert.Equal(t, "TRACE", recs[0]["level"])
+}
+
+func TestDefaultLogger_TraceSampling(t *testing.T) {
+	var buf bytes.Buffer
+	s := newSampler(2, 3, time.Minute)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	l := newLogger(&buf, "trace", "json", s, nil)
+
+	for i := 0; i < 8; i++ {
+		l.Trace("device_profile.list")
+	}
+	l.Trace("device_profile.create")
+	assert.Len(t, decodeLines(t, &buf), 2+2+1, "2 initial, every 3rd of the next 6, and another message")
+
+	buf.Reset()
+	now = now.Add(time.Minute)
+	l.Trace("device_profile.list")
+	assert.Len(t, decodeLines(t, &buf), 1, "counts reset every tick")
+}
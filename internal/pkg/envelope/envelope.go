@@ -0,0 +1,184 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	// KeySize is the size in bytes of master and data keys (AES-256).
+	KeySize = 32
+
+	// sealedPrefix marks header values encrypted by Seal.
+	sealedPrefix = "enc:v1:"
+)
+
+// ErrUnknownKey is returned when a row was encrypted under a master key missing from the keyring.
+var ErrUnknownKey = errors.New("unknown master key")
+
+// Keyring holds the master keys by ID. New data keys are wrapped with the active one;
+// the others are kept to unwrap the data keys of rows not yet rotated.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewKeyring builds a Keyring, checking that every key is KeySize bytes long and that
+// the active key is present.
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	for id, k := range keys {
+		if len(k) != KeySize {
+			return nil, fmt.Errorf("master key %q must be %d bytes, got %d", id, KeySize, len(k))
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active master key %q: %w", active, ErrUnknownKey)
+	}
+	return &Keyring{active: active, keys: keys}, nil
+}
+
+// ActiveKeyID returns the ID of the master key wrapping new data keys.
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// NewDataKey generates a data key and returns it with its wrapped form and the ID of
+// the master key that wrapped it.
+func (k *Keyring) NewDataKey() (dataKey, wrapped []byte, keyID string, err error) {
+	dataKey = make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, "", err
+	}
+	wrapped, err = seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return dataKey, wrapped, k.active, nil
+}
+
+// UnwrapDataKey decrypts a data key wrapped by the master key keyID.
+func (k *Keyring) UnwrapDataKey(keyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %q: %w", keyID, ErrUnknownKey)
+	}
+	return open(master, wrapped, []byte(keyID))
+}
+
+// EncryptHeaders encrypts the string values of h under a new data key. Header names are
+// kept in clear so rows can still be filtered by them. It returns the encrypted copy,
+// the master key ID and the wrapped data key to store alongside it.
+func (k *Keyring) EncryptHeaders(h datatypes.JSONMap) (datatypes.JSONMap, string, []byte, error) {
+	dataKey, wrapped, keyID, err := k.NewDataKey()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	out := make(datatypes.JSONMap, len(h))
+	for name, v := range h {
+		s, ok := v.(string)
+		if !ok {
+			out[name] = v
+			continue
+		}
+		ct, err := seal(dataKey, []byte(s), []byte(name))
+		if err != nil {
+			return nil, "", nil, err
+		}
+		out[name] = sealedPrefix + base64.StdEncoding.EncodeToString(ct)
+	}
+	return out, keyID, wrapped, nil
+}
+
+// DecryptHeaders reverses EncryptHeaders. Rows without a key ID predate encryption and
+// are returned as they are.
+func (k *Keyring) DecryptHeaders(h datatypes.JSONMap, keyID *string, wrapped []byte) (datatypes.JSONMap, error) {
+	if keyID == nil {
+		return h, nil
+	}
+	dataKey, err := k.UnwrapDataKey(*keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	out := make(datatypes.JSONMap, len(h))
+	for name, v := range h {
+		s, ok := v.(string)
+		if !ok || !strings.HasPrefix(s, sealedPrefix) {
+			out[name] = v
+			continue
+		}
+		ct, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, sealedPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", name, err)
+		}
+		pt, err := open(dataKey, ct, []byte(name))
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", name, err)
+		}
+		out[name] = string(pt)
+	}
+	return out, nil
+}
+
+// Encrypt encrypts plaintext under a new data key, authenticating aad, which must be
+// passed again to Decrypt. It returns the ciphertext, the master key ID and the wrapped
+// data key to store alongside it.
+func (k *Keyring) Encrypt(plaintext, aad []byte) ([]byte, string, []byte, error) {
+	dataKey, wrapped, keyID, err := k.NewDataKey()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	ct, err := seal(dataKey, plaintext, aad)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return ct, keyID, wrapped, nil
+}
+
+// Decrypt reverses Encrypt.
+func (k *Keyring) Decrypt(ciphertext []byte, keyID string, wrapped, aad []byte) ([]byte, error) {
+	dataKey, err := k.UnwrapDataKey(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return open(dataKey, ciphertext, aad)
+}
+
+// seal encrypts plaintext with AES-GCM, authenticating aad, and prefixes the nonce.
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ct := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ct, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,111 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+	k := make([]byte, KeySize)
+	_, err := rand.Read(k)
+	require.NoError(t, err)
+	return k
+}
+
+func TestNewKeyring_Validation(t *testing.T) {
+	_, err := NewKeyring("k1", map[string][]byte{"k1": []byte("short")})
+	assert.Error(t, err)
+
+	_, err = NewKeyring("k2", map[string][]byte{"k1": newKey(t)})
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_HeadersRoundTrip(t *testing.T) {
+	kr, err := NewKeyring("k1", map[string][]byte{"k1": newKey(t)})
+	require.NoError(t, err)
+
+	in := datatypes.JSONMap{"Cookie": "session=abc", "X-Retries": float64(3)}
+	enc, keyID, wrapped, err := kr.EncryptHeaders(in)
+	require.NoError(t, err)
+
+	assert.Equal(t, "k1", keyID)
+	assert.Contains(t, enc, "Cookie", "header names stay in clear")
+	assert.True(t, strings.HasPrefix(enc["Cookie"].(string), sealedPrefix))
+	assert.NotContains(t, enc["Cookie"], "abc")
+	assert.Equal(t, float64(3), enc["X-Retries"])
+	assert.False(t, bytes.Contains(wrapped, kr.keys["k1"]))
+
+	out, err := kr.DecryptHeaders(enc, &keyID, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	k1, k2 := newKey(t), newKey(t)
+	old, err := NewKeyring("k1", map[string][]byte{"k1": k1})
+	require.NoError(t, err)
+	enc, keyID, wrapped, err := old.EncryptHeaders(datatypes.JSONMap{"Authorization": "Bearer t"})
+	require.NoError(t, err)
+
+	rotated, err := NewKeyring("k2", map[string][]byte{"k1": k1, "k2": k2})
+	require.NoError(t, err)
+	out, err := rotated.DecryptHeaders(enc, &keyID, wrapped)
+	require.NoError(t, err, "rows under a retired key remain readable")
+	assert.Equal(t, "Bearer t", out["Authorization"])
+
+	_, newID, _, err := rotated.EncryptHeaders(out)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", newID)
+
+	withoutOld, err := NewKeyring("k2", map[string][]byte{"k2": k2})
+	require.NoError(t, err)
+	_, err = withoutOld.DecryptHeaders(enc, &keyID, wrapped)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_DetectsTampering(t *testing.T) {
+	kr, err := NewKeyring("k1", map[string][]byte{"k1": newKey(t)})
+	require.NoError(t, err)
+	enc, keyID, wrapped, err := kr.EncryptHeaders(datatypes.JSONMap{"A": "1", "B": "2"})
+	require.NoError(t, err)
+
+	// Swapping values between headers breaks the authentication of the header name.
+	enc["A"], enc["B"] = enc["B"], enc["A"]
+	_, err = kr.DecryptHeaders(enc, &keyID, wrapped)
+	assert.Error(t, err)
+}
+
+func TestKeyring_PlaintextRowsPassThrough(t *testing.T) {
+	kr, err := NewKeyring("k1", map[string][]byte{"k1": newKey(t)})
+	require.NoError(t, err)
+	in := datatypes.JSONMap{"Accept": "*/*"}
+
+	out, err := kr.DecryptHeaders(in, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+}
+
+func TestKeyring_EncryptRoundTrip(t *testing.T) {
+	kr, err := NewKeyring("k1", map[string][]byte{"k1": newKey(t)})
+	require.NoError(t, err)
+	body := []byte(`{"custom_headers":{"Cookie":"session=abc"}}`)
+
+	ct, keyID, wrapped, err := kr.Encrypt(body, []byte("scope/key"))
+	require.NoError(t, err)
+	assert.Equal(t, "k1", keyID)
+	assert.False(t, bytes.Contains(ct, []byte("session=abc")))
+
+	out, err := kr.Decrypt(ct, keyID, wrapped, []byte("scope/key"))
+	require.NoError(t, err)
+	assert.Equal(t, body, out)
+
+	_, err = kr.Decrypt(ct, keyID, wrapped, []byte("scope/other"))
+	assert.Error(t, err, "ciphertext is bound to its aad")
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "zenrows"
+
+	// ScopeRequest labels timeouts of a whole HTTP request.
+	ScopeRequest = "request"
+	// ScopeDatabase labels timeouts of a single database statement.
+	ScopeDatabase = "database"
+
+	// AuthSuccess labels accepted authentication attempts.
+	AuthSuccess = "success"
+	// AuthFailure labels rejected authentication attempts.
+	AuthFailure = "failure"
+
+	// ReloadApplied labels configuration reloads that changed reloadable settings.
+	ReloadApplied = "applied"
+	// ReloadUnchanged labels configuration reloads that changed no reloadable setting.
+	ReloadUnchanged = "unchanged"
+	// ReloadRejected labels configuration reloads that failed to read or validate.
+	ReloadRejected = "rejected"
+)
+
+// Timeouts counts requests and database statements aborted by a deadline, by scope.
+var Timeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "timeouts_total",
+	Help:      "Requests and database statements aborted because their deadline expired.",
+}, []string{"scope"})
+
+// HTTPRequests counts handled HTTP requests by method, route template and status.
+var HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "requests_total",
+	Help:      "HTTP requests handled, by method, route template and status.",
+}, []string{"method", "route", "status"})
+
+// HTTPRequestDuration observes HTTP request latency by method, route template and status.
+var HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "request_duration_seconds",
+	Help:      "HTTP request latency, by method, route template and status.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "route", "status"})
+
+// HTTPRequestsInFlight is the number of HTTP requests currently being served.
+var HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "requests_in_flight",
+	Help:      "HTTP requests currently being served.",
+})
+
+// AuthAttempts counts authentication attempts by outcome.
+var AuthAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "auth",
+	Name:      "attempts_total",
+	Help:      "Authentication attempts, by outcome.",
+}, []string{"outcome"})
+
+// ConfigReloads counts configuration reloads by result.
+var ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "config",
+	Name:      "reloads_total",
+	Help:      "Configuration reloads, by result.",
+}, []string{"result"})
+
+// ConfigRestartRequired is the number of changed settings that only apply after a restart.
+var ConfigRestartRequired = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: "config",
+	Name:      "restart_required_settings",
+	Help:      "Settings changed in the configuration that only apply after a restart.",
+})
+
+// QueryDuration observes the latency of repository operations, e.g. device_profile.list.
+var QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "db",
+	Name:      "query_duration_seconds",
+	Help:      "Latency of repository operations, by operation.",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+}, []string{"operation"})
+
+// ObserveQuery starts timing the repository operation and returns the func recording
+// it, meant to be deferred: defer metrics.ObserveQuery("device_profile.list")().
+func ObserveQuery(operation string) func() {
+	start := time.Now()
+	return func() {
+		QueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBStats exposes the connection pool statistics of db under the given name.
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves every registered metric, Go runtime and process metrics included, in
+// the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -1,58 +1,118 @@
 package middleware
 
 import (
+	"crypto/x509"
 	"encoding/base64"
+	"net/http"
 	"strings"
 
 	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/metrics"
+	"zenrows-challenge/internal/pkg/problem"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/util"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
 )
 
-// AuthUserIDKey is the key stored in Fiber locals for the authenticated user's ID.
-const AuthUserIDKey = "auth_user_id"
-
-// BasicAuthCheckMiddleware validates HTTP Basic credentials and stores the user ID
-// in the context before passing control to the next handler.
+// BasicAuthCheckMiddleware validates HTTP Basic credentials and stores the caller's
+// util.Principal in the request context before passing control to the next handler.
+// Requests already authenticated, by ClientCertAuthMiddleware, are passed on as is.
+// It must run after RequestContextMiddleware.
 func BasicAuthCheckMiddleware(svc port.AuthenticationService, v *validator.Validate) fiber.Handler {
 	return func(c fiber.Ctx) error {
+		if _, ok := util.PrincipalFrom(c.Context()); ok {
+			return c.Next()
+		}
 		h := c.Get("Authorization")
 		if h == "" {
-			return fiber.ErrUnauthorized
+			return unauthorized(c)
 		}
 
+		ctx := c.Context()
+
 		parts := strings.SplitN(h, " ", 2)
 		if len(parts) != 2 || !strings.EqualFold(parts[0], "Basic") {
-			return fiber.ErrUnauthorized
+			svc.RecordAuthFailure(ctx, "", "unsupported authorization scheme")
+			return unauthorized(c)
 		}
 
 		b, err := base64.StdEncoding.DecodeString(parts[1])
 		if err != nil {
-			return fiber.ErrUnauthorized
+			svc.RecordAuthFailure(ctx, "", "malformed credentials")
+			return unauthorized(c)
 		}
 
 		creds := string(b)
 		i := strings.IndexByte(creds, ':')
 		if i <= 0 {
-			return fiber.ErrUnauthorized
+			svc.RecordAuthFailure(ctx, "", "malformed credentials")
+			return unauthorized(c)
 		}
 
+		// The password is only ever handled as a redact.Secret, so it cannot reach logs,
+		// spans or error causes by accident.
 		user := creds[:i]
-		pass := creds[i+1:]
+		pass := redact.Secret(creds[i+1:])
 		if user == "" || pass == "" {
-			return fiber.ErrUnauthorized
+			svc.RecordAuthFailure(ctx, user, "empty credentials")
+			return unauthorized(c)
 		}
 
-		userID, err := svc.CheckCredentials(user, pass)
+		userID, err := svc.CheckCredentials(ctx, user, pass)
 		if err != nil {
-			return fiber.ErrUnauthorized
+			return unauthorized(c)
+		}
+
+		if v.Var(userID, "required,uuid4") != nil {
+			return unauthorized(c)
+		}
+		metrics.AuthAttempts.WithLabelValues(metrics.AuthSuccess).Inc()
+		c.SetContext(util.WithPrincipal(ctx, util.Principal{UserID: uuid.MustParse(userID), Username: user}))
+		return c.Next()
+	}
+}
+
+// ClientCertAuthMiddleware authenticates requests sent over a TLS connection with a
+// verified client certificate as the user identity maps the certificate to. Requests
+// without one are left to BasicAuthCheckMiddleware, which must follow; a certificate
+// mapped to no active user is rejected. With a nil identity, client certificates are
+// ignored. It must run after RequestContextMiddleware.
+func ClientCertAuthMiddleware(svc port.AuthenticationService, identity func(*x509.Certificate) string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if identity == nil {
+			return c.Next()
+		}
+		state := c.RequestCtx().TLSConnectionState()
+		if state == nil || len(state.VerifiedChains) == 0 {
+			return c.Next()
 		}
 
-		if userID == "" && v.Var(userID, "required,uuid4") != nil {
-			return fiber.ErrUnauthorized
+		ctx := c.Context()
+		user := identity(state.VerifiedChains[0][0])
+		if user == "" {
+			svc.RecordAuthFailure(ctx, "", "client certificate without identity")
+			return unauthorized(c)
+		}
+		userID, err := svc.CheckCertificateUser(ctx, user)
+		if err != nil {
+			return unauthorized(c)
 		}
-		c.Locals(AuthUserIDKey, userID)
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return unauthorized(c)
+		}
+		metrics.AuthAttempts.WithLabelValues(metrics.AuthSuccess).Inc()
+		c.SetContext(util.WithPrincipal(ctx, util.Principal{UserID: id, Username: user}))
 		return c.Next()
 	}
 }
+
+// unauthorized counts the failed attempt and challenges the client for Basic credentials.
+func unauthorized(c fiber.Ctx) error {
+	metrics.AuthAttempts.WithLabelValues(metrics.AuthFailure).Inc()
+	c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="zenrows"`)
+	return problem.Write(c, http.StatusUnauthorized, "NOT_AUTHORIZED", "unauthorized")
+}
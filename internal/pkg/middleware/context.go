@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// requestWatchInterval is how often a running request checks for a server shutdown when
+// client disconnects are not checked. Requests finishing sooner are never checked.
+const requestWatchInterval = 200 * time.Millisecond
+
+// ErrClientDisconnected is the cause of the request context cancellation when the client
+// closes its connection before the response is written.
+var ErrClientDisconnected = errors.New("client disconnected")
+
+// RequestContextMiddleware roots the request's context.Context, returned by c.Context(),
+// in the fasthttp request context and attaches the RequestMeta of the request. Work
+// derived from it is cancelled when the server shuts down, a deadline set further down
+// the chain expires or the client closes the connection, with ErrClientDisconnected as
+// the cause. It must run before any middleware reading c.Context().
+//
+// Disconnects are checked every disconnectCheck, once a request has run that long, by
+// peeking at the socket; zero disables the check. The end of the client's stream is taken
+// as a disconnect, so clients that half-close their side of the connection after sending
+// the request are not supported while the check is on. TLS clients that send close_notify
+// before closing are not detected.
+func RequestContextMiddleware(disconnectCheck time.Duration) fiber.Handler {
+	interval := requestWatchInterval
+	if disconnectCheck > 0 {
+		interval = disconnectCheck
+	}
+	return func(c fiber.Ctx) error {
+		// The fasthttp context is not a parent context.WithCancel can watch without a
+		// goroutine racing the server shutdown, so the request polls it instead.
+		ctx, cancel := context.WithCancelCause(context.WithoutCancel(c.RequestCtx()))
+		defer cancel(nil)
+		var peerClosed func() bool
+		if disconnectCheck > 0 {
+			peerClosed = peerClosedFunc(c.RequestCtx().Conn())
+		}
+		stop := watchRequest(c.RequestCtx().Done(), peerClosed, interval, cancel)
+		defer stop()
+
+		c.SetContext(util.WithRequestMeta(ctx, util.RequestMetaFromFiber(c)))
+		return c.Next()
+	}
+}
+
+// watchRequest cancels the request once shutdown is closed, or with ErrClientDisconnected
+// once peerClosed reports the client gone, checking every interval until stop is called.
+// With a nil peerClosed, only the shutdown is watched.
+func watchRequest(shutdown <-chan struct{}, peerClosed func() bool, interval time.Duration, cancel context.CancelCauseFunc) (stop func()) {
+	var (
+		mu      sync.Mutex
+		stopped bool
+		timer   *time.Timer
+	)
+	check := func() {
+		var cause error
+		select {
+		case <-shutdown:
+			cause = context.Canceled
+		default:
+			// The peek runs unlocked so stop never waits for it.
+			if peerClosed != nil && peerClosed() {
+				cause = ErrClientDisconnected
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return
+		}
+		if cause != nil {
+			cancel(cause)
+			return
+		}
+		timer.Reset(interval)
+	}
+	mu.Lock()
+	timer = time.AfterFunc(interval, check)
+	mu.Unlock()
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		timer.Stop()
+	}
+}
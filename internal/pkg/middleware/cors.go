@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"time"
+
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/cors"
+)
+
+// CORSMiddleware answers preflight requests and sets the CORS response headers for the
+// origins allowed accepts. allowed is called on every request, so the accepted origins
+// can change at runtime; requests from other origins get no CORS headers, which makes
+// browsers block them.
+func CORSMiddleware(allowed func(origin string) bool, allowCredentials bool, maxAge time.Duration) fiber.Handler {
+	return cors.New(cors.Config{
+		AllowOriginsFunc: allowed,
+		AllowHeaders: []string{
+			fiber.HeaderAuthorization, fiber.HeaderContentType, fiber.HeaderAccept,
+			util.RequestIDHeader, IdempotencyKeyHeader,
+		},
+		ExposeHeaders: []string{
+			util.RequestIDHeader, fiber.HeaderRetryAfter,
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset",
+		},
+		AllowCredentials: allowCredentials,
+		MaxAge:           int(maxAge.Seconds()),
+	})
+}
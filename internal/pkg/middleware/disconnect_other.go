@@ -0,0 +1,10 @@
+//go:build !(linux || darwin)
+
+package middleware
+
+import "net"
+
+// peerClosedFunc returns nil: client disconnects are not detected on this platform.
+func peerClosedFunc(net.Conn) func() bool {
+	return nil
+}
@@ -0,0 +1,44 @@
+//go:build linux || darwin
+
+package middleware
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerClosedFunc returns a function reporting whether the peer closed conn, or nil when
+// conn does not expose its socket. The check peeks at the socket without blocking, so
+// data of a pipelined request is left for the server to read. The end of the stream
+// counts as closed: a half-close cannot be told apart from a close.
+func peerClosedFunc(conn net.Conn) func() bool {
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = tc.NetConn()
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	return func() bool {
+		var closed bool
+		buf := make([]byte, 1)
+		err := rc.Read(func(fd uintptr) bool {
+			n, _, err := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+			switch {
+			case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EINTR):
+			case err != nil:
+				closed = true
+			default:
+				closed = n == 0
+			}
+			return true
+		})
+		return closed || err != nil
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/problem"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client supplied key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from a stored key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyMiddleware makes a route safe to retry when the client sends an
+// Idempotency-Key header. The first request reserves the key; its response is stored
+// for ttl and replayed to later requests using the same key and body. Reusing the key
+// with a different request yields 422, and a retry arriving while the first request
+// is still running yields 409. The reservation is leased for lease, which should
+// exceed the request timeout: past it, the first request is presumed lost and a retry
+// takes the key over. Server errors release the key so the request can be retried.
+// It must run after BasicAuthCheckMiddleware, as keys are scoped per user.
+func IdempotencyMiddleware(log applog.AppLogger, repo port.IdempotencyRepo, ttl, lease time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return problem.Write(c, http.StatusBadRequest, "INVALID_ARGUMENT", "Idempotency-Key must be at most 255 characters")
+		}
+
+		p, ok := util.PrincipalFrom(c.Context())
+		if !ok {
+			return problem.Write(c, http.StatusUnauthorized, "NOT_AUTHORIZED", "unauthorized")
+		}
+
+		now := time.Now()
+		lockedUntil := now.Add(lease)
+		rec := &entity.IdempotencyKey{
+			Scope:       p.UserID.String(),
+			Key:         key,
+			Fingerprint: requestFingerprint(c),
+			ExpiresAt:   now.Add(ttl),
+			LockedUntil: &lockedUntil,
+		}
+		stored, reserved, err := repo.ReserveIdempotencyKey(c.Context(), rec)
+		if err != nil {
+			log.Error("idempotency key reservation failed", "key", key, "error", err)
+			return problem.Write(c, http.StatusInternalServerError, "INTERNAL_ERROR", "internal error")
+		}
+
+		if !reserved {
+			return replayIdempotentResponse(c, stored, rec.Fingerprint)
+		}
+
+		if err := c.Next(); err != nil {
+			releaseIdempotencyKey(c.Context(), log, repo, rec)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			releaseIdempotencyKey(c.Context(), log, repo, rec)
+			return nil
+		}
+
+		contentType := string(c.Response().Header.ContentType())
+		rec.ResponseStatus = &status
+		rec.ResponseBody = append([]byte(nil), c.Response().Body()...)
+		rec.ContentType = &contentType
+		if err := repo.CompleteIdempotencyKey(c.Context(), rec); err != nil {
+			log.Error("idempotency key completion failed", "key", key, "error", err)
+			releaseIdempotencyKey(c.Context(), log, repo, rec)
+		}
+		return nil
+	}
+}
+
+func replayIdempotentResponse(c fiber.Ctx, stored *entity.IdempotencyKey, fingerprint string) error {
+	if stored.Fingerprint != fingerprint {
+		return problem.Write(c, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used with a different request")
+	}
+	if stored.Status != entity.IdempotencyCompleted || stored.ResponseStatus == nil {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return problem.Write(c, http.StatusConflict, "IDEMPOTENCY_KEY_IN_PROGRESS", "a request with this Idempotency-Key is still being processed")
+	}
+
+	if stored.ContentType != nil && *stored.ContentType != "" {
+		c.Set(fiber.HeaderContentType, *stored.ContentType)
+	}
+	c.Set(IdempotentReplayedHeader, "true")
+	return c.Status(*stored.ResponseStatus).Send(stored.ResponseBody)
+}
+
+func releaseIdempotencyKey(ctx context.Context, log applog.AppLogger, repo port.IdempotencyRepo, rec *entity.IdempotencyKey) {
+	// The reservation must be dropped even when the request was cancelled.
+	if err := repo.ReleaseIdempotencyKey(context.WithoutCancel(ctx), rec.Scope, rec.Key); err != nil {
+		log.Error("idempotency key release failed", "key", rec.Key, "error", err)
+	}
+}
+
+// requestFingerprint hashes the method, path, query and body so a reused key can be told
+// apart from a genuine retry. Query arguments are sorted, so their order does not matter.
+func requestFingerprint(c fiber.Ctx) string {
+	var query []string
+	for k, v := range c.Request().URI().QueryArgs().All() {
+		query = append(query, url.QueryEscape(string(k))+"="+url.QueryEscape(string(v)))
+	}
+	slices.Sort(query)
+
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Path()))
+	h.Write([]byte{0})
+	// Without a query the hash is the one computed before queries were covered, so that
+	// keys stored by an older version still match their retries.
+	if len(query) > 0 {
+		h.Write([]byte(strings.Join(query, "&")))
+		h.Write([]byte{0})
+	}
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
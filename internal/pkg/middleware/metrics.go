@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zenrows-challenge/internal/pkg/metrics"
+	"zenrows-challenge/internal/pkg/problem"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// MetricsMiddleware records the count, latency and in-flight number of HTTP requests.
+// Requests are labelled by route template, e.g. /device-profiles/:id, so the label
+// cardinality does not grow with the IDs in the path.
+func MetricsMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+		start := time.Now()
+
+		err := c.Next()
+
+		labels := []string{c.Method(), c.Route().Path, strconv.Itoa(responseStatus(c, err))}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// responseStatus returns the status the request is answered with. When err is set, the
+// app's error handler renders it after the chain returns.
+func responseStatus(c fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// BearerTokenMiddleware requires "Authorization: Bearer <token>". It guards operational
+// endpoints such as /metrics, which are not tied to API users.
+func BearerTokenMiddleware(token string) fiber.Handler {
+	expected := []byte("Bearer " + token)
+	return func(c fiber.Ctx) error {
+		if subtle.ConstantTimeCompare([]byte(c.Get(fiber.HeaderAuthorization)), expected) != 1 {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return problem.Write(c, http.StatusUnauthorized, "NOT_AUTHORIZED", "unauthorized")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/openapi"
+	"zenrows-challenge/internal/pkg/problem"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequestValidationMiddleware rejects requests whose path, query or header parameters
+// or JSON body do not match their operation in doc, before they reach the handler. The
+// response is the INVALID_ARGUMENT error of handler validation, listing every violation.
+// Requests for operations missing from doc are let through.
+func RequestValidationMiddleware(doc *openapi.Document) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		err := doc.ValidateRequest(openapi.Request{
+			Method: c.Method(),
+			Path:   c.Path(),
+			Query: func(name string) (string, bool) {
+				v := c.Request().URI().QueryArgs().Peek(name)
+				return string(v), v != nil
+			},
+			Header:      func(name string) string { return c.Get(name) },
+			ContentType: c.Get(fiber.HeaderContentType),
+			Body:        c.Body(),
+		})
+		var inv *apperr.InvalidArgErr
+		if errors.As(err, &inv) {
+			return problem.Write(c, http.StatusBadRequest, inv.Code(), inv.Message(), inv.Details()...)
+		}
+		return c.Next()
+	}
+}
+
+// ResponseValidationMiddleware checks responses against doc and replaces the ones that
+// do not match with a 500, logging the mismatch, so handlers drifting from the spec are
+// caught in tests and development. It is meant to run first so it sees the responses of
+// every other middleware; errors returned down the chain are left to the error handler.
+// Responses to requests the client abandoned are not checked.
+func ResponseValidationMiddleware(log applog.AppLogger, doc *openapi.Document) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		resp := c.Response()
+		if c.Method() == fiber.MethodHead || resp.StatusCode() == problem.StatusClientClosedRequest {
+			return nil
+		}
+		err := doc.ValidateResponse(c.Method(), c.Path(), resp.StatusCode(), string(resp.Header.ContentType()), resp.Body())
+		if err == nil {
+			return nil
+		}
+		log.WithContext(c.Context()).Error("Response does not match the API specification",
+			"method", c.Method(), "path", c.Path(), "status", resp.StatusCode(), "error", err)
+		resp.ResetBody()
+		return problem.Write(c, http.StatusInternalServerError, "INTERNAL_ERROR", "response does not match the API specification")
+	}
+}
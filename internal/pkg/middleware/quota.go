@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/problem"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequestQuotaMiddleware counts every authenticated request against the user's
+// per-minute quota and rejects it with 429 once the quota is exhausted. It must
+// run after BasicAuthCheckMiddleware.
+func RequestQuotaMiddleware(svc port.QuotaService) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		p, ok := util.PrincipalFrom(c.Context())
+		if !ok {
+			return problem.Write(c, http.StatusUnauthorized, "NOT_AUTHORIZED", "unauthorized")
+		}
+
+		if err := svc.ConsumeRequest(c.Context(), p.UserID.String()); err != nil {
+			var qe *apperr.QuotaExceededErr
+			if errors.As(err, &qe) {
+				if d := qe.RetryAfter(); d > 0 {
+					c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(d)))
+				}
+				return problem.Write(c, http.StatusTooManyRequests, qe.Code(), qe.Message())
+			}
+			return problem.Write(c, http.StatusInternalServerError, "INTERNAL_ERROR", "internal error")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/problem"
+	"zenrows-challenge/internal/pkg/ratelimit"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RateLimitMiddleware applies the token-bucket limits of the policy returned by policy,
+// called on every request so that limits can change at runtime. Buckets are per
+// authenticated user when the limiter runs after authentication, and per client IP
+// otherwise.
+// It sets the RateLimit-* headers on every response and Retry-After when rejecting.
+// Store failures are logged and the request is let through.
+func RateLimitMiddleware(log applog.AppLogger, store ratelimit.Store, policy func() ratelimit.Policy) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		policy := policy()
+		limit, class := policy.ForMethod(c.Method())
+		if !limit.Enabled() {
+			return c.Next()
+		}
+
+		identity := "ip:" + c.IP()
+		if p, ok := util.PrincipalFrom(c.Context()); ok {
+			identity = "user:" + p.UserID.String()
+		}
+		key := policy.Name + ":" + class + ":" + identity
+
+		res, err := store.Take(c.Context(), key, limit)
+		if err != nil {
+			log.Warn("rate limit store unavailable, allowing request", "key", key, "error", err)
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+		if !res.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			return problem.Write(c, http.StatusTooManyRequests, "RATE_LIMITED", "too many requests")
+		}
+		return c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
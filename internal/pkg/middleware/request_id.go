@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength bounds client supplied request IDs, which end up in logs and audit events.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware propagates the X-Request-ID of the request, or assigns a new one
+// when it is missing or malformed, and echoes it in the response. It must run before
+// RequestContextMiddleware, which copies the ID into the request's RequestMeta.
+func RequestIDMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id := c.Get(util.RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+			c.Request().Header.Set(util.RequestIDHeader, id)
+		}
+		c.Set(util.RequestIDHeader, id)
+		return c.Next()
+	}
+}
+
+// AccessLogMiddleware logs one line per request once it has been answered. The request
+// ID and trace IDs are added by the logger from the request's context.
+func AccessLogMiddleware(log applog.AppLogger) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := responseStatus(c, err)
+		args := []any{
+			"method", c.Method(),
+			"route", c.Route().Path,
+			"path", c.Path(),
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"bytes", len(c.Response().Body()),
+			"ip", c.IP(),
+		}
+		if p, ok := util.PrincipalFrom(c.Context()); ok {
+			args = append(args, "user_id", p.UserID.String())
+		}
+		logger := log.WithContext(c.Context())
+		if status >= fiber.StatusInternalServerError {
+			logger.Error("http.request", args...)
+		} else {
+			logger.Info("http.request", args...)
+		}
+		return err
+	}
+}
+
+// validRequestID accepts non-empty printable ASCII IDs of bounded length.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
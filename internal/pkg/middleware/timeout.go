@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"zenrows-challenge/internal/pkg/metrics"
+	"zenrows-challenge/internal/pkg/problem"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequestTimeoutMiddleware bounds the request context by d, so pending database work is
+// cancelled once it expires. A request that failed or ended with a 5xx after its deadline
+// passed is answered with 504; responses completed in time are left untouched. It must
+// run right after RequestContextMiddleware. A non-positive d disables the limit.
+func RequestTimeoutMiddleware(d time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if d <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), d)
+		defer cancel()
+		c.SetContext(ctx)
+
+		err := c.Next()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+		if err == nil && c.Response().StatusCode() < http.StatusInternalServerError {
+			return nil
+		}
+		metrics.Timeouts.WithLabelValues(metrics.ScopeRequest).Inc()
+		return problem.Write(c, http.StatusGatewayTimeout, "TIMEOUT", "request timed out")
+	}
+}
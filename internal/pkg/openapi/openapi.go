@@ -0,0 +1,336 @@
+// Package openapi validates HTTP requests and responses against an OpenAPI 3.1
+// document.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"zenrows-challenge/internal/pkg/apperr"
+)
+
+const refPrefix = "#/components/"
+
+// Document is a parsed OpenAPI document.
+type Document struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+		Responses  map[string]*Response  `json:"responses"`
+	} `json:"components"`
+
+	routes []route
+}
+
+// Operation is one method of a path.
+type Operation struct {
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a query, header or path parameter.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// route is a path template split into segments; "{name}" segments match any value.
+type route struct {
+	template string
+	segments []string
+	params   int
+}
+
+// Load parses an OpenAPI document, checking that its references resolve and its
+// patterns compile.
+func Load(data []byte) (*Document, error) {
+	var d Document
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	var errs []error
+	check := func(where string, s *Schema) {
+		if err := d.compile(s, map[*Schema]bool{}); err != nil {
+			errs = append(errs, fmt.Errorf("openapi: %s: %w", where, err))
+		}
+	}
+	for name, s := range d.Components.Schemas {
+		check("schema "+name, s)
+	}
+	for template, ops := range d.Paths {
+		for method, op := range ops {
+			where := strings.ToUpper(method) + " " + template
+			for _, p := range op.Parameters {
+				if p = d.parameter(p); p == nil {
+					errs = append(errs, fmt.Errorf("openapi: %s: unresolved parameter", where))
+					continue
+				}
+				check(where+" parameter "+p.Name, p.Schema)
+			}
+			if op.RequestBody != nil {
+				for mt, c := range op.RequestBody.Content {
+					check(where+" request "+mt, c.Schema)
+				}
+			}
+			for status, r := range op.Responses {
+				if r = d.response(r); r == nil {
+					errs = append(errs, fmt.Errorf("openapi: %s: unresolved response %s", where, status))
+					continue
+				}
+				for mt, c := range r.Content {
+					check(where+" response "+status+" "+mt, c.Schema)
+				}
+			}
+		}
+		segments := strings.Split(strings.Trim(template, "/"), "/")
+		r := route{template: template, segments: segments}
+		for _, s := range segments {
+			if strings.HasPrefix(s, "{") {
+				r.params++
+			}
+		}
+		d.routes = append(d.routes, r)
+	}
+	// Literal segments win over parameters, e.g. /users/me over /users/{id}.
+	sort.Slice(d.routes, func(i, j int) bool { return d.routes[i].params < d.routes[j].params })
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &d, nil
+}
+
+// compile resolves the references of s and compiles its patterns.
+func (d *Document) compile(s *Schema, seen map[*Schema]bool) error {
+	if s == nil || seen[s] {
+		return nil
+	}
+	seen[s] = true
+	if s.Ref != "" {
+		t := d.resolve(s)
+		if t == nil {
+			return fmt.Errorf("unresolved reference %q", s.Ref)
+		}
+		return d.compile(t, seen)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	for _, p := range s.Properties {
+		if err := d.compile(p, seen); err != nil {
+			return err
+		}
+	}
+	if err := d.compile(s.AdditionalProperties, seen); err != nil {
+		return err
+	}
+	return d.compile(s.Items, seen)
+}
+
+func (d *Document) resolve(s *Schema) *Schema {
+	if s == nil || s.Ref == "" {
+		return s
+	}
+	return d.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix+"schemas/")]
+}
+
+func (d *Document) parameter(p *Parameter) *Parameter {
+	if p == nil || p.Ref == "" {
+		return p
+	}
+	return d.Components.Parameters[strings.TrimPrefix(p.Ref, refPrefix+"parameters/")]
+}
+
+func (d *Document) response(r *Response) *Response {
+	if r == nil || r.Ref == "" {
+		return r
+	}
+	return d.Components.Responses[strings.TrimPrefix(r.Ref, refPrefix+"responses/")]
+}
+
+// operation returns the operation serving method and path, with the values of the path
+// parameters, or nil when the document does not describe it.
+func (d *Document) operation(method, path string) (*Operation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range d.routes {
+		if len(r.segments) != len(segments) {
+			continue
+		}
+		params := make(map[string]string, r.params)
+		match := true
+		for i, s := range r.segments {
+			if strings.HasPrefix(s, "{") {
+				params[strings.Trim(s, "{}")] = segments[i]
+			} else if s != segments[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return d.Paths[r.template][strings.ToLower(method)], params
+		}
+	}
+	return nil, nil
+}
+
+// Request is the part of an HTTP request checked against the document.
+type Request struct {
+	Method string
+	Path   string
+	// Query returns the value of a query parameter and whether it was sent.
+	Query func(name string) (string, bool)
+	// Header returns the value of a request header, or "".
+	Header      func(name string) string
+	ContentType string
+	Body        []byte
+}
+
+// ValidateRequest checks the parameters and the JSON body of r against the operation
+// it targets. It returns an *apperr.InvalidArgErr listing every violation, or nil when
+// r is valid or its operation is not in the document.
+func (d *Document) ValidateRequest(r Request) error {
+	op, pathParams := d.operation(r.Method, r.Path)
+	if op == nil {
+		return nil
+	}
+
+	var out []apperr.FieldViolation
+	for _, p := range op.Parameters {
+		p = d.parameter(p)
+		var raw string
+		var ok bool
+		switch p.In {
+		case "path":
+			raw, ok = pathParams[p.Name]
+		case "query":
+			raw, ok = r.Query(p.Name)
+		case "header":
+			raw = r.Header(p.Name)
+			ok = raw != ""
+		}
+		if !ok {
+			if p.Required {
+				out = append(out, apperr.FieldViolation{Field: p.Name, Rule: "required", Message: "is required"})
+			}
+			continue
+		}
+		v, ok := d.parseParam(p.Schema, raw)
+		if !ok {
+			typ := d.resolve(p.Schema).Type
+			out = append(out, apperr.FieldViolation{Field: p.Name, Rule: "type", Param: typ, Message: "must be of type " + typ})
+			continue
+		}
+		out = d.validate(p.Schema, v, p.Name, out)
+	}
+
+	if rb := op.RequestBody; rb != nil {
+		mt, ok := rb.Content[mediaType(r.ContentType)]
+		switch {
+		case len(r.Body) == 0:
+			if rb.Required {
+				return apperr.NewInvalidArgErr("invalid request body", nil)
+			}
+		case ok && mt.Schema != nil:
+			var body any
+			if err := json.Unmarshal(r.Body, &body); err != nil {
+				return apperr.NewInvalidArgErr("invalid request body", err)
+			}
+			out = d.validate(mt.Schema, body, "", out)
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return apperr.NewInvalidArgErr("validation failed", nil).WithDetails(out...)
+}
+
+// ValidateResponse checks that the status of a response is documented for the
+// operation of method and path, and that its body matches the schema of its media type.
+// Responses of operations missing from the document are not checked, nor are the bodies
+// of 204 and 304 responses, which are never sent.
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	op, _ := d.operation(method, path)
+	if op == nil {
+		return nil
+	}
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		body = nil
+	}
+	resp := d.response(op.Responses[strconv.Itoa(status)])
+	if resp == nil {
+		if resp = d.response(op.Responses["default"]); resp == nil {
+			return fmt.Errorf("status %d is not documented", status)
+		}
+	}
+	if len(resp.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d is documented without a body", status)
+		}
+		return nil
+	}
+	mt, ok := resp.Content[mediaType(contentType)]
+	if !ok {
+		return fmt.Errorf("content type %q is not documented for status %d", contentType, status)
+	}
+	if mt.Schema == nil || !strings.HasSuffix(mediaType(contentType), "json") {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if out := d.validate(mt.Schema, v, "", nil); len(out) > 0 {
+		errs := make([]error, len(out))
+		for i, fv := range out {
+			errs[i] = fmt.Errorf("%s %s", fieldOrBody(fv.Field), fv.Message)
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mt
+}
+
+func fieldOrBody(field string) string {
+	if field == "" {
+		return "body"
+	}
+	return field
+}
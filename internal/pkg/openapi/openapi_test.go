@@ -0,0 +1,152 @@
+package openapi
+
+import (
+	"testing"
+
+	"zenrows-challenge/internal/pkg/apperr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDoc = `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/items": {
+      "get": {
+        "parameters": [{"$ref": "#/components/parameters/Page"}],
+        "responses": {"200": {"content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Item"}}}}}}
+      },
+      "post": {
+        "parameters": [{"name": "Idempotency-Key", "in": "header", "schema": {"type": "string", "maxLength": 4}}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+        "responses": {"201": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}}}
+      }
+    },
+    "/items/me": {"get": {"responses": {"204": {}}}},
+    "/items/{id}": {
+      "delete": {
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string", "format": "uuid"}}],
+        "responses": {"202": {}, "204": {}, "404": {"$ref": "#/components/responses/NotFound"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 5},
+          "kind": {"type": "string", "enum": ["a", "b"]},
+          "code": {"type": "string", "minLength": 2, "maxLength": 2, "pattern": "^[^a-z]*$"},
+          "size": {"type": "integer", "exclusiveMinimum": 0},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+        },
+        "required": ["name"]
+      },
+      "Error": {"type": "object", "properties": {"code": {"type": "string"}}, "required": ["code"]}
+    },
+    "parameters": {
+      "Page": {"name": "page", "in": "query", "schema": {"type": "integer", "minimum": 1}}
+    },
+    "responses": {
+      "NotFound": {"content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+    }
+  }
+}`
+
+func TestLoad(t *testing.T) {
+	_, err := Load([]byte(testDoc))
+	require.NoError(t, err)
+
+	_, err = Load([]byte(`{"components": {"schemas": {"A": {"$ref": "#/components/schemas/Missing"}}}}`))
+	assert.ErrorContains(t, err, "unresolved reference")
+
+	_, err = Load([]byte(`{"components": {"schemas": {"A": {"type": "string", "pattern": "("}}}}`))
+	assert.ErrorContains(t, err, "schema A")
+}
+
+func TestValidateRequest(t *testing.T) {
+	doc, err := Load([]byte(testDoc))
+	require.NoError(t, err)
+
+	request := func(method, path string, query map[string]string, body string) Request {
+		return Request{
+			Method: method,
+			Path:   path,
+			Query: func(name string) (string, bool) {
+				v, ok := query[name]
+				return v, ok
+			},
+			Header:      func(name string) string { return query["header:"+name] },
+			ContentType: "application/json; charset=utf-8",
+			Body:        []byte(body),
+		}
+	}
+
+	cases := []struct {
+		name       string
+		req        Request
+		message    string
+		violations []apperr.FieldViolation
+	}{
+		{name: "valid body", req: request("POST", "/items", nil, `{"name": "box", "kind": "a", "code": "US", "size": 3, "labels": {"x": "y"}, "extra": 1}`)},
+		{name: "valid query", req: request("GET", "/items", map[string]string{"page": "2"}, "")},
+		{name: "literal path wins over parameter", req: request("GET", "/items/me", nil, "")},
+		{name: "undocumented operation", req: request("PATCH", "/items", nil, "{")},
+		{name: "query below minimum", req: request("GET", "/items", map[string]string{"page": "0"}, ""),
+			message:    "validation failed",
+			violations: []apperr.FieldViolation{{Field: "page", Rule: "min", Param: "1", Message: "must be at least 1"}}},
+		{name: "query of the wrong type", req: request("GET", "/items", map[string]string{"page": "x"}, ""),
+			message:    "validation failed",
+			violations: []apperr.FieldViolation{{Field: "page", Rule: "type", Param: "integer", Message: "must be of type integer"}}},
+		{name: "invalid path parameter", req: request("DELETE", "/items/42", nil, ""),
+			message:    "validation failed",
+			violations: []apperr.FieldViolation{{Field: "id", Rule: "uuid", Message: "must be a valid UUID"}}},
+		{name: "header too long", req: request("POST", "/items", map[string]string{"header:Idempotency-Key": "12345"}, `{"name": "box"}`),
+			message:    "validation failed",
+			violations: []apperr.FieldViolation{{Field: "Idempotency-Key", Rule: "max", Param: "4", Message: "must be at most 4 characters long"}}},
+		{name: "missing body", req: request("POST", "/items", nil, ""), message: "invalid request body"},
+		{name: "malformed body", req: request("POST", "/items", nil, "{"), message: "invalid request body"},
+		{name: "every body violation", req: request("POST", "/items", nil, `{"kind": "c", "code": "u", "size": 0, "labels": {"x": 1}}`),
+			message: "validation failed",
+			violations: []apperr.FieldViolation{
+				{Field: "code", Rule: "len", Param: "2", Message: "must be exactly 2 characters long"},
+				{Field: "code", Rule: "pattern", Param: "^[^a-z]*$", Message: "must match ^[^a-z]*$"},
+				{Field: "kind", Rule: "oneof", Param: "a b", Message: "must be one of: a, b"},
+				{Field: "labels[x]", Rule: "type", Param: "string", Message: "must be of type string"},
+				{Field: "name", Rule: "required", Message: "is required"},
+				{Field: "size", Rule: "gt", Param: "0", Message: "must be greater than 0"},
+			}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := doc.ValidateRequest(tc.req)
+			if tc.message == "" {
+				require.NoError(t, err)
+				return
+			}
+			var inv *apperr.InvalidArgErr
+			require.ErrorAs(t, err, &inv)
+			assert.Equal(t, tc.message, inv.Message())
+			assert.Equal(t, tc.violations, inv.Details())
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc, err := Load([]byte(testDoc))
+	require.NoError(t, err)
+	const id = "/items/5b0e9b2c-8d0a-4c55-9f3c-3a4f6d6f9d10"
+
+	assert.NoError(t, doc.ValidateResponse("GET", "/items", 200, "application/json", []byte(`[{"name": "box"}]`)))
+	assert.NoError(t, doc.ValidateResponse("DELETE", id, 204, "", nil))
+	assert.NoError(t, doc.ValidateResponse("DELETE", id, 404, "application/problem+json", []byte(`{"code": "NOT_FOUND"}`)))
+	assert.NoError(t, doc.ValidateResponse("GET", "/unknown", 200, "text/plain", []byte("ok")))
+
+	assert.ErrorContains(t, doc.ValidateResponse("GET", "/items", 200, "application/json", []byte(`[{"size": 1}]`)), "[0].name is required")
+	assert.ErrorContains(t, doc.ValidateResponse("GET", "/items", 500, "application/json", nil), "status 500 is not documented")
+	assert.ErrorContains(t, doc.ValidateResponse("GET", "/items", 200, "text/plain", []byte("ok")), `content type "text/plain"`)
+	assert.ErrorContains(t, doc.ValidateResponse("DELETE", id, 202, "application/json", []byte("{}")), "without a body")
+	assert.NoError(t, doc.ValidateResponse("DELETE", id, 204, "text/plain", []byte("No Content")), "204 bodies are never sent")
+}
@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"zenrows-challenge/internal/pkg/apperr"
+
+	"github.com/google/uuid"
+)
+
+// Schema is the subset of JSON Schema the API document uses: type, format (uuid and
+// date-time), enum, pattern, string lengths, numeric bounds, object properties and
+// arrays. Other keywords are ignored.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+
+	pattern *regexp.Regexp
+}
+
+// validate appends to out the violations of v, a value decoded from JSON, reported at
+// field. Rules and messages follow the ones of the validation package.
+func (d *Document) validate(s *Schema, v any, field string, out []apperr.FieldViolation) []apperr.FieldViolation {
+	s = d.resolve(s)
+	if s == nil {
+		return out
+	}
+	if !hasType(s.Type, v) {
+		return append(out, apperr.FieldViolation{Field: field, Rule: "type", Param: s.Type, Message: "must be of type " + s.Type})
+	}
+	if len(s.Enum) > 0 && !contains(s.Enum, v) {
+		values := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			values[i] = fmt.Sprint(e)
+		}
+		out = append(out, apperr.FieldViolation{Field: field, Rule: "oneof", Param: strings.Join(values, " "),
+			Message: "must be one of: " + strings.Join(values, ", ")})
+	}
+
+	switch v := v.(type) {
+	case string:
+		out = d.validateString(s, v, field, out)
+	case float64:
+		out = validateNumber(s, v, field, out)
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				out = append(out, apperr.FieldViolation{Field: join(field, name), Rule: "required", Message: "is required"})
+			}
+		}
+		for name, e := range v {
+			if p, ok := s.Properties[name]; ok {
+				out = d.validate(p, e, join(field, name), out)
+			} else if s.AdditionalProperties != nil {
+				out = d.validate(s.AdditionalProperties, e, field+"["+name+"]", out)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, e := range v {
+				out = d.validate(s.Items, e, field+"["+strconv.Itoa(i)+"]", out)
+			}
+		}
+	}
+	return out
+}
+
+func (d *Document) validateString(s *Schema, v, field string, out []apperr.FieldViolation) []apperr.FieldViolation {
+	n := len([]rune(v))
+	switch {
+	case s.MinLength != nil && s.MaxLength != nil && *s.MinLength == *s.MaxLength && n != *s.MinLength:
+		out = append(out, apperr.FieldViolation{Field: field, Rule: "len", Param: strconv.Itoa(*s.MinLength),
+			Message: fmt.Sprintf("must be exactly %d characters long", *s.MinLength)})
+	case s.MinLength != nil && n < *s.MinLength:
+		out = append(out, apperr.FieldViolation{Field: field, Rule: "min", Param: strconv.Itoa(*s.MinLength),
+			Message: fmt.Sprintf("must be at least %d characters long", *s.MinLength)})
+	case s.MaxLength != nil && n > *s.MaxLength:
+		out = append(out, apperr.FieldViolation{Field: field, Rule: "max", Param: strconv.Itoa(*s.MaxLength),
+			Message: fmt.Sprintf("must be at most %d characters long", *s.MaxLength)})
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		out = append(out, apperr.FieldViolation{Field: field, Rule: "pattern", Param: s.Pattern,
+			Message: "must match " + s.Pattern})
+	}
+	switch s.Format {
+	case "uuid":
+		if _, err := uuid.Parse(v); err != nil {
+			out = append(out, apperr.FieldViolation{Field: field, Rule: "uuid", Message: "must be a valid UUID"})
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			out = append(out, apperr.FieldViolation{Field: field, Rule: "datetime", Message: "must be an RFC 3339 timestamp"})
+		}
+	}
+	return out
+}
+
+func validateNumber(s *Schema, v float64, field string, out []apperr.FieldViolation) []apperr.FieldViolation {
+	bound := func(rule, msg string, b float64) apperr.FieldViolation {
+		p := strconv.FormatFloat(b, 'f', -1, 64)
+		return apperr.FieldViolation{Field: field, Rule: rule, Param: p, Message: msg + " " + p}
+	}
+	if s.Minimum != nil && v < *s.Minimum {
+		out = append(out, bound("min", "must be at least", *s.Minimum))
+	}
+	if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+		out = append(out, bound("gt", "must be greater than", *s.ExclusiveMinimum))
+	}
+	if s.Maximum != nil && v > *s.Maximum {
+		out = append(out, bound("max", "must be at most", *s.Maximum))
+	}
+	if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+		out = append(out, bound("lt", "must be less than", *s.ExclusiveMaximum))
+	}
+	return out
+}
+
+// parseParam converts a query, header or path parameter to the JSON type of s, so it can
+// be validated like a body value. ok is false when raw is not of that type.
+func (d *Document) parseParam(s *Schema, raw string) (v any, ok bool) {
+	if s = d.resolve(s); s == nil {
+		return raw, true
+	}
+	switch s.Type {
+	case "integer", "number":
+		f, err := strconv.ParseFloat(raw, 64)
+		return f, err == nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		return b, err == nil
+	default:
+		return raw, true
+	}
+}
+
+func hasType(typ string, v any) bool {
+	switch typ {
+	case "":
+		return true
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+func contains(enum []any, v any) bool {
+	for _, e := range enum {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
@@ -0,0 +1,172 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	// ContentType is the media type of RFC 7807 problem details.
+	ContentType = "application/problem+json"
+
+	// FormatProblem renders errors as RFC 7807 problem details.
+	FormatProblem = "problem"
+	// FormatLegacy renders errors as the original {code, message, details} object.
+	FormatLegacy = "legacy"
+
+	// FormatHeader lets a client opt in to an error format, e.g. "X-Error-Format: legacy".
+	FormatHeader = "X-Error-Format"
+
+	defaultTypeBaseURI = "https://api.zenrows.com/problems/"
+
+	// StatusClientClosedRequest is the non-standard status recorded for requests the
+	// client abandoned before they completed. No body is sent, as nobody reads it.
+	StatusClientClosedRequest = 499
+)
+
+// Options are the server-wide error rendering settings.
+type Options struct {
+	// Format is the format used when the client asks for none, FormatProblem when empty.
+	Format string
+	// TypeBaseURI prefixes the problem types, https://api.zenrows.com/problems/ when empty.
+	TypeBaseURI string
+}
+
+var options atomic.Pointer[Options]
+
+func init() {
+	options.Store(&Options{})
+}
+
+// Configure sets the options used by Write from then on.
+func Configure(o Options) {
+	options.Store(&o)
+}
+
+// Details is an RFC 7807 problem details object. The application error code, the
+// request ID and field violations are carried as extension members.
+type Details struct {
+	Type       string                  `json:"type"`
+	Title      string                  `json:"title"`
+	Status     int                     `json:"status"`
+	Detail     string                  `json:"detail,omitempty"`
+	Instance   string                  `json:"instance,omitempty"`
+	Code       string                  `json:"code"`
+	RequestID  string                  `json:"request_id,omitempty"`
+	Violations []apperr.FieldViolation `json:"details,omitempty"`
+}
+
+// LegacyError is the error body used before problem details were introduced.
+type LegacyError struct {
+	Code    string                  `json:"code"`
+	Message string                  `json:"message"`
+	Details []apperr.FieldViolation `json:"details,omitempty"`
+}
+
+// Write renders an error response in the format negotiated with the client.
+func Write(c fiber.Ctx, status int, code, detail string, violations ...apperr.FieldViolation) error {
+	if negotiateFormat(c) == FormatLegacy {
+		return c.Status(status).JSON(LegacyError{Code: code, Message: detail, Details: violations})
+	}
+
+	return c.Status(status).JSON(Details{
+		Type:       typeURI(code),
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     detail,
+		Instance:   c.OriginalURL(),
+		Code:       code,
+		RequestID:  requestID(c),
+		Violations: violations,
+	}, ContentType)
+}
+
+// ErrorHandler is a fiber.ErrorHandler rendering errors that escape the handlers,
+// such as unknown routes, disallowed methods, body limits and recovered panics.
+func ErrorHandler(c fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		msg := fe.Message
+		if fe.Code >= http.StatusInternalServerError {
+			msg = "internal error"
+		}
+		return Write(c, fe.Code, CodeForStatus(fe.Code), strings.ToLower(msg))
+	}
+	return Write(c, http.StatusInternalServerError, "INTERNAL_ERROR", "internal error")
+}
+
+// CodeForStatus returns the application error code matching an HTTP status.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "NOT_AUTHORIZED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusMethodNotAllowed:
+		return "METHOD_NOT_ALLOWED"
+	case http.StatusConflict:
+		return "ALREADY_EXISTS"
+	case http.StatusRequestEntityTooLarge:
+		return "PAYLOAD_TOO_LARGE"
+	case http.StatusUnsupportedMediaType:
+		return "UNSUPPORTED_MEDIA_TYPE"
+	case http.StatusTooManyRequests:
+		return "RATE_LIMITED"
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "TIMEOUT"
+	case StatusClientClosedRequest:
+		return "CANCELED"
+	default:
+		return "INTERNAL_ERROR"
+	}
+}
+
+// negotiateFormat picks the error format. Problem details are the default; the legacy
+// shape is only used when the client opts in with the X-Error-Format header, or when the
+// configured Format is legacy and the client does not ask for application/problem+json.
+func negotiateFormat(c fiber.Ctx) string {
+	switch strings.ToLower(c.Get(FormatHeader)) {
+	case FormatLegacy:
+		return FormatLegacy
+	case FormatProblem:
+		return FormatProblem
+	}
+	if strings.Contains(strings.ToLower(c.Get(fiber.HeaderAccept)), ContentType) {
+		return FormatProblem
+	}
+	if strings.EqualFold(options.Load().Format, FormatLegacy) {
+		return FormatLegacy
+	}
+	return FormatProblem
+}
+
+// typeURI builds the problem type from the error code, e.g. NOT_FOUND becomes
+// <base>/not-found.
+func typeURI(code string) string {
+	base := options.Load().TypeBaseURI
+	if base == "" {
+		base = defaultTypeBaseURI
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return base + strings.ReplaceAll(strings.ToLower(code), "_", "-")
+}
+
+func requestID(c fiber.Ctx) string {
+	if id := c.GetRespHeader(util.RequestIDHeader); id != "" {
+		return id
+	}
+	return c.Get(util.RequestIDHeader)
+}
@@ -0,0 +1,126 @@
+package problem
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/apperr"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(recover.New())
+	app.Get("/invalid", func(c fiber.Ctx) error {
+		return Write(c, http.StatusBadRequest, "INVALID_ARGUMENT", "invalid page parameter",
+			apperr.FieldViolation{Field: "page", Rule: "min", Param: "1", Message: "must be at least 1"})
+	})
+	app.Get("/panic", func(c fiber.Ctx) error {
+		panic("boom")
+	})
+	return app
+}
+
+func do(t *testing.T, app *fiber.App, method, path, accept, format string) (*http.Response, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if accept != "" {
+		req.Header.Set(fiber.HeaderAccept, accept)
+	}
+	if format != "" {
+		req.Header.Set(FormatHeader, format)
+	}
+	req.Header.Set("X-Request-ID", "req-1")
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(body, &out))
+	return resp, out
+}
+
+func TestWrite_Negotiation(t *testing.T) {
+	app := newTestApp()
+
+	cases := []struct {
+		name        string
+		accept      string
+		format      string
+		contentType string
+		legacy      bool
+	}{
+		{name: "no accept header defaults to problem details", contentType: ContentType},
+		{name: "problem+json is honoured", accept: "application/problem+json, application/json", contentType: ContentType},
+		{name: "plain json defaults to problem details", accept: "application/json", contentType: ContentType},
+		{name: "legacy shape on opt-in", accept: "application/json", format: "legacy", contentType: fiber.MIMEApplicationJSON, legacy: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, out := do(t, app, http.MethodGet, "/invalid?page=0", tc.accept, tc.format)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Contains(t, resp.Header.Get(fiber.HeaderContentType), tc.contentType)
+			assert.Equal(t, "INVALID_ARGUMENT", out["code"])
+			require.Len(t, out["details"], 1)
+
+			if tc.legacy {
+				assert.Equal(t, "invalid page parameter", out["message"])
+				assert.NotContains(t, out, "type")
+				return
+			}
+			assert.Equal(t, defaultTypeBaseURI+"invalid-argument", out["type"])
+			assert.Equal(t, "Bad Request", out["title"])
+			assert.EqualValues(t, http.StatusBadRequest, out["status"])
+			assert.Equal(t, "invalid page parameter", out["detail"])
+			assert.Equal(t, "/invalid?page=0", out["instance"])
+			assert.Equal(t, "req-1", out["request_id"])
+		})
+	}
+}
+
+func TestWrite_Configured(t *testing.T) {
+	Configure(Options{Format: FormatLegacy, TypeBaseURI: "https://example.com/errors"})
+	t.Cleanup(func() { Configure(Options{}) })
+	app := newTestApp()
+
+	_, out := do(t, app, http.MethodGet, "/invalid?page=0", "application/json", "")
+	assert.Equal(t, "invalid page parameter", out["message"], "legacy is the configured default")
+
+	_, out = do(t, app, http.MethodGet, "/invalid?page=0", ContentType, "")
+	assert.Equal(t, "https://example.com/errors/invalid-argument", out["type"])
+}
+
+func TestErrorHandler(t *testing.T) {
+	app := newTestApp()
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{name: "unknown route", method: http.MethodGet, path: "/missing", status: http.StatusNotFound, code: "NOT_FOUND"},
+		{name: "method not allowed", method: http.MethodPost, path: "/invalid", status: http.StatusMethodNotAllowed, code: "METHOD_NOT_ALLOWED"},
+		{name: "recovered panic", method: http.MethodGet, path: "/panic", status: http.StatusInternalServerError, code: "INTERNAL_ERROR"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, out := do(t, app, tc.method, tc.path, "", "")
+			assert.Equal(t, tc.status, resp.StatusCode)
+			assert.Equal(t, ContentType, resp.Header.Get(fiber.HeaderContentType))
+			assert.Equal(t, tc.code, out["code"])
+			assert.EqualValues(t, tc.status, out["status"])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit configures a token bucket: Rate tokens are added every second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool { return l.Rate > 0 && l.Burst > 0 }
+
+// Bucket is the persisted state of a token bucket.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Result describes the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next token is available; zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key. Implementations must make Take atomic per key.
+type Store interface {
+	// Take refills the bucket for key according to limit and tries to consume one token.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewBucket returns a full bucket for limit.
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), UpdatedAt: now}
+}
+
+// Take refills b for the time elapsed since its last update and consumes a token when
+// one is available. It returns the updated bucket alongside the result.
+func Take(b Bucket, limit Limit, now time.Time) (Bucket, Result) {
+	burst := float64(limit.Burst)
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*limit.Rate)
+	}
+	b.UpdatedAt = now
+
+	res := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.Tokens) / limit.Rate)
+	}
+	res.Remaining = int(math.Floor(b.Tokens))
+	res.ResetAfter = secondsToDuration((burst - b.Tokens) / limit.Rate)
+	return b, res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Policy holds the limits of a route group, split between read and write requests.
+type Policy struct {
+	Name  string
+	Read  Limit
+	Write Limit
+}
+
+// ForMethod returns the limit applying to an HTTP method and the bucket class it belongs to.
+func (p Policy) ForMethod(method string) (Limit, string) {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return p.Read, "read"
+	default:
+		return p.Write, "write"
+	}
+}
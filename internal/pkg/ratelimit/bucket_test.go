@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTake_ConsumesAndRefills(t *testing.T) {
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBucket(limit, now)
+
+	b, res := Take(b, limit, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	b, res = Take(b, limit, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 2*time.Second, res.ResetAfter)
+
+	b, res = Take(b, limit, now)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	_, res = Take(b, limit, now.Add(1500*time.Millisecond))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestMemoryStore_SeparatesKeys(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 0.001, Burst: 1}
+	ctx := context.Background()
+
+	res, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	res, err = store.Take(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
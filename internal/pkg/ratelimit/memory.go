@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. It is suitable for single-instance
+// deployments; use a shared store when running several instances.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	bucket Bucket
+	limit  Limit
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), now: time.Now}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	mb, ok := s.buckets[key]
+	if !ok {
+		mb = &memoryBucket{bucket: NewBucket(limit, now)}
+		s.buckets[key] = mb
+	}
+	mb.limit = limit
+
+	var res Result
+	mb.bucket, res = Take(mb.bucket, limit, now)
+	return res, nil
+}
+
+// sweep drops buckets that would be full by now, since they are equivalent to new
+// ones. Callers must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, mb := range s.buckets {
+		missing := float64(mb.limit.Burst) - mb.bucket.Tokens
+		if now.Sub(mb.bucket.UpdatedAt).Seconds()*mb.limit.Rate >= missing {
+			delete(s.buckets, key)
+		}
+	}
+}
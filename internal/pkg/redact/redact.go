@@ -0,0 +1,142 @@
+package redact
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// Mask replaces redacted values.
+const Mask = "[REDACTED]"
+
+var (
+	// DefaultHeaders are the header names treated as sensitive when redaction.headers is unset.
+	DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	// DefaultLogKeys are the log attribute keys masked when redaction.log_keys is unset.
+	DefaultLogKeys = []string{"password", "secret", "token", "username"}
+	// DefaultPatterns match secrets embedded in free text when redaction.patterns is unset:
+	// bearer tokens and credentials in URLs.
+	DefaultPatterns = []string{`(?i)bearer\s+[a-z0-9._~+/-]+=*`, `[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`}
+)
+
+// Redactor masks sensitive values: those of sensitive header names and log keys, matched
+// case-insensitively, and any substring matching one of the patterns.
+type Redactor struct {
+	headers  map[string]struct{}
+	logKeys  map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// New builds a Redactor, failing when a pattern does not compile.
+func New(headers, logKeys, patterns []string) (*Redactor, error) {
+	r := &Redactor{headers: lowerSet(headers), logKeys: lowerSet(logKeys)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// IsSensitiveHeader reports whether the values of the header name are masked.
+func (r *Redactor) IsSensitiveHeader(name string) bool {
+	_, ok := r.headers[strings.ToLower(name)]
+	return ok
+}
+
+// String masks the substrings of s matching a pattern.
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Mask)
+	}
+	return s
+}
+
+// Headers returns a copy of h with the values of sensitive headers masked and the other
+// values passed through String.
+func (r *Redactor) Headers(h map[string]string) map[string]string {
+	if h == nil {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if r.IsSensitiveHeader(k) {
+			out[k] = Mask
+		} else {
+			out[k] = r.String(v)
+		}
+	}
+	return out
+}
+
+// Attr masks a log attribute: values of sensitive keys entirely, strings and errors by
+// pattern, and maps as header sets. It fits slog.HandlerOptions.ReplaceAttr.
+func (r *Redactor) Attr(_ []string, a slog.Attr) slog.Attr {
+	if _, ok := r.logKeys[strings.ToLower(a.Key)]; ok || r.IsSensitiveHeader(a.Key) {
+		return slog.String(a.Key, Mask)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.String(v.String()))
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, r.String(x.Error()))
+		case map[string]string:
+			return slog.Any(a.Key, r.Headers(x))
+		case map[string]any:
+			return slog.Any(a.Key, r.anyHeaders(x))
+		case datatypes.JSONMap:
+			return slog.Any(a.Key, r.anyHeaders(x))
+		}
+	}
+	return a
+}
+
+func (r *Redactor) anyHeaders(h map[string]any) map[string]any {
+	out := make(map[string]any, len(h))
+	for k, v := range h {
+		switch s, ok := v.(string); {
+		case r.IsSensitiveHeader(k):
+			out[k] = Mask
+		case ok:
+			out[k] = r.String(s)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Secret holds a value that must never be rendered, such as a password. It prints,
+// logs and marshals as Mask; Reveal returns the value.
+type Secret string
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string { return string(s) }
+
+// String implements fmt.Stringer.
+func (Secret) String() string { return Mask }
+
+// GoString implements fmt.GoStringer, covering %#v.
+func (Secret) GoString() string { return Mask }
+
+// LogValue implements slog.LogValuer.
+func (Secret) LogValue() slog.Value { return slog.StringValue(Mask) }
+
+// MarshalText implements encoding.TextMarshaler, covering JSON.
+func (Secret) MarshalText() ([]byte, error) { return []byte(Mask), nil }
+
+func lowerSet(items []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		set[strings.ToLower(strings.TrimSpace(item))] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/util"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies the spans created by this service.
+const InstrumentationName = "zenrows-challenge"
+
+// Attribute keys recorded on spans.
+const (
+	AttrUserID    = attribute.Key("enduser.id")
+	AttrProfileID = attribute.Key("device_profile.id")
+	AttrErrorCode = attribute.Key("error.code")
+)
+
+// Tracer returns the tracer of the globally installed provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start starts a span named name as a child of the span in ctx. The user ID of the
+// caller is recorded when ctx carries a principal.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if p, ok := util.PrincipalFrom(ctx); ok {
+		attrs = append(attrs, AttrUserID.String(p.UserID.String()))
+	}
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error errp points to, if any, and ends span. It is meant to be
+// deferred with the address of a named error result.
+func End(span trace.Span, errp *error) {
+	if errp != nil && *errp != nil {
+		RecordError(span, *errp)
+	}
+	span.End()
+}
+
+// RecordError marks span as failed by err, adding the application error code if err
+// is an apperr error.
+func RecordError(span trace.Span, err error) {
+	var be apperr.BaseError
+	if errors.As(err, &be) {
+		span.SetAttributes(AttrErrorCode.String(be.Code()))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// ProfileID returns the attribute identifying a device profile.
+func ProfileID(id string) attribute.KeyValue {
+	return AttrProfileID.String(id)
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// ctxKey defines a private type for context keys to avoid collisions.
+type ctxKey string
+
+// CtxPrincipalKey is the context key holding the Principal of the current request.
+const CtxPrincipalKey ctxKey = "principal"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID   uuid.UUID
+	Username string
+}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, CtxPrincipalKey, p)
+}
+
+// PrincipalFrom returns the Principal stored in ctx. ok is false for unauthenticated requests.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(CtxPrincipalKey).(Principal)
+	return p, ok && p.UserID != uuid.Nil
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequestIDHeader is the header carrying the request correlation ID.
+const RequestIDHeader = "X-Request-ID"
+
+// CtxRequestMetaKey is the context key holding the RequestMeta of the current request.
+const CtxRequestMetaKey ctxKey = "request_meta"
+
+// RequestMeta describes where a request came from, for auditing.
+type RequestMeta struct {
+	SourceIP  string
+	UserAgent string
+	RequestID string
+}
+
+// RequestMetaFromFiber extracts the RequestMeta of a Fiber request.
+func RequestMetaFromFiber(c fiber.Ctx) RequestMeta {
+	return RequestMeta{
+		SourceIP:  c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		RequestID: c.Get(RequestIDHeader),
+	}
+}
+
+// WithRequestMeta returns a copy of ctx carrying m.
+func WithRequestMeta(ctx context.Context, m RequestMeta) context.Context {
+	return context.WithValue(ctx, CtxRequestMetaKey, m)
+}
+
+// RequestMetaFrom returns the RequestMeta stored in ctx, if any.
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	m, _ := ctx.Value(CtxRequestMetaKey).(RequestMeta)
+	return m
+}
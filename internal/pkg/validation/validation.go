@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"zenrows-challenge/internal/pkg/apperr"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// New returns a validator that reports fields by their JSON names, so violations can
+// be mapped back to the request body.
+func New() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonFieldName)
+	return v
+}
+
+// Violations converts validator.ValidationErrors into field violations. It returns nil
+// when err does not come from the validator.
+func Violations(err error) []apperr.FieldViolation {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+	out := make([]apperr.FieldViolation, 0, len(verrs))
+	for _, fe := range verrs {
+		out = append(out, apperr.FieldViolation{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: message(fe),
+		})
+	}
+	return out
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	default:
+		return name
+	}
+}
+
+// fieldPath drops the root struct name from the namespace, e.g.
+// "DeviceProfileCreateRequest.custom_headers[X-Token]" becomes "custom_headers[X-Token]".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if _, rest, ok := strings.Cut(ns, "."); ok {
+		return rest
+	}
+	return fe.Field()
+}
+
+func message(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		if isString {
+			return fmt.Sprintf("must be exactly %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must have exactly %s items", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "uppercase":
+		return "must be uppercase"
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
+
+// VarViolations converts the result of validating a single variable, which carries no
+// field name, into violations of field.
+func VarViolations(field string, err error) []apperr.FieldViolation {
+	out := Violations(err)
+	for i := range out {
+		out[i].Field = field
+	}
+	return out
+}
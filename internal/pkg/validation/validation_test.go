@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sampleRequest struct {
+	Name        string            `json:"name" validate:"required,max=5"`
+	DeviceType  string            `json:"device_type" validate:"oneof=desktop mobile"`
+	CountryCode *string           `json:"country_code,omitempty" validate:"omitempty,len=2"`
+	Headers     map[string]string `json:"headers" validate:"dive,max=3"`
+}
+
+func TestViolations(t *testing.T) {
+	cc := "USA"
+	err := New().Struct(sampleRequest{
+		DeviceType:  "tablet",
+		CountryCode: &cc,
+		Headers:     map[string]string{"X-Token": "long"},
+	})
+	require.Error(t, err)
+
+	got := Violations(err)
+	require.Len(t, got, 4)
+
+	byField := map[string]string{}
+	for _, v := range got {
+		byField[v.Field] = v.Rule
+	}
+	assert.Equal(t, "required", byField["name"])
+	assert.Equal(t, "oneof", byField["device_type"])
+	assert.Equal(t, "len", byField["country_code"])
+	assert.Equal(t, "max", byField["headers[X-Token]"])
+	assert.Equal(t, "must be one of: desktop, mobile", got[1].Message)
+}
+
+func TestViolations_IgnoresOtherErrors(t *testing.T) {
+	assert.Nil(t, Violations(errors.New("boom")))
+}
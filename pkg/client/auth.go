@@ -0,0 +1,33 @@
+package client
+
+import "net/http"
+
+// Auth adds credentials to outgoing requests.
+type Auth interface {
+	Apply(req *http.Request)
+}
+
+// AuthFunc adapts a function to Auth, for schemes not provided by this package.
+type AuthFunc func(req *http.Request)
+
+// Apply calls f(req).
+func (f AuthFunc) Apply(req *http.Request) { f(req) }
+
+// BasicAuth authenticates with HTTP Basic credentials, the scheme the API accepts.
+func BasicAuth(username, password string) Auth {
+	return AuthFunc(func(req *http.Request) { req.SetBasicAuth(username, password) })
+}
+
+// BearerToken sends token in an Authorization: Bearer header, e.g. for gateways
+// issuing access tokens in front of the API.
+func BearerToken(token string) Auth {
+	return AuthFunc(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) })
+}
+
+// APIKey sends key in header, or in X-API-Key when header is empty.
+func APIKey(header, key string) Auth {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return AuthFunc(func(req *http.Request) { req.Header.Set(header, key) })
+}
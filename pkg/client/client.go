@@ -0,0 +1,196 @@
+// Package client is a typed Go client for the device profile API.
+//
+// A Client authenticates every request with its Auth, retries requests rejected with
+// 429 or a 5xx status, honouring Retry-After, and returns API errors as *Error:
+//
+//	c, err := client.New("https://api.example.com", client.WithAuth(client.BasicAuth("alice", "secret")))
+//	if err != nil { ... }
+//	p, err := c.GetProfile(ctx, id, nil)
+//	if errors.Is(err, client.ErrNotFound) { ... }
+//
+// The package depends on the standard library only.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUserAgent = "zenrows-go-client/1"
+
+	idempotencyKeyHeader = "Idempotency-Key"
+	requestIDHeader      = "X-Request-ID"
+)
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL   *url.URL
+	http      *http.Client
+	auth      Auth
+	retry     RetryPolicy
+	userAgent string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client requests are sent with, e.g. to configure TLS,
+// proxies or timeouts. It defaults to a client with a 30s timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithAuth sets how requests are authenticated.
+func WithAuth(a Auth) Option {
+	return func(c *Client) { c.auth = a }
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// WithUserAgent sets the User-Agent header of requests.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a Client for the API served at baseURL, e.g. https://api.example.com.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: base URL %q must be an absolute http or https URL", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:   u,
+		http:      &http.Client{Timeout: 30 * time.Second},
+		retry:     DefaultRetryPolicy,
+		userAgent: defaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// request describes one API call; it is rebuilt into a new *http.Request on every attempt.
+type request struct {
+	method string
+	// path is appended to the base URL as is, so its segments must already be escaped.
+	path   string
+	query  url.Values
+	body   any
+	header http.Header
+}
+
+// do sends r, retrying as the retry policy allows, and decodes a successful response
+// into out when it is not nil.
+func (c *Client) do(ctx context.Context, r request, out any) error {
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, r, body)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("client: read response: %w", err)
+		}
+
+		if resp.StatusCode < http.StatusBadRequest {
+			if out == nil || len(data) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("client: decode response: %w", err)
+			}
+			return nil
+		}
+
+		apiErr := decodeError(resp, data)
+		if attempt >= c.retry.MaxAttempts || !retryable(apiErr) {
+			return apiErr
+		}
+		if err := c.retry.wait(ctx, attempt, apiErr.RetryAfter); err != nil {
+			return apiErr
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, r request, body []byte) (*http.Response, error) {
+	u := *c.baseURL
+	u.RawPath = c.baseURL.EscapedPath() + r.path
+	path, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		return nil, fmt.Errorf("client: build request: %w", err)
+	}
+	u.Path = path
+	u.RawQuery = r.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: build request: %w", err)
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.auth != nil {
+		c.auth.Apply(req)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("client: %s %s: %w", r.method, r.path, err)
+	}
+	return resp, nil
+}
+
+// newIdempotencyKey returns a random key, so retries of one create are recognised by the
+// server while separate calls are not.
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Ptr returns a pointer to v, for the optional fields of requests.
+func Ptr[T any](v T) *T { return &v }
+
+// errIDRequired is returned without calling the API when a resource ID is empty.
+var errIDRequired = errors.New("client: id is required")
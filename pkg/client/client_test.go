@@ -0,0 +1,613 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	nethttp "net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	httpadapter "zenrows-challenge/internal/adapter/http"
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/openapi"
+	"zenrows-challenge/internal/pkg/problem"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/util"
+	"zenrows-challenge/pkg/client"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// fastRetries keeps retry tests quick while still exercising the backoff.
+var fastRetries = client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// testServer is the API as wired in main, with its services kept in memory, served on
+// an in-memory listener.
+type testServer struct {
+	url      string
+	http     *nethttp.Client
+	profiles *memoryProfiles
+	faults   *faults
+
+	mu       sync.Mutex
+	requests []string
+	headers  []nethttp.Header
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	log := applog.NewAppLoggerTo(io.Discard)
+	v := validator.New()
+	doc, err := openapi.Load(httpadapter.OpenAPISpec())
+	require.NoError(t, err)
+
+	redactor, err := redact.New([]string{"X-Token"}, nil, nil)
+	require.NoError(t, err)
+
+	s := &testServer{url: "http://api.test", profiles: &memoryProfiles{}, faults: &faults{}}
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(s.record, s.faults.inject, middleware.ResponseValidationMiddleware(log, doc),
+		middleware.RequestIDMiddleware(), middleware.RequestContextMiddleware(0))
+	next := func(c fiber.Ctx) error { return c.Next() }
+	httpadapter.RegisterRoutes(app, httpadapter.Routes{
+		PublicLimit:     next,
+		Authenticate:    []fiber.Handler{middleware.BasicAuthCheckMiddleware(aliceAuth{}, v)},
+		Protected:       []fiber.Handler{middleware.RequestValidationMiddleware(doc)},
+		Idempotency:     middleware.IdempotencyMiddleware(log, newMemoryIdempotencyRepo(), time.Hour, time.Minute),
+		Livez:           next,
+		Readyz:          next,
+		DeviceTemplates: httpadapter.NewDeviceTemplateHandlerImpl(log, memoryTemplates{}),
+		DeviceProfiles:  httpadapter.NewDeviceProfileHandlerImpl(log, s.profiles, v, redactor),
+		Users:           unusedHandlers{},
+		Audit:           unusedHandlers{},
+		OpenAPI:         httpadapter.NewOpenAPIHandlerImpl(),
+	})
+
+	ln := fasthttputil.NewInmemoryListener()
+	go func() { _ = app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	s.http = &nethttp.Client{
+		Timeout: 5 * time.Second,
+		Transport: &nethttp.Transport{
+			DialContext: func(context.Context, string, string) (net.Conn, error) { return ln.Dial() },
+		},
+	}
+	return s
+}
+
+// client returns a client of the server authenticated as alice.
+func (s *testServer) client(t *testing.T, opts ...client.Option) *client.Client {
+	t.Helper()
+	opts = append([]client.Option{
+		client.WithHTTPClient(s.http),
+		client.WithAuth(client.BasicAuth("alice", "secret")),
+		client.WithRetryPolicy(fastRetries),
+	}, opts...)
+	c, err := client.New(s.url, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func (s *testServer) record(c fiber.Ctx) error {
+	s.mu.Lock()
+	s.requests = append(s.requests, c.Method()+" "+c.Path())
+	h := nethttp.Header{}
+	for k, v := range c.GetReqHeaders() {
+		h[k] = v
+	}
+	s.headers = append(s.headers, h)
+	s.mu.Unlock()
+	return c.Next()
+}
+
+// sent returns the requests received so far, as "METHOD /path".
+func (s *testServer) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *testServer) lastHeader(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headers[len(s.headers)-1].Get(name)
+}
+
+// fault replaces the response to the next request matching method and path.
+type fault struct {
+	method, path string
+	status       int
+	retryAfter   string
+	// body is sent as is when set; otherwise the error is written as problem details.
+	body string
+	// lost lets the request be handled first, as when the response is lost on its way back.
+	lost bool
+}
+
+type faults struct {
+	mu     sync.Mutex
+	queued []fault
+}
+
+func (f *faults) add(fs ...fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queued = append(f.queued, fs...)
+}
+
+func (f *faults) inject(c fiber.Ctx) error {
+	f.mu.Lock()
+	i := slices.IndexFunc(f.queued, func(q fault) bool { return q.method == c.Method() && q.path == c.Path() })
+	var ft fault
+	if i >= 0 {
+		ft = f.queued[i]
+		f.queued = slices.Delete(f.queued, i, i+1)
+	}
+	f.mu.Unlock()
+	if i < 0 {
+		return c.Next()
+	}
+
+	if ft.lost {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		c.Response().ResetBody()
+	}
+	if ft.retryAfter != "" {
+		c.Set(fiber.HeaderRetryAfter, ft.retryAfter)
+	}
+	if ft.body != "" {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlain)
+		return c.Status(ft.status).SendString(ft.body)
+	}
+	return problem.Write(c, ft.status, problem.CodeForStatus(ft.status), strings.ToLower(nethttp.StatusText(ft.status)))
+}
+
+func TestProfiles(t *testing.T) {
+	s := newTestServer(t)
+	c := s.client(t)
+	ctx := t.Context()
+
+	created, err := c.CreateProfile(ctx, client.CreateProfileRequest{
+		Name:          "Pixel",
+		DeviceType:    client.DeviceTypeMobile,
+		Width:         client.Ptr(412),
+		CountryCode:   client.Ptr("RO"),
+		CustomHeaders: map[string]string{"X-Token": "s3cret", "Accept-Language": "ro"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Pixel", created.Name)
+	assert.Equal(t, aliceID, created.UserID)
+	assert.NotEmpty(t, s.lastHeader("Idempotency-Key"))
+	assert.NotEqual(t, "s3cret", created.CustomHeaders["X-Token"], "created profiles are masked too")
+	assert.Equal(t, "ro", created.CustomHeaders["Accept-Language"])
+
+	got, err := c.GetProfile(ctx, created.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, 412, *got.Width)
+	assert.NotEqual(t, "s3cret", got.CustomHeaders["X-Token"], "sensitive headers are masked by default")
+	got, err = c.GetProfile(ctx, created.ID, &client.GetOptions{Reveal: true})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", got.CustomHeaders["X-Token"])
+
+	updated, err := c.UpdateProfile(ctx, created.ID, client.UpdateProfileRequest{
+		Name:          client.Ptr("Pixel 9"),
+		CustomHeaders: map[string]string{"X-Token": "n3w"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Pixel 9", updated.Name)
+	require.Contains(t, updated.CustomHeaders, "X-Token")
+	assert.NotEqual(t, "n3w", updated.CustomHeaders["X-Token"])
+	got, err = c.GetProfile(ctx, created.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Pixel 9", got.Name)
+	assert.Equal(t, client.DeviceTypeMobile, got.DeviceType)
+
+	listed, err := c.ListProfiles(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, created.ID, listed[0].ID)
+
+	require.NoError(t, c.DeleteProfile(ctx, created.ID))
+	_, err = c.GetProfile(ctx, created.ID, nil)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.ErrorIs(t, c.DeleteProfile(ctx, created.ID), client.ErrNotFound)
+}
+
+func TestProfilesIterator(t *testing.T) {
+	s := newTestServer(t)
+	c := s.client(t)
+	ctx := t.Context()
+
+	var want []string
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		p, err := c.CreateProfile(ctx, client.CreateProfileRequest{Name: name, DeviceType: client.DeviceTypeDesktop})
+		require.NoError(t, err)
+		want = append([]string{p.ID}, want...)
+	}
+
+	t.Run("walks every page", func(t *testing.T) {
+		before := len(s.sent())
+		var got []string
+		for p, err := range c.Profiles(ctx, &client.ListOptions{PageSize: 2}) {
+			require.NoError(t, err)
+			got = append(got, p.ID)
+		}
+		assert.Equal(t, want, got)
+		assert.Len(t, s.sent()[before:], 3, "the third page is short, so no fourth is fetched")
+	})
+
+	t.Run("stops fetching when the loop breaks", func(t *testing.T) {
+		before := len(s.sent())
+		n := 0
+		for _, err := range c.Profiles(ctx, &client.ListOptions{PageSize: 2}) {
+			require.NoError(t, err)
+			if n++; n == 2 {
+				break
+			}
+		}
+		assert.Len(t, s.sent()[before:], 1)
+	})
+
+	t.Run("yields errors", func(t *testing.T) {
+		s.faults.add(fault{method: "GET", path: "/device-profiles", status: nethttp.StatusForbidden})
+		var errs []error
+		for _, err := range c.Profiles(ctx, nil) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], client.ErrPermissionDenied)
+	})
+}
+
+func TestListTemplates(t *testing.T) {
+	c := newTestServer(t).client(t)
+
+	templates, err := c.ListTemplates(t.Context())
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, "Desktop Chrome", templates[0].Name)
+	assert.Equal(t, "en", templates[0].DefaultHeaders["Accept-Language"])
+}
+
+func TestErrors(t *testing.T) {
+	s := newTestServer(t)
+	c := s.client(t, client.WithRetryPolicy(client.NoRetries))
+	ctx := t.Context()
+
+	t.Run("validation errors carry the field violations", func(t *testing.T) {
+		_, err := c.CreateProfile(ctx, client.CreateProfileRequest{Name: "", DeviceType: "tablet"})
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.ErrorIs(t, err, client.ErrInvalidArgument)
+		assert.Equal(t, nethttp.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "validation failed", apiErr.Message)
+		assert.NotEmpty(t, apiErr.RequestID)
+		assert.Equal(t, []client.FieldViolation{
+			{Field: "device_type", Rule: "oneof", Param: "desktop mobile", Message: "must be one of: desktop, mobile"},
+			{Field: "name", Rule: "min", Param: "1", Message: "must be at least 1 characters long"},
+		}, apiErr.Violations)
+	})
+
+	t.Run("invalid ids", func(t *testing.T) {
+		before := len(s.sent())
+		_, err := c.GetProfile(ctx, "42", nil)
+		assert.ErrorIs(t, err, client.ErrInvalidArgument)
+		_, err = c.GetProfile(ctx, "", nil)
+		assert.Error(t, err)
+		assert.Len(t, s.sent()[before:], 1, "empty ids are rejected without a request")
+	})
+
+	t.Run("ids are escaped in the path", func(t *testing.T) {
+		for _, id := range []string{"../device-templates", "a/b", "..", "50%"} {
+			before := len(s.sent())
+			_, err := c.GetProfile(ctx, id, nil)
+			assert.ErrorIs(t, err, client.ErrInvalidArgument, id)
+			sent := s.sent()[before:]
+			require.Len(t, sent, 1)
+			assert.Regexp(t, `^GET /device-profiles/[^/]+$`, sent[0], id)
+		}
+	})
+
+	t.Run("bad credentials", func(t *testing.T) {
+		other := s.client(t, client.WithAuth(client.BasicAuth("alice", "wrong")))
+		_, err := other.ListProfiles(ctx, nil)
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, client.CodeNotAuthorized, apiErr.Code)
+		assert.Equal(t, nethttp.StatusUnauthorized, apiErr.StatusCode)
+	})
+
+	t.Run("bodies without an error object get the code of their status", func(t *testing.T) {
+		s.faults.add(fault{method: "GET", path: "/device-templates", status: nethttp.StatusBadGateway, body: "<html>bad gateway</html>"})
+		_, err := c.ListTemplates(ctx)
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, client.CodeInternal, apiErr.Code)
+		assert.Equal(t, nethttp.StatusBadGateway, apiErr.StatusCode)
+	})
+
+	t.Run("legacy error bodies are decoded", func(t *testing.T) {
+		s.faults.add(fault{method: "GET", path: "/device-templates", status: nethttp.StatusConflict,
+			body: `{"code": "ALREADY_EXISTS", "message": "device profile already exists"}`})
+		_, err := c.ListTemplates(ctx)
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.ErrorIs(t, err, client.ErrAlreadyExists)
+		assert.Equal(t, "device profile already exists", apiErr.Message)
+	})
+}
+
+func TestRetries(t *testing.T) {
+	s := newTestServer(t)
+	c := s.client(t)
+	ctx := t.Context()
+
+	t.Run("retries 5xx until the request succeeds", func(t *testing.T) {
+		before := len(s.sent())
+		s.faults.add(
+			fault{method: "GET", path: "/device-templates", status: nethttp.StatusServiceUnavailable},
+			fault{method: "GET", path: "/device-templates", status: nethttp.StatusInternalServerError},
+		)
+		_, err := c.ListTemplates(ctx)
+		require.NoError(t, err)
+		assert.Len(t, s.sent()[before:], 3)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		before := len(s.sent())
+		for range 3 {
+			s.faults.add(fault{method: "GET", path: "/device-templates", status: nethttp.StatusGatewayTimeout})
+		}
+		_, err := c.ListTemplates(ctx)
+		assert.ErrorIs(t, err, client.ErrTimeout)
+		assert.Len(t, s.sent()[before:], 3)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		before := len(s.sent())
+		_, err := c.GetProfile(ctx, uuid.NewString(), nil)
+		assert.ErrorIs(t, err, client.ErrNotFound)
+		assert.Len(t, s.sent()[before:], 1)
+	})
+
+	t.Run("honours Retry-After", func(t *testing.T) {
+		s.faults.add(fault{method: "GET", path: "/device-templates", status: nethttp.StatusTooManyRequests, retryAfter: "1"})
+		start := time.Now()
+		_, err := c.ListTemplates(ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("does not wait past the context deadline", func(t *testing.T) {
+		s.faults.add(fault{method: "GET", path: "/device-templates", status: nethttp.StatusTooManyRequests, retryAfter: "30"})
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		start := time.Now()
+		_, err := c.ListTemplates(ctx)
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, client.CodeRateLimited, apiErr.Code)
+		assert.Equal(t, 30*time.Second, apiErr.RetryAfter)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("a retried create does not create twice", func(t *testing.T) {
+		before := len(s.sent())
+		s.faults.add(fault{method: "POST", path: "/device-profiles", status: nethttp.StatusBadGateway, lost: true})
+		p, err := c.CreateProfile(ctx, client.CreateProfileRequest{Name: "retried", DeviceType: client.DeviceTypeDesktop})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"POST /device-profiles", "POST /device-profiles"}, s.sent()[before:])
+
+		var ids []string
+		for p, err := range c.Profiles(ctx, nil) {
+			require.NoError(t, err)
+			if p.Name == "retried" {
+				ids = append(ids, p.ID)
+			}
+		}
+		assert.Equal(t, []string{p.ID}, ids)
+	})
+}
+
+func TestAuth(t *testing.T) {
+	s := newTestServer(t)
+	ctx := t.Context()
+
+	_, err := s.client(t, client.WithAuth(client.APIKey("", "k1"))).ListTemplates(ctx)
+	assert.ErrorIs(t, err, client.ErrNotAuthorized, "the API only accepts Basic credentials")
+	assert.Equal(t, "k1", s.lastHeader("X-API-Key"))
+
+	_, _ = s.client(t, client.WithAuth(client.APIKey("X-Gateway-Key", "k2"))).ListTemplates(ctx)
+	assert.Equal(t, "k2", s.lastHeader("X-Gateway-Key"))
+
+	_, _ = s.client(t, client.WithAuth(client.BearerToken("t1"))).ListTemplates(ctx)
+	assert.Equal(t, "Bearer t1", s.lastHeader("Authorization"))
+
+	_, err = s.client(t).ListTemplates(ctx)
+	assert.NoError(t, err)
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"", "api.example.com", "ftp://api.example.com", "http://"} {
+		_, err := client.New(u)
+		assert.Error(t, err, u)
+	}
+	_, err := client.New("https://api.example.com/v1/")
+	assert.NoError(t, err)
+}
+
+const aliceID = "6f1c2a7e-3b4d-4e8f-9a0b-1c2d3e4f5a6b"
+
+// aliceAuth knows alice, with password secret.
+type aliceAuth struct{}
+
+func (aliceAuth) CheckCredentials(_ context.Context, username string, password redact.Secret) (string, error) {
+	if username == "alice" && password == "secret" {
+		return aliceID, nil
+	}
+	return "", apperr.NewNotAuthorizedErr("invalid credentials", nil)
+}
+
+func (aliceAuth) CheckCertificateUser(context.Context, string) (string, error) {
+	return "", apperr.NewNotAuthorizedErr("unknown user", nil)
+}
+
+func (aliceAuth) RecordAuthFailure(context.Context, string, string) {}
+
+// memoryProfiles is a port.DeviceProfileService keeping the profiles in memory.
+type memoryProfiles struct {
+	mu    sync.Mutex
+	items []entity.DeviceProfile
+}
+
+func owner(ctx context.Context) uuid.UUID {
+	p, _ := util.PrincipalFrom(ctx)
+	return p.UserID
+}
+
+func (m *memoryProfiles) find(ctx context.Context, id string) int {
+	return slices.IndexFunc(m.items, func(dp entity.DeviceProfile) bool {
+		return dp.ID.String() == id && dp.UserID == owner(ctx)
+	})
+}
+
+func (m *memoryProfiles) ListDeviceProfilesByUserID(ctx context.Context, page, pageSize int) ([]entity.DeviceProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []entity.DeviceProfile
+	for _, dp := range slices.Backward(m.items) {
+		if dp.UserID == owner(ctx) {
+			out = append(out, dp)
+		}
+	}
+	start := min((page-1)*pageSize, len(out))
+	return out[start:min(start+pageSize, len(out))], nil
+}
+
+func (m *memoryProfiles) GetDeviceProfile(ctx context.Context, id string) (*entity.DeviceProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.find(ctx, id)
+	if i < 0 {
+		return nil, apperr.NewNotFoundErr("device profile not found", nil)
+	}
+	dp := m.items[i]
+	return &dp, nil
+}
+
+func (m *memoryProfiles) CreateDeviceProfile(_ context.Context, dp *entity.DeviceProfile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dp.ID = uuid.New()
+	dp.CreatedAt = time.Now().UTC()
+	dp.UpdatedAt = dp.CreatedAt
+	m.items = append(m.items, *dp)
+	return nil
+}
+
+func (m *memoryProfiles) UpdateDeviceProfile(ctx context.Context, dp *entity.DeviceProfile) (*entity.DeviceProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.find(ctx, dp.ID.String())
+	if i < 0 {
+		return nil, apperr.NewNotFoundErr("device profile not found", nil)
+	}
+	if dp.Name != "" {
+		m.items[i].Name = dp.Name
+	}
+	if dp.DeviceType != "" {
+		m.items[i].DeviceType = dp.DeviceType
+	}
+	m.items[i].UpdatedAt = time.Now().UTC()
+	return dp, nil
+}
+
+func (m *memoryProfiles) DeleteDeviceProfile(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.find(ctx, id)
+	if i < 0 {
+		return apperr.NewNotFoundErr("device profile not found", nil)
+	}
+	m.items = slices.Delete(m.items, i, i+1)
+	return nil
+}
+
+type memoryTemplates struct{}
+
+func (memoryTemplates) RetrieveDeviceTemplates(context.Context) ([]entity.DeviceTemplate, error) {
+	return []entity.DeviceTemplate{{
+		ID: uuid.New(), Name: "Desktop Chrome", DeviceType: "desktop", UserAgent: "Mozilla/5.0",
+		DefaultHeaders: map[string]any{"Accept-Language": "en"}, CreatedAt: time.Now().UTC(),
+	}}, nil
+}
+
+func (memoryTemplates) ImportDeviceTemplates(context.Context, []entity.DeviceTemplate) (int, int, error) {
+	return 0, 0, errors.New("not supported")
+}
+
+// memoryIdempotencyRepo mimics the Postgres reservation semantics in memory.
+type memoryIdempotencyRepo struct {
+	mu   sync.Mutex
+	keys map[string]entity.IdempotencyKey
+}
+
+func newMemoryIdempotencyRepo() *memoryIdempotencyRepo {
+	return &memoryIdempotencyRepo{keys: make(map[string]entity.IdempotencyKey)}
+}
+
+func (r *memoryIdempotencyRepo) ReserveIdempotencyKey(_ context.Context, k *entity.IdempotencyKey) (*entity.IdempotencyKey, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := k.Scope + "/" + k.Key
+	if existing, ok := r.keys[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, false, nil
+	}
+	stored := *k
+	stored.Status = entity.IdempotencyInProgress
+	r.keys[id] = stored
+	return &stored, true, nil
+}
+
+func (r *memoryIdempotencyRepo) CompleteIdempotencyKey(_ context.Context, k *entity.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *k
+	stored.Status = entity.IdempotencyCompleted
+	r.keys[k.Scope+"/"+k.Key] = stored
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) ReleaseIdempotencyKey(_ context.Context, scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, scope+"/"+key)
+	return nil
+}
+
+// unusedHandlers serves the routes the client does not call.
+type unusedHandlers struct{}
+
+func (unusedHandlers) GetUsage(c fiber.Ctx) error { return c.SendStatus(nethttp.StatusNotImplemented) }
+func (unusedHandlers) ListAuditEvents(c fiber.Ctx) error {
+	return c.SendStatus(nethttp.StatusNotImplemented)
+}
+func (unusedHandlers) ListMyAuditEvents(c fiber.Ctx) error {
+	return c.SendStatus(nethttp.StatusNotImplemented)
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Code is an application error code, as found in the code member of error responses.
+type Code string
+
+// Error codes returned by the API.
+const (
+	CodeInvalidArgument          Code = "INVALID_ARGUMENT"
+	CodeNotFound                 Code = "NOT_FOUND"
+	CodeAlreadyExists            Code = "ALREADY_EXISTS"
+	CodeNotAuthorized            Code = "NOT_AUTHORIZED"
+	CodePermissionDenied         Code = "PERMISSION_DENIED"
+	CodeQuotaExceeded            Code = "QUOTA_EXCEEDED"
+	CodeRateLimited              Code = "RATE_LIMITED"
+	CodeTimeout                  Code = "TIMEOUT"
+	CodeInternal                 Code = "INTERNAL_ERROR"
+	CodeMethodNotAllowed         Code = "METHOD_NOT_ALLOWED"
+	CodePayloadTooLarge          Code = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType     Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeIdempotencyKeyReused     Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInProgress Code = "IDEMPOTENCY_KEY_IN_PROGRESS"
+)
+
+// Sentinel errors to match an *Error by code with errors.Is.
+var (
+	ErrInvalidArgument  = &Error{Code: CodeInvalidArgument}
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrAlreadyExists    = &Error{Code: CodeAlreadyExists}
+	ErrNotAuthorized    = &Error{Code: CodeNotAuthorized}
+	ErrPermissionDenied = &Error{Code: CodePermissionDenied}
+	ErrQuotaExceeded    = &Error{Code: CodeQuotaExceeded}
+	ErrRateLimited      = &Error{Code: CodeRateLimited}
+	ErrTimeout          = &Error{Code: CodeTimeout}
+	ErrInternal         = &Error{Code: CodeInternal}
+)
+
+// FieldViolation describes why a single request field was rejected.
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	Code       Code
+	Message    string
+	Violations []FieldViolation
+	RequestID  string
+	// RetryAfter is the delay asked for by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("client: %s (%d)", e.Code, e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	for _, v := range e.Violations {
+		msg += fmt.Sprintf("; %s %s", v.Field, v.Message)
+	}
+	return msg
+}
+
+// Is reports whether target is an *Error with the same code, so that
+// errors.Is(err, ErrNotFound) holds for every NOT_FOUND response.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// decodeError reads an error response, either RFC 7807 problem details or the legacy
+// {code, message, details} object. Bodies of neither shape, e.g. from a proxy, get the
+// code matching the status.
+func decodeError(resp *http.Response, data []byte) *Error {
+	var body struct {
+		Code      Code             `json:"code"`
+		Message   string           `json:"message"`
+		Detail    string           `json:"detail"`
+		Title     string           `json:"title"`
+		RequestID string           `json:"request_id"`
+		Details   []FieldViolation `json:"details"`
+	}
+	_ = json.Unmarshal(data, &body)
+
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Code:       body.Code,
+		Message:    body.Message,
+		Violations: body.Details,
+		RequestID:  body.RequestID,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	if e.Code == "" {
+		e.Code = codeForStatus(resp.StatusCode)
+	}
+	if e.Message == "" {
+		e.Message = body.Detail
+	}
+	if e.Message == "" {
+		e.Message = body.Title
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get(requestIDHeader)
+	}
+	return e
+}
+
+// parseRetryAfter reads a Retry-After value given in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// codeForStatus mirrors the status to code mapping of the server.
+func codeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return CodeNotAuthorized
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeAlreadyExists
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeTimeout
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize is the page size used when ListOptions.PageSize is not set.
+	DefaultPageSize = 20
+	// MaxPageSize is the largest page the API returns.
+	MaxPageSize = 100
+)
+
+// Device types of profiles and templates.
+const (
+	DeviceTypeDesktop = "desktop"
+	DeviceTypeMobile  = "mobile"
+)
+
+// DeviceProfile is a device profile of the authenticated user.
+type DeviceProfile struct {
+	ID            string            `json:"id"`
+	UserID        string            `json:"user_id"`
+	TemplateID    *string           `json:"template_id,omitempty"`
+	Name          string            `json:"name"`
+	DeviceType    string            `json:"device_type"`
+	Width         *int              `json:"width,omitempty"`
+	Height        *int              `json:"height,omitempty"`
+	UserAgent     *string           `json:"user_agent,omitempty"`
+	CountryCode   *string           `json:"country_code,omitempty"`
+	CustomHeaders map[string]string `json:"custom_headers,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// CreateProfileRequest describes a new profile. With a TemplateID, the profile is
+// created from the template and the other fields are ignored.
+type CreateProfileRequest struct {
+	TemplateID    *string           `json:"template_id,omitempty"`
+	Name          string            `json:"name"`
+	DeviceType    string            `json:"device_type"`
+	Width         *int              `json:"width,omitempty"`
+	Height        *int              `json:"height,omitempty"`
+	UserAgent     *string           `json:"user_agent,omitempty"`
+	CountryCode   *string           `json:"country_code,omitempty"`
+	CustomHeaders map[string]string `json:"custom_headers,omitempty"`
+}
+
+// UpdateProfileRequest holds the fields to change; nil fields are left as they are.
+type UpdateProfileRequest struct {
+	TemplateID    *string           `json:"template_id,omitempty"`
+	Name          *string           `json:"name,omitempty"`
+	DeviceType    *string           `json:"device_type,omitempty"`
+	Width         *int              `json:"width,omitempty"`
+	Height        *int              `json:"height,omitempty"`
+	UserAgent     *string           `json:"user_agent,omitempty"`
+	CountryCode   *string           `json:"country_code,omitempty"`
+	CustomHeaders map[string]string `json:"custom_headers,omitempty"`
+}
+
+// ListOptions selects a page of profiles.
+type ListOptions struct {
+	// Page is the 1-based page number; 0 means the first page.
+	Page int
+	// PageSize defaults to DefaultPageSize and is capped at MaxPageSize.
+	PageSize int
+	// Reveal asks for sensitive custom header values unmasked.
+	Reveal bool
+}
+
+// GetOptions tunes GetProfile.
+type GetOptions struct {
+	// Reveal asks for sensitive custom header values unmasked.
+	Reveal bool
+}
+
+// ListProfiles returns one page of the profiles of the authenticated user, newest first.
+func (c *Client) ListProfiles(ctx context.Context, opts *ListOptions) ([]DeviceProfile, error) {
+	var o ListOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.normalize()
+	q := url.Values{}
+	q.Set("page", strconv.Itoa(o.Page))
+	q.Set("page_size", strconv.Itoa(o.PageSize))
+	if o.Reveal {
+		q.Set("reveal", "true")
+	}
+
+	var out []DeviceProfile
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/device-profiles", query: q}, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Profiles iterates over the profiles of the authenticated user, fetching pages of
+// opts.PageSize from opts.Page on as the loop advances. An error ends the iteration
+// after being yielded.
+//
+//	for p, err := range c.Profiles(ctx, nil) {
+//		if err != nil { return err }
+//		...
+//	}
+func (c *Client) Profiles(ctx context.Context, opts *ListOptions) iter.Seq2[DeviceProfile, error] {
+	return func(yield func(DeviceProfile, error) bool) {
+		var o ListOptions
+		if opts != nil {
+			o = *opts
+		}
+		o.normalize()
+		for {
+			page, err := c.ListProfiles(ctx, &o)
+			if err != nil {
+				yield(DeviceProfile{}, err)
+				return
+			}
+			for _, p := range page {
+				if !yield(p, nil) {
+					return
+				}
+			}
+			if len(page) < o.PageSize {
+				return
+			}
+			o.Page++
+		}
+	}
+}
+
+// GetProfile returns the profile with the given ID. opts may be nil.
+func (c *Client) GetProfile(ctx context.Context, id string, opts *GetOptions) (*DeviceProfile, error) {
+	if id == "" {
+		return nil, errIDRequired
+	}
+	q := url.Values{}
+	if opts != nil && opts.Reveal {
+		q.Set("reveal", "true")
+	}
+	var out DeviceProfile
+	if err := c.do(ctx, request{method: http.MethodGet, path: profilePath(id), query: q}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateProfile creates a profile. The request carries a generated Idempotency-Key, so
+// a retry after a lost response returns the profile created by the first attempt
+// instead of creating another one. Sensitive custom header values are masked in the
+// returned profile; GetProfile with Reveal returns them.
+func (c *Client) CreateProfile(ctx context.Context, req CreateProfileRequest) (*DeviceProfile, error) {
+	h := http.Header{}
+	h.Set(idempotencyKeyHeader, newIdempotencyKey())
+	var out DeviceProfile
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/device-profiles", body: req, header: h}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProfile changes the non-nil fields of req on the profile with the given ID. The
+// API echoes the update, with sensitive custom header values masked, so fields missing
+// from req are zero in the returned profile; use GetProfile for the stored one.
+func (c *Client) UpdateProfile(ctx context.Context, id string, req UpdateProfileRequest) (*DeviceProfile, error) {
+	if id == "" {
+		return nil, errIDRequired
+	}
+	var out DeviceProfile
+	if err := c.do(ctx, request{method: http.MethodPut, path: profilePath(id), body: req}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteProfile deletes the profile with the given ID.
+func (c *Client) DeleteProfile(ctx context.Context, id string) error {
+	if id == "" {
+		return errIDRequired
+	}
+	return c.do(ctx, request{method: http.MethodDelete, path: profilePath(id)}, nil)
+}
+
+func (o *ListOptions) normalize() {
+	if o.Page < 1 {
+		o.Page = 1
+	}
+	if o.PageSize < 1 {
+		o.PageSize = DefaultPageSize
+	}
+	if o.PageSize > MaxPageSize {
+		o.PageSize = MaxPageSize
+	}
+}
+
+// profilePath returns the path of the profile with the given ID, escaped so that no ID
+// can address another route. Dot segments are escaped too, as proxies may resolve them.
+func profilePath(id string) string {
+	seg := url.PathEscape(id)
+	if id == "." || id == ".." {
+		seg = strings.ReplaceAll(id, ".", "%2E")
+	}
+	return "/device-profiles/" + seg
+}
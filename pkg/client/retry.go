@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides how often and how long apart failed requests are retried.
+// Requests rejected with 429, a 5xx status other than 501, or because a retry of the
+// same create is still in progress are retried; a Retry-After header takes precedence
+// over the backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts; 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles on each attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, but not Retry-After.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy makes up to 4 attempts, backing off from 200ms up to 5s.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// NoRetries disables retries.
+var NoRetries = RetryPolicy{MaxAttempts: 1}
+
+func retryable(e *Error) bool {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return true
+	case e.StatusCode == http.StatusConflict:
+		return e.Code == CodeIdempotencyKeyInProgress
+	case e.StatusCode >= http.StatusInternalServerError:
+		return e.StatusCode != http.StatusNotImplemented
+	}
+	return false
+}
+
+// delay returns how long to wait after the given failed attempt, counted from 1: the
+// server supplied retryAfter, or an exponential backoff with full jitter.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+// wait sleeps before the next attempt. It returns early with an error when ctx is done,
+// or right away when ctx would expire before the wait is over.
+func (p RetryPolicy) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	d := p.delay(attempt, retryAfter)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DeviceTemplate is a predefined device a profile can be created from.
+type DeviceTemplate struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	DeviceType     string            `json:"device_type"`
+	Width          *int              `json:"width,omitempty"`
+	Height         *int              `json:"height,omitempty"`
+	UserAgent      string            `json:"user_agent"`
+	CountryCode    *string           `json:"country_code,omitempty"`
+	DefaultHeaders map[string]string `json:"default_headers,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// ListTemplates returns every device template. Templates are managed by operators, so
+// the API only lists them.
+func (c *Client) ListTemplates(ctx context.Context) ([]DeviceTemplate, error) {
+	var out []DeviceTemplate
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/device-templates"}, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
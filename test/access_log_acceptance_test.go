@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	msg       string
+	args      []any
+	requestID string
+}
+
+// recordingLogger keeps the Info records it receives, with the request ID of their context.
+type recordingLogger struct {
+	noopLogger
+	ctx     context.Context
+	mu      *sync.Mutex
+	entries *[]logEntry
+}
+
+func newRecordingLogger() recordingLogger {
+	return recordingLogger{ctx: context.Background(), mu: &sync.Mutex{}, entries: &[]logEntry{}}
+}
+
+func (l recordingLogger) Info(msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.entries = append(*l.entries, logEntry{msg: msg, args: args, requestID: util.RequestMetaFrom(l.ctx).RequestID})
+}
+
+func (l recordingLogger) WithContext(ctx context.Context) applog.AppLogger {
+	l.ctx = ctx
+	return l
+}
+
+func TestAccessLog(t *testing.T) {
+	cases := []struct {
+		name      string
+		requestID string
+		keep      bool
+	}{
+		{name: "client request ID is propagated", requestID: "client-id-1", keep: true},
+		{name: "missing request ID is assigned"},
+		{name: "malformed request ID is replaced", requestID: "bad id\twith spaces"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			log := newRecordingLogger()
+			app := fiber.New()
+			app.Use(middleware.RequestIDMiddleware(), middleware.RequestContextMiddleware(0), middleware.AccessLogMiddleware(log))
+			app.Get("/device-profiles/:id", func(c fiber.Ctx) error { return c.SendString("ok") })
+
+			req := httptest.NewRequest(nethttp.MethodGet, "/device-profiles/42", nil)
+			if tc.requestID != "" {
+				req.Header.Set(util.RequestIDHeader, tc.requestID)
+			}
+			resp, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+			require.NoError(t, err)
+
+			id := resp.Header.Get(util.RequestIDHeader)
+			require.NotEmpty(t, id)
+			if tc.keep {
+				assert.Equal(t, tc.requestID, id)
+			} else {
+				assert.NotEqual(t, tc.requestID, id)
+			}
+
+			require.Len(t, *log.entries, 1)
+			entry := (*log.entries)[0]
+			assert.Equal(t, "http.request", entry.msg)
+			assert.Equal(t, id, entry.requestID)
+			assert.Subset(t, entry.args, []any{"route", "/device-profiles/:id", "status", nethttp.StatusOK, "bytes", 2})
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package test
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/middleware"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	var allowed atomic.Value
+	allowed.Store("https://app.example.com")
+
+	app := fiber.New()
+	app.Use(middleware.CORSMiddleware(func(origin string) bool { return origin == allowed.Load() }, false, 10*time.Minute))
+	app.Get("/device-profiles", func(c fiber.Ctx) error { return c.SendStatus(nethttp.StatusOK) })
+
+	preflight := func(origin string) *nethttp.Response {
+		req := httptest.NewRequest(nethttp.MethodOptions, "/device-profiles", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", nethttp.MethodGet)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := preflight("https://app.example.com")
+	assert.Equal(t, nethttp.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+
+	resp = preflight("https://evil.example.com")
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// The origins are consulted on every request, so a reload takes effect immediately.
+	allowed.Store("https://evil.example.com")
+	resp = preflight("https://evil.example.com")
+	assert.Equal(t, "https://evil.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+}
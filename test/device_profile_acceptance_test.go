@@ -19,7 +19,8 @@ import (
 	"zenrows-challenge/internal/core/port"
 	"zenrows-challenge/internal/core/usecase"
 	"zenrows-challenge/internal/pkg/apperr"
-	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/util"
 	testutil "zenrows-challenge/test/util"
 
 	"github.com/go-playground/validator/v10"
@@ -28,6 +29,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -40,6 +42,17 @@ func (noopLogger) Debug(string, ...any) {}
 func (noopLogger) Trace(string, ...any) {}
 func (noopLogger) Fatal(string, ...any) {}
 
+func (noopLogger) Infof(string, ...any)  {}
+func (noopLogger) Warnf(string, ...any)  {}
+func (noopLogger) Errorf(string, ...any) {}
+func (noopLogger) Debugf(string, ...any) {}
+func (noopLogger) Tracef(string, ...any) {}
+func (noopLogger) Fatalf(string, ...any) {}
+
+func (l noopLogger) With(...any) applog.AppLogger { return l }
+
+func (l noopLogger) WithContext(context.Context) applog.AppLogger { return l }
+
 var basicAuthHeader = "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
 
 type acceptanceSuite struct {
@@ -116,8 +129,8 @@ func newDeviceProfileSuite(t *testing.T, withAuth bool, svc port.DeviceProfileSe
 		dbConn, err = testutil.NewTestDB()
 		require.NoError(t, err)
 
-		repository = repo.NewDeviceProfileRepoImpl(logger, dbConn)
-		svc = usecase.NewDeviceProfileServiceImpl(logger, repository, templateRepoStub{}, v)
+		repository = repo.NewDeviceProfileRepoImpl(logger, dbConn, nil)
+		svc = usecase.NewDeviceProfileServiceImpl(logger, repository, templateRepoStub{}, quotaStub{}, repo.NewUnitOfWorkImpl(logger, dbConn, nil), v)
 
 		username := "accept_user_" + uuid.NewString()
 		pw, err := bcrypt.GenerateFromPassword([]byte("pass1234"), bcrypt.DefaultCost)
@@ -127,11 +140,10 @@ func newDeviceProfileSuite(t *testing.T, withAuth bool, svc port.DeviceProfileSe
 		userID = user.ID
 	}
 
-	handler := httpadapter.NewDeviceProfileHandlerImpl(logger, svc, v)
+	handler := httpadapter.NewDeviceProfileHandlerImpl(logger, svc, v, nil)
 
 	app := fiber.New()
 	if withAuth {
-		uid := userID.String()
 		app.Use(func(c fiber.Ctx) error {
 			if c.Get("Authorization") != basicAuthHeader {
 				return c.Status(nethttp.StatusUnauthorized).JSON(map[string]string{
@@ -139,7 +151,7 @@ func newDeviceProfileSuite(t *testing.T, withAuth bool, svc port.DeviceProfileSe
 					"message": "unauthorized",
 				})
 			}
-			c.Locals(middleware.AuthUserIDKey, uid)
+			c.SetContext(util.WithPrincipal(c.Context(), util.Principal{UserID: userID}))
 			return c.Next()
 		})
 	}
@@ -179,14 +191,32 @@ func newDeviceProfileSuite(t *testing.T, withAuth bool, svc port.DeviceProfileSe
 
 type templateRepoStub struct{}
 
-func (templateRepoStub) GetDeviceTemplates() ([]entity.DeviceTemplate, error) {
+func (templateRepoStub) GetDeviceTemplates(context.Context) ([]entity.DeviceTemplate, error) {
 	return nil, gorm.ErrRecordNotFound
 }
 
-func (templateRepoStub) GetDeviceTemplateByID(id *uuid.UUID) (*entity.DeviceTemplate, error) {
+func (templateRepoStub) GetDeviceTemplateByID(_ context.Context, id *uuid.UUID) (*entity.DeviceTemplate, error) {
 	return nil, gorm.ErrRecordNotFound
 }
 
+func (templateRepoStub) UpsertDeviceTemplates(context.Context, []entity.DeviceTemplate) (int, int, error) {
+	return 0, 0, gorm.ErrInvalidTransaction
+}
+
+type quotaStub struct{}
+
+func (quotaStub) ConsumeRequest(context.Context, string) error { return nil }
+
+func (quotaStub) CheckDeviceProfileCreate(context.Context, port.TxRepos, string, *entity.DeviceProfile) error {
+	return nil
+}
+
+func (quotaStub) CheckCustomHeaders(context.Context, string, datatypes.JSONMap) error { return nil }
+
+func (quotaStub) GetUsage(context.Context) (*entity.QuotaUsage, error) {
+	return &entity.QuotaUsage{}, nil
+}
+
 func waitForServer(t *testing.T, client *nethttp.Client, url string) {
 	t.Helper()
 	deadline := time.Now().Add(5 * time.Second)
@@ -222,7 +252,7 @@ func TestListDeviceProfilesByUserID(t *testing.T) {
 						Name:       fmt.Sprintf("profile_%d", i),
 						DeviceType: "desktop",
 					}
-					require.NoError(t, suite.repo.CreateDeviceProfile(&dp))
+					require.NoError(t, suite.repo.CreateDeviceProfile(t.Context(), &dp))
 					time.Sleep(5 * time.Millisecond)
 				}
 			},
@@ -247,7 +277,7 @@ func TestListDeviceProfilesByUserID(t *testing.T) {
 						Name:       fmt.Sprintf("page_profile_%d", i),
 						DeviceType: "desktop",
 					}
-					require.NoError(t, suite.repo.CreateDeviceProfile(&dp))
+					require.NoError(t, suite.repo.CreateDeviceProfile(t.Context(), &dp))
 					time.Sleep(5 * time.Millisecond)
 				}
 			},
@@ -279,7 +309,7 @@ func TestListDeviceProfilesByUserID(t *testing.T) {
 			expectedStatus: nethttp.StatusBadRequest,
 			assertFn: func(t *testing.T, status int, payload []byte, _ *acceptanceSuite) {
 				require.Equal(t, nethttp.StatusBadRequest, status)
-				var out map[string]string
+				var out map[string]any
 				require.NoError(t, json.Unmarshal(payload, &out))
 				assert.Equal(t, "INVALID_ARGUMENT", out["code"])
 			},
@@ -291,7 +321,7 @@ func TestListDeviceProfilesByUserID(t *testing.T) {
 			expectedStatus: nethttp.StatusBadRequest,
 			assertFn: func(t *testing.T, status int, payload []byte, _ *acceptanceSuite) {
 				require.Equal(t, nethttp.StatusBadRequest, status)
-				var out map[string]string
+				var out map[string]any
 				require.NoError(t, json.Unmarshal(payload, &out))
 				assert.Equal(t, "INVALID_ARGUMENT", out["code"])
 			},
@@ -306,7 +336,7 @@ func TestListDeviceProfilesByUserID(t *testing.T) {
 			expectedStatus: nethttp.StatusBadRequest,
 			assertFn: func(t *testing.T, status int, payload []byte, _ *acceptanceSuite) {
 				require.Equal(t, nethttp.StatusBadRequest, status)
-				var out map[string]string
+				var out map[string]any
 				require.NoError(t, json.Unmarshal(payload, &out))
 				assert.Equal(t, "INVALID_ARGUMENT", out["code"])
 			},
@@ -364,7 +394,7 @@ func TestCreateDeviceProfile(t *testing.T) {
 				assert.Equal(t, "My profile", out.Name)
 				assert.Equal(t, suite.userID, out.UserID)
 
-				listed, err := suite.repo.ListDeviceProfiles(suite.userID.String(), 1, 10)
+				listed, err := suite.repo.ListDeviceProfiles(t.Context(), suite.userID.String(), 1, 10)
 				require.NoError(t, err)
 				assert.Equal(t, 1, len(listed))
 			},
@@ -408,7 +438,7 @@ func TestUpdateDeviceProfile(t *testing.T) {
 			name: "updates profile",
 			setup: func(t *testing.T, suite *acceptanceSuite) (string, []byte) {
 				dp := entity.DeviceProfile{UserID: suite.userID, Name: "Original", DeviceType: "desktop"}
-				require.NoError(t, suite.repo.CreateDeviceProfile(&dp))
+				require.NoError(t, suite.repo.CreateDeviceProfile(t.Context(), &dp))
 				payload := map[string]any{"name": "Updated"}
 				body, err := json.Marshal(payload)
 				require.NoError(t, err)
@@ -426,7 +456,7 @@ func TestUpdateDeviceProfile(t *testing.T) {
 			name: "rejects empty payload",
 			setup: func(t *testing.T, suite *acceptanceSuite) (string, []byte) {
 				dp := entity.DeviceProfile{UserID: suite.userID, Name: "Original", DeviceType: "desktop"}
-				require.NoError(t, suite.repo.CreateDeviceProfile(&dp))
+				require.NoError(t, suite.repo.CreateDeviceProfile(t.Context(), &dp))
 				return dp.ID.String(), []byte("{}")
 			},
 			expectedStatus: nethttp.StatusBadRequest,
@@ -465,13 +495,13 @@ func TestDeleteDeviceProfile(t *testing.T) {
 			name: "deletes profile",
 			setup: func(t *testing.T, suite *acceptanceSuite) string {
 				dp := entity.DeviceProfile{UserID: suite.userID, Name: "ToDelete", DeviceType: "desktop"}
-				require.NoError(t, suite.repo.CreateDeviceProfile(&dp))
+				require.NoError(t, suite.repo.CreateDeviceProfile(t.Context(), &dp))
 				return dp.ID.String()
 			},
 			expectedStatus: nethttp.StatusNoContent,
 			assertFn: func(t *testing.T, status int, suite *acceptanceSuite) {
 				require.Equal(t, nethttp.StatusNoContent, status)
-				remaining, err := suite.repo.ListDeviceProfiles(suite.userID.String(), 1, 10)
+				remaining, err := suite.repo.ListDeviceProfiles(t.Context(), suite.userID.String(), 1, 10)
 				require.NoError(t, err)
 				assert.Len(t, remaining, 0)
 			},
@@ -511,6 +541,10 @@ func (e *erroringDeviceProfileService) ListDeviceProfilesByUserID(context.Contex
 	return nil, e.err
 }
 
+func (e *erroringDeviceProfileService) GetDeviceProfile(context.Context, string) (*entity.DeviceProfile, error) {
+	return nil, e.err
+}
+
 func (e *erroringDeviceProfileService) CreateDeviceProfile(context.Context, *entity.DeviceProfile) error {
 	return fmt.Errorf("not implemented")
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 func setupDPRepo(t *testing.T) (*repo.DeviceProfileRepoImpl, *entity.User) {
@@ -26,7 +27,7 @@ func setupDPRepo(t *testing.T) (*repo.DeviceProfileRepoImpl, *entity.User) {
 	dbConn, err := util.NewTestDB()
 	require.NoError(t, err)
 	logger := applog.NewAppDefaultLogger()
-	r := repo.NewDeviceProfileRepoImpl(logger, dbConn)
+	r := repo.NewDeviceProfileRepoImpl(logger, dbConn, nil)
 
 	// Unique user for isolation
 	uname := "testuser_" + uuid.NewString()
@@ -61,7 +62,7 @@ func TestDeviceProfileRepo_CreateDeviceProfile(t *testing.T) {
 					CountryCode:   &cc,
 					CustomHeaders: datatypes.JSONMap{"X-Test": "true"},
 				}
-				require.NoError(t, r.CreateDeviceProfile(&dp))
+				require.NoError(t, r.CreateDeviceProfile(t.Context(), &dp))
 				assert.NotZero(t, dp.ID)
 			},
 		},
@@ -75,7 +76,7 @@ func TestDeviceProfileRepo_CreateDeviceProfile(t *testing.T) {
 func TestDeviceProfileRepo_ListDeviceProfilesRetrievesCreatedProfile(t *testing.T) {
 	r, u := setupDPRepo(t)
 	dp := entity.DeviceProfile{UserID: u.ID, Name: "P1", DeviceType: "desktop"}
-	require.NoError(t, r.CreateDeviceProfile(&dp))
+	require.NoError(t, r.CreateDeviceProfile(t.Context(), &dp))
 
 	tests := []struct {
 		name string
@@ -84,7 +85,7 @@ func TestDeviceProfileRepo_ListDeviceProfilesRetrievesCreatedProfile(t *testing.
 		{
 			name: "fetches by id",
 			run: func(t *testing.T) {
-				items, err := r.ListDeviceProfiles(u.ID.String(), 1, 10)
+				items, err := r.ListDeviceProfiles(t.Context(), u.ID.String(), 1, 10)
 				require.NoError(t, err)
 				var got *entity.DeviceProfile
 				for i := range items {
@@ -110,7 +111,7 @@ func TestDeviceProfileRepo_UpdateDeviceProfileSelective(t *testing.T) {
 	width := 800
 	ua := "UA/0"
 	dp := entity.DeviceProfile{UserID: u.ID, Name: "Psel", DeviceType: "desktop", Width: &width, UserAgent: &ua}
-	require.NoError(t, r.CreateDeviceProfile(&dp))
+	require.NoError(t, r.CreateDeviceProfile(t.Context(), &dp))
 
 	tests := []struct {
 		name string
@@ -122,9 +123,9 @@ func TestDeviceProfileRepo_UpdateDeviceProfileSelective(t *testing.T) {
 				newWidth := 1280
 				newUA := "UA/1"
 				patch := entity.DeviceProfile{ID: dp.ID, UserID: dp.UserID, Name: "Psel2", Width: &newWidth, UserAgent: &newUA}
-				require.NoError(t, r.UpdateDeviceProfile(&patch))
+				require.NoError(t, r.UpdateDeviceProfile(t.Context(), &patch))
 
-				items, err := r.ListDeviceProfiles(u.ID.String(), 1, 10)
+				items, err := r.ListDeviceProfiles(t.Context(), u.ID.String(), 1, 10)
 				require.NoError(t, err)
 				var got *entity.DeviceProfile
 				for i := range items {
@@ -156,7 +157,7 @@ func TestDeviceProfileRepo_ListDeviceProfiles(t *testing.T) {
 	// Create multiple profiles
 	for _, n := range []string{"L1", "L2", "L3"} {
 		tmp := entity.DeviceProfile{UserID: u.ID, Name: n, DeviceType: "desktop"}
-		require.NoError(t, r.CreateDeviceProfile(&tmp))
+		require.NoError(t, r.CreateDeviceProfile(t.Context(), &tmp))
 		time.Sleep(5 * time.Millisecond)
 	}
 
@@ -167,11 +168,11 @@ func TestDeviceProfileRepo_ListDeviceProfiles(t *testing.T) {
 		{
 			name: "paginates results",
 			run: func(t *testing.T) {
-				p1, err := r.ListDeviceProfiles(u.ID.String(), 1, 2)
+				p1, err := r.ListDeviceProfiles(t.Context(), u.ID.String(), 1, 2)
 				require.NoError(t, err)
 				assert.Len(t, p1, 2)
 
-				p2, err := r.ListDeviceProfiles(u.ID.String(), 2, 2)
+				p2, err := r.ListDeviceProfiles(t.Context(), u.ID.String(), 2, 2)
 				require.NoError(t, err)
 				assert.GreaterOrEqual(t, len(p2), 1)
 			},
@@ -183,10 +184,42 @@ func TestDeviceProfileRepo_ListDeviceProfiles(t *testing.T) {
 	}
 }
 
+func TestDeviceProfileRepo_GetDeviceProfile(t *testing.T) {
+	r, u := setupDPRepo(t)
+	dp := entity.DeviceProfile{UserID: u.ID, Name: "G1", DeviceType: "desktop", CustomHeaders: datatypes.JSONMap{"X-A": "1"}}
+	require.NoError(t, r.CreateDeviceProfile(t.Context(), &dp))
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "returns the profile of its owner",
+			run: func(t *testing.T) {
+				got, err := r.GetDeviceProfile(t.Context(), u.ID.String(), dp.ID.String())
+				require.NoError(t, err)
+				assert.Equal(t, "G1", got.Name)
+				assert.Equal(t, "1", got.CustomHeaders["X-A"])
+			},
+		},
+		{
+			name: "does not return profiles of other users",
+			run: func(t *testing.T) {
+				_, err := r.GetDeviceProfile(t.Context(), uuid.NewString(), dp.ID.String())
+				assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+}
+
 func TestDeviceProfileRepo_UpdateDeviceProfile(t *testing.T) {
 	r, u := setupDPRepo(t)
 	dp := entity.DeviceProfile{UserID: u.ID, Name: "PU1", DeviceType: "desktop"}
-	require.NoError(t, r.CreateDeviceProfile(&dp))
+	require.NoError(t, r.CreateDeviceProfile(t.Context(), &dp))
 
 	tests := []struct {
 		name string
@@ -196,8 +229,8 @@ func TestDeviceProfileRepo_UpdateDeviceProfile(t *testing.T) {
 			name: "updates full entity",
 			run: func(t *testing.T) {
 				dp.DeviceType = "mobile"
-				require.NoError(t, r.UpdateDeviceProfile(&dp))
-				items, err := r.ListDeviceProfiles(u.ID.String(), 1, 10)
+				require.NoError(t, r.UpdateDeviceProfile(t.Context(), &dp))
+				items, err := r.ListDeviceProfiles(t.Context(), u.ID.String(), 1, 10)
 				require.NoError(t, err)
 				var got *entity.DeviceProfile
 				for i := range items {
@@ -220,7 +253,7 @@ func TestDeviceProfileRepo_UpdateDeviceProfile(t *testing.T) {
 func TestDeviceProfileRepo_DeleteDeviceProfile(t *testing.T) {
 	r, u := setupDPRepo(t)
 	dp := entity.DeviceProfile{UserID: u.ID, Name: "PD1", DeviceType: "desktop"}
-	require.NoError(t, r.CreateDeviceProfile(&dp))
+	require.NoError(t, r.CreateDeviceProfile(t.Context(), &dp))
 
 	tests := []struct {
 		name string
@@ -229,8 +262,8 @@ func TestDeviceProfileRepo_DeleteDeviceProfile(t *testing.T) {
 		{
 			name: "deletes by id",
 			run: func(t *testing.T) {
-				require.NoError(t, r.DeleteDeviceProfile(u.ID.String(), dp.ID.String()))
-				items, err := r.ListDeviceProfiles(u.ID.String(), 1, 10)
+				require.NoError(t, r.DeleteDeviceProfile(t.Context(), u.ID.String(), dp.ID.String()))
+				items, err := r.ListDeviceProfiles(t.Context(), u.ID.String(), 1, 10)
 				require.NoError(t, err)
 				assert.Len(t, items, 0)
 				for _, item := range items {
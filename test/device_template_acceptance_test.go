@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net"
@@ -14,7 +15,8 @@ import (
 	"zenrows-challenge/internal/core/port"
 	"zenrows-challenge/internal/core/usecase"
 	"zenrows-challenge/internal/pkg/apperr"
-	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/util"
+	"zenrows-challenge/internal/pkg/validation"
 	testutil "zenrows-challenge/test/util"
 
 	"github.com/gofiber/fiber/v3"
@@ -75,7 +77,7 @@ func newDeviceTemplateSuite(t *testing.T, withAuth bool, svc port.DeviceTemplate
 		require.NoError(t, err)
 
 		repoImpl = repo.NewDeviceTemplateRepoImpl(logger, dbConn)
-		svc = usecase.NewDeviceTemplateServiceImpl(logger, repoImpl)
+		svc = usecase.NewDeviceTemplateServiceImpl(logger, repoImpl, repo.NewUnitOfWorkImpl(logger, dbConn, nil), validation.New())
 	}
 
 	handler := httpadapter.NewDeviceTemplateHandlerImpl(logger, svc)
@@ -89,7 +91,7 @@ func newDeviceTemplateSuite(t *testing.T, withAuth bool, svc port.DeviceTemplate
 					"message": "unauthorized",
 				})
 			}
-			c.Locals(middleware.AuthUserIDKey, uuid.New().String())
+			c.SetContext(util.WithPrincipal(c.Context(), util.Principal{UserID: uuid.New()}))
 			return c.Next()
 		})
 	}
@@ -128,10 +130,14 @@ type erroringTemplateService struct {
 	err error
 }
 
-func (e erroringTemplateService) RetrieveDeviceTemplates() ([]entity.DeviceTemplate, error) {
+func (e erroringTemplateService) RetrieveDeviceTemplates(context.Context) ([]entity.DeviceTemplate, error) {
 	return nil, e.err
 }
 
+func (e erroringTemplateService) ImportDeviceTemplates(context.Context, []entity.DeviceTemplate) (int, int, error) {
+	return 0, 0, e.err
+}
+
 func TestDeviceTemplateHandler_List(t *testing.T) {
 	cases := []struct {
 		name           string
@@ -194,7 +200,7 @@ func TestDeviceTemplateHandler_List(t *testing.T) {
 			expectedStatus: nethttp.StatusUnauthorized,
 			assertFn: func(t *testing.T, status int, payload []byte) {
 				require.Equal(t, nethttp.StatusUnauthorized, status)
-				var out map[string]string
+				var out map[string]any
 				require.NoError(t, json.Unmarshal(payload, &out))
 				assert.Equal(t, "NOT_AUTHORIZED", out["code"])
 			},
@@ -207,7 +213,7 @@ func TestDeviceTemplateHandler_List(t *testing.T) {
 			expectedStatus: nethttp.StatusBadRequest,
 			assertFn: func(t *testing.T, status int, payload []byte) {
 				require.Equal(t, nethttp.StatusBadRequest, status)
-				var out map[string]string
+				var out map[string]any
 				require.NoError(t, json.Unmarshal(payload, &out))
 				assert.Equal(t, "INVALID_ARGUMENT", out["code"])
 			},
@@ -35,7 +35,7 @@ func TestDeviceTemplateRepositoryIntegration(t *testing.T) {
 			name: "retrieve device templates",
 			run: func(t *testing.T) {
 
-				dts, err := r.GetDeviceTemplates()
+				dts, err := r.GetDeviceTemplates(t.Context())
 				require.NoError(t, err)
 
 				for i, dt := range dts {
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/pkg/applog"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string                    { return c.name }
+func (c checkerFunc) Check(ctx context.Context) error { return c.check(ctx) }
+
+// warnLogger keeps the Warn records it receives, formatted as "msg key=value ...".
+type warnLogger struct {
+	noopLogger
+	mu      *sync.Mutex
+	entries *[]string
+}
+
+func newWarnLogger() warnLogger {
+	return warnLogger{mu: &sync.Mutex{}, entries: &[]string{}}
+}
+
+func (l warnLogger) Warn(msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := 0; i+1 < len(args); i += 2 {
+		msg += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	*l.entries = append(*l.entries, msg)
+}
+
+func (l warnLogger) WithContext(context.Context) applog.AppLogger { return l }
+
+func (l warnLogger) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), *l.entries...)
+}
+
+func getHealth(t *testing.T, app *fiber.App, path string) (int, infra.HealthReport) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(nethttp.MethodGet, path, nil), fiber.TestConfig{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	var report infra.HealthReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func TestHealthProbes(t *testing.T) {
+	ok := checkerFunc{name: "database", check: func(context.Context) error { return nil }}
+	down := checkerFunc{name: "migrations", check: func(context.Context) error { return errors.New("schema version 9, expected 10") }}
+	slow := checkerFunc{name: "slow", check: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }}
+
+	newApp := func(h *infra.Health) *fiber.App {
+		app := fiber.New()
+		app.Get("/livez", h.Livez)
+		app.Get("/readyz", h.Readyz)
+		return app
+	}
+
+	t.Run("ready when every check passes", func(t *testing.T) {
+		status, report := getHealth(t, newApp(infra.NewHealth(noopLogger{}, time.Second, ok)), "/readyz")
+		assert.Equal(t, nethttp.StatusOK, status)
+		assert.Equal(t, infra.HealthOK, report.Status)
+		assert.Equal(t, infra.HealthOK, report.Checks["database"].Status)
+	})
+
+	t.Run("not ready when a check fails or times out", func(t *testing.T) {
+		log := newWarnLogger()
+		h := infra.NewHealth(log, 20*time.Millisecond, ok, down)
+		h.Register(slow)
+		app := newApp(h)
+
+		status, report := getHealth(t, app, "/readyz")
+		assert.Equal(t, nethttp.StatusServiceUnavailable, status)
+		assert.Equal(t, infra.HealthFailing, report.Status)
+		assert.Equal(t, infra.HealthOK, report.Checks["database"].Status)
+		assert.Equal(t, infra.HealthFailing, report.Checks["migrations"].Status)
+		assert.Equal(t, infra.HealthFailing, report.Checks["slow"].Status)
+
+		logged := log.all()
+		require.Len(t, logged, 2, "failure causes are logged, not returned")
+		assert.True(t, containsAll(logged, "check=migrations", "schema version 9, expected 10"), logged)
+		assert.True(t, containsAll(logged, "check=slow", context.DeadlineExceeded.Error()), logged)
+
+		status, _ = getHealth(t, app, "/livez")
+		assert.Equal(t, nethttp.StatusOK, status, "liveness does not depend on the checks")
+	})
+
+	t.Run("not ready while draining", func(t *testing.T) {
+		h := infra.NewHealth(noopLogger{}, time.Second, ok)
+		app := newApp(h)
+		h.Drain()
+
+		status, report := getHealth(t, app, "/readyz")
+		assert.Equal(t, nethttp.StatusServiceUnavailable, status)
+		assert.Equal(t, infra.HealthDraining, report.Status)
+	})
+}
+
+// containsAll reports whether one of entries contains every part.
+func containsAll(entries []string, parts ...string) bool {
+	for _, e := range entries {
+		found := true
+		for _, p := range parts {
+			found = found && strings.Contains(e, p)
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/util"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyRepo mimics the Postgres reservation semantics in memory.
+type memoryIdempotencyRepo struct {
+	mu   sync.Mutex
+	keys map[string]entity.IdempotencyKey
+}
+
+func newMemoryIdempotencyRepo() *memoryIdempotencyRepo {
+	return &memoryIdempotencyRepo{keys: make(map[string]entity.IdempotencyKey)}
+}
+
+func (r *memoryIdempotencyRepo) ReserveIdempotencyKey(_ context.Context, k *entity.IdempotencyKey) (*entity.IdempotencyKey, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := k.Scope + "/" + k.Key
+	if existing, ok := r.keys[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, false, nil
+	}
+	stored := *k
+	stored.Status = entity.IdempotencyInProgress
+	r.keys[id] = stored
+	return &stored, true, nil
+}
+
+func (r *memoryIdempotencyRepo) CompleteIdempotencyKey(_ context.Context, k *entity.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *k
+	stored.Status = entity.IdempotencyCompleted
+	r.keys[k.Scope+"/"+k.Key] = stored
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) ReleaseIdempotencyKey(_ context.Context, scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, scope+"/"+key)
+	return nil
+}
+
+func newIdempotencyApp(handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	userID := uuid.New()
+	app.Use(func(c fiber.Ctx) error {
+		c.SetContext(util.WithPrincipal(c.Context(), util.Principal{UserID: userID}))
+		return c.Next()
+	})
+	app.Post("/device-profiles", middleware.IdempotencyMiddleware(noopLogger{}, newMemoryIdempotencyRepo(), time.Hour, time.Minute), handler)
+	return app
+}
+
+func postWithKey(t *testing.T, app *fiber.App, key, body string) *nethttp.Response {
+	t.Helper()
+	return postWithKeyTo(t, app, "/device-profiles", key, body)
+}
+
+func postWithKeyTo(t *testing.T, app *fiber.App, target, key, body string) *nethttp.Response {
+	t.Helper()
+	req := httptest.NewRequest(nethttp.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	cases := []struct {
+		name     string
+		run      func(t *testing.T, app *fiber.App)
+		expected int32
+	}{
+		{
+			name: "replays stored response for the same key and body",
+			run: func(t *testing.T, app *fiber.App) {
+				first := postWithKey(t, app, "k1", `{"name":"a"}`)
+				require.Equal(t, nethttp.StatusCreated, first.StatusCode)
+
+				second := postWithKey(t, app, "k1", `{"name":"a"}`)
+				require.Equal(t, nethttp.StatusCreated, second.StatusCode)
+				assert.Equal(t, "true", second.Header.Get(middleware.IdempotentReplayedHeader))
+			},
+			expected: 1,
+		},
+		{
+			name: "rejects key reuse with a different body",
+			run: func(t *testing.T, app *fiber.App) {
+				require.Equal(t, nethttp.StatusCreated, postWithKey(t, app, "k2", `{"name":"a"}`).StatusCode)
+				assert.Equal(t, nethttp.StatusUnprocessableEntity, postWithKey(t, app, "k2", `{"name":"b"}`).StatusCode)
+			},
+			expected: 1,
+		},
+		{
+			name: "rejects key reuse with a different query",
+			run: func(t *testing.T, app *fiber.App) {
+				require.Equal(t, nethttp.StatusCreated, postWithKeyTo(t, app, "/device-profiles?a=1&b=2", "k3", `{"name":"a"}`).StatusCode)
+				assert.Equal(t, nethttp.StatusUnprocessableEntity, postWithKeyTo(t, app, "/device-profiles?a=1&b=2&reveal=true", "k3", `{"name":"a"}`).StatusCode)
+
+				replay := postWithKeyTo(t, app, "/device-profiles?b=2&a=1", "k3", `{"name":"a"}`)
+				require.Equal(t, nethttp.StatusCreated, replay.StatusCode)
+				assert.Equal(t, "true", replay.Header.Get(middleware.IdempotentReplayedHeader), "argument order does not matter")
+			},
+			expected: 1,
+		},
+		{
+			name: "requests without a key are not deduplicated",
+			run: func(t *testing.T, app *fiber.App) {
+				require.Equal(t, nethttp.StatusCreated, postWithKey(t, app, "", `{"name":"a"}`).StatusCode)
+				require.Equal(t, nethttp.StatusCreated, postWithKey(t, app, "", `{"name":"a"}`).StatusCode)
+			},
+			expected: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			app := newIdempotencyApp(func(c fiber.Ctx) error {
+				calls.Add(1)
+				return c.Status(nethttp.StatusCreated).JSON(map[string]string{"name": "a"})
+			})
+			tc.run(t, app)
+			assert.Equal(t, tc.expected, calls.Load())
+		})
+	}
+}
+
+func TestIdempotencyMiddleware_ConcurrentDuplicateIsRejected(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	app := newIdempotencyApp(func(c fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.Status(nethttp.StatusCreated).JSON(map[string]string{"name": "a"})
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- postWithKey(t, app, "k3", `{"name":"a"}`).StatusCode
+	}()
+	<-started
+
+	resp := postWithKey(t, app, "k3", `{"name":"a"}`)
+	assert.Equal(t, nethttp.StatusConflict, resp.StatusCode)
+
+	close(release)
+	assert.Equal(t, nethttp.StatusCreated, <-done)
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/envelope"
+	"zenrows-challenge/test/util"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyRepo(t *testing.T) {
+	require.NoError(t, util.LoadConfig())
+	if _, err := util.InitTestContainers(t); err != nil {
+		require.NoError(t, err)
+	}
+	dbConn, err := util.NewTestDB()
+	require.NoError(t, err)
+	keys, err := envelope.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, envelope.KeySize)})
+	require.NoError(t, err)
+	r := repo.NewIdempotencyRepoImpl(applog.NewAppDefaultLogger(), dbConn, keys)
+
+	newKey := func(lease time.Duration) *entity.IdempotencyKey {
+		lockedUntil := time.Now().Add(lease)
+		return &entity.IdempotencyKey{
+			Scope:       uuid.NewString(),
+			Key:         uuid.NewString(),
+			Fingerprint: "fp",
+			ExpiresAt:   time.Now().Add(time.Hour),
+			LockedUntil: &lockedUntil,
+		}
+	}
+
+	t.Run("stores the response encrypted and replays it in clear", func(t *testing.T) {
+		k := newKey(time.Minute)
+		_, reserved, err := r.ReserveIdempotencyKey(t.Context(), k)
+		require.NoError(t, err)
+		require.True(t, reserved)
+
+		status, contentType := http.StatusCreated, "application/json"
+		body := []byte(`{"custom_headers":{"Cookie":"session=abc"}}`)
+		k.ResponseStatus, k.ResponseBody, k.ContentType = &status, body, &contentType
+		require.NoError(t, r.CompleteIdempotencyKey(t.Context(), k))
+
+		var raw entity.IdempotencyKey
+		require.NoError(t, dbConn.First(&raw, "scope = ? AND key = ?", k.Scope, k.Key).Error)
+		assert.NotContains(t, string(raw.ResponseBody), "session=abc")
+		assert.Nil(t, raw.LockedUntil)
+
+		stored, reserved, err := r.ReserveIdempotencyKey(t.Context(), newKeyFor(k))
+		require.NoError(t, err)
+		assert.False(t, reserved)
+		assert.Equal(t, body, stored.ResponseBody)
+	})
+
+	t.Run("a retry takes over a reservation whose lease ran out", func(t *testing.T) {
+		k := newKey(-time.Second)
+		_, reserved, err := r.ReserveIdempotencyKey(t.Context(), k)
+		require.NoError(t, err)
+		require.True(t, reserved)
+
+		other := newKeyFor(k)
+		other.Fingerprint = "other"
+		_, reserved, err = r.ReserveIdempotencyKey(t.Context(), other)
+		require.NoError(t, err)
+		assert.False(t, reserved, "a different request never takes the key over")
+
+		_, reserved, err = r.ReserveIdempotencyKey(t.Context(), newKeyFor(k))
+		require.NoError(t, err)
+		assert.True(t, reserved)
+
+		_, reserved, err = r.ReserveIdempotencyKey(t.Context(), newKeyFor(k))
+		require.NoError(t, err)
+		assert.False(t, reserved, "the new lease holds")
+	})
+
+	t.Run("purges expired keys", func(t *testing.T) {
+		expired, live := newKey(time.Minute), newKey(time.Minute)
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		for _, k := range []*entity.IdempotencyKey{expired, live} {
+			_, _, err := r.ReserveIdempotencyKey(t.Context(), k)
+			require.NoError(t, err)
+		}
+
+		n, err := r.PurgeExpiredIdempotencyKeys(t.Context())
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, n, int64(1))
+
+		var count int64
+		require.NoError(t, dbConn.Model(&entity.IdempotencyKey{}).Where("scope = ?", expired.Scope).Count(&count).Error)
+		assert.Zero(t, count)
+		require.NoError(t, dbConn.Model(&entity.IdempotencyKey{}).Where("scope = ?", live.Scope).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+// newKeyFor returns a fresh reservation for the same scope, key and request as k.
+func newKeyFor(k *entity.IdempotencyKey) *entity.IdempotencyKey {
+	lockedUntil := time.Now().Add(time.Minute)
+	return &entity.IdempotencyKey{
+		Scope:       k.Scope,
+		Key:         k.Key,
+		Fingerprint: k.Fingerprint,
+		ExpiresAt:   time.Now().Add(time.Hour),
+		LockedUntil: &lockedUntil,
+	}
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/metrics"
+	"zenrows-challenge/internal/pkg/middleware"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetricsApp() *fiber.App {
+	app := fiber.New()
+	app.Use(middleware.MetricsMiddleware())
+	app.Get("/metrics", middleware.BearerTokenMiddleware("secret"), adaptor.HTTPHandler(metrics.Handler()))
+	app.Get("/device-profiles/:id", func(c fiber.Ctx) error { return c.SendStatus(nethttp.StatusNoContent) })
+	return app
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	app := newMetricsApp()
+	testConfig := fiber.TestConfig{Timeout: 5 * time.Second}
+
+	resp, err := app.Test(httptest.NewRequest(nethttp.MethodGet, "/device-profiles/8a1b2c3d", nil), testConfig)
+	require.NoError(t, err)
+	require.Equal(t, nethttp.StatusNoContent, resp.StatusCode)
+
+	t.Run("requires the bearer token", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest(nethttp.MethodGet, "/metrics", nil), testConfig)
+		require.NoError(t, err)
+		assert.Equal(t, nethttp.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "Bearer", resp.Header.Get(fiber.HeaderWWWAuthenticate))
+	})
+
+	t.Run("labels requests by route template", func(t *testing.T) {
+		req := httptest.NewRequest(nethttp.MethodGet, "/metrics", nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+		resp, err := app.Test(req, testConfig)
+		require.NoError(t, err)
+		require.Equal(t, nethttp.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `route="/device-profiles/:id",status="204"`)
+		assert.NotContains(t, string(body), "8a1b2c3d")
+		assert.Contains(t, string(body), "go_goroutines")
+	})
+}
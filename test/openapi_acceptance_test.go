@@ -0,0 +1,114 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httpadapter "zenrows-challenge/internal/adapter/http"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/openapi"
+	"zenrows-challenge/internal/pkg/problem"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIValidation(t *testing.T) {
+	doc, err := openapi.Load(httpadapter.OpenAPISpec())
+	require.NoError(t, err)
+
+	// profile is a DeviceProfileResponse; broken drops its required user_id.
+	profile := map[string]any{
+		"id": uuid.NewString(), "user_id": uuid.NewString(), "name": "Pixel", "device_type": "mobile",
+		"created_at": time.Now().Format(time.RFC3339), "updated_at": time.Now().Format(time.RFC3339),
+	}
+	var broken bool
+	reached := 0
+
+	app := fiber.New()
+	app.Use(middleware.ResponseValidationMiddleware(noopLogger{}, doc))
+	protected := app.Group("/", middleware.RequestValidationMiddleware(doc))
+	protected.Get("/device-profiles", func(c fiber.Ctx) error {
+		reached++
+		return c.JSON([]map[string]any{profile})
+	})
+	protected.Post("/device-profiles", func(c fiber.Ctx) error {
+		reached++
+		body := map[string]any{}
+		for k, v := range profile {
+			if !broken || k != "user_id" {
+				body[k] = v
+			}
+		}
+		return c.Status(nethttp.StatusCreated).JSON(body)
+	})
+	protected.Delete("/device-profiles/:id", func(c fiber.Ctx) error {
+		reached++
+		return c.SendStatus(nethttp.StatusNoContent)
+	})
+
+	do := func(method, target, body string) (*nethttp.Response, problem.Details) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderAccept, problem.ContentType)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var out problem.Details
+		if resp.StatusCode >= 400 {
+			b, _ := io.ReadAll(resp.Body)
+			require.NoError(t, json.Unmarshal(b, &out))
+		}
+		return resp, out
+	}
+
+	t.Run("valid requests reach the handler", func(t *testing.T) {
+		reached = 0
+		resp, _ := do(nethttp.MethodGet, "/device-profiles?page=2&page_size=10&reveal=true", "")
+		assert.Equal(t, nethttp.StatusOK, resp.StatusCode)
+		resp, _ = do(nethttp.MethodPost, "/device-profiles", `{"name": "Pixel", "device_type": "mobile", "country_code": "RO"}`)
+		assert.Equal(t, nethttp.StatusCreated, resp.StatusCode)
+		resp, _ = do(nethttp.MethodDelete, "/device-profiles/"+uuid.NewString(), "")
+		assert.Equal(t, nethttp.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, 3, reached)
+	})
+
+	t.Run("invalid requests are rejected with field violations", func(t *testing.T) {
+		reached = 0
+		resp, p := do(nethttp.MethodGet, "/device-profiles?page=0&reveal=maybe", "")
+		assert.Equal(t, nethttp.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "INVALID_ARGUMENT", p.Code)
+		assert.Equal(t, []apperr.FieldViolation{
+			{Field: "page", Rule: "min", Param: "1", Message: "must be at least 1"},
+			{Field: "reveal", Rule: "type", Param: "boolean", Message: "must be of type boolean"},
+		}, p.Violations)
+
+		resp, p = do(nethttp.MethodPost, "/device-profiles", `{"name": "", "device_type": "tablet", "width": 0}`)
+		assert.Equal(t, nethttp.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, []apperr.FieldViolation{
+			{Field: "device_type", Rule: "oneof", Param: "desktop mobile", Message: "must be one of: desktop, mobile"},
+			{Field: "name", Rule: "min", Param: "1", Message: "must be at least 1 characters long"},
+			{Field: "width", Rule: "gt", Param: "0", Message: "must be greater than 0"},
+		}, p.Violations)
+
+		resp, p = do(nethttp.MethodDelete, "/device-profiles/42", "")
+		assert.Equal(t, nethttp.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, []apperr.FieldViolation{{Field: "id", Rule: "uuid", Message: "must be a valid UUID"}}, p.Violations)
+		assert.Zero(t, reached)
+	})
+
+	t.Run("responses not matching the spec become errors", func(t *testing.T) {
+		broken = true
+		t.Cleanup(func() { broken = false })
+		resp, p := do(nethttp.MethodPost, "/device-profiles", `{"name": "Pixel", "device_type": "mobile"}`)
+		assert.Equal(t, nethttp.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "INTERNAL_ERROR", p.Code)
+	})
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"testing"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/core/entity"
+	"zenrows-challenge/internal/core/port"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/test/util"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestQuotaRepo_GetUserQuota(t *testing.T) {
+	require.NoError(t, util.LoadConfig())
+	if _, err := util.InitTestContainers(t); err != nil {
+		require.NoError(t, err)
+	}
+	dbConn, err := util.NewTestDB()
+	require.NoError(t, err)
+	logger := applog.NewAppDefaultLogger()
+	r := repo.NewQuotaRepoImpl(logger, dbConn)
+
+	plain := entity.User{Username: "quota_" + uuid.NewString(), PasswordHash: "x", Plan: "pro"}
+	require.NoError(t, dbConn.Create(&plain).Error)
+	limited := entity.User{Username: "quota_" + uuid.NewString(), PasswordHash: "x", Plan: "free"}
+	require.NoError(t, dbConn.Create(&limited).Error)
+	maxProfiles := 7
+	require.NoError(t, dbConn.Create(&entity.UserQuota{UserID: limited.ID, MaxProfiles: &maxProfiles}).Error)
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "returns the plan of a user without overrides",
+			run: func(t *testing.T) {
+				plan, override, err := r.GetUserQuota(t.Context(), plain.ID.String())
+				require.NoError(t, err)
+				assert.Equal(t, "pro", plan)
+				assert.Nil(t, override)
+			},
+		},
+		{
+			name: "returns the plan and the overrides",
+			run: func(t *testing.T) {
+				plan, override, err := r.GetUserQuota(t.Context(), limited.ID.String())
+				require.NoError(t, err)
+				assert.Equal(t, "free", plan)
+				require.NotNil(t, override)
+				assert.Equal(t, 7, *override.MaxProfiles)
+				assert.Nil(t, override.MaxCustomHeaders)
+			},
+		},
+		{
+			name: "locks the user within a transaction",
+			run: func(t *testing.T) {
+				uow := repo.NewUnitOfWorkImpl(logger, dbConn, nil)
+				require.NoError(t, uow.Do(t.Context(), func(tx port.TxRepos) error {
+					plan, _, err := tx.Quotas().LockUserQuota(t.Context(), limited.ID.String())
+					assert.Equal(t, "free", plan)
+					return err
+				}))
+			},
+		},
+		{
+			name: "reports unknown users as not found",
+			run: func(t *testing.T) {
+				_, _, err := r.GetUserQuota(t.Context(), uuid.NewString())
+				assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+}
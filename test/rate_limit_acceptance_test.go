@@ -0,0 +1,212 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	httpadapter "zenrows-challenge/internal/adapter/http"
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/problem"
+	"zenrows-challenge/internal/pkg/ratelimit"
+	"zenrows-challenge/internal/pkg/redact"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStore is a ratelimit.MemoryStore remembering the keys it was asked for.
+type recordingStore struct {
+	store *ratelimit.MemoryStore
+	err   error
+
+	mu   sync.Mutex
+	keys []string
+}
+
+func (s *recordingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.mu.Lock()
+	s.keys = append(s.keys, key)
+	s.mu.Unlock()
+	if s.err != nil {
+		return ratelimit.Result{}, s.err
+	}
+	return s.store.Take(ctx, key, limit)
+}
+
+func (s *recordingStore) lastKey() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[len(s.keys)-1]
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	policy := ratelimit.Policy{
+		Name:  "api",
+		Read:  ratelimit.Limit{Rate: 0.5, Burst: 2},
+		Write: ratelimit.Limit{Rate: 0.5, Burst: 1},
+	}
+	newApp := func(store ratelimit.Store) *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+		app.Use(middleware.RateLimitMiddleware(applog.NewAppLoggerTo(io.Discard), store, func() ratelimit.Policy { return policy }))
+		app.Get("/device-profiles", func(c fiber.Ctx) error { return nil })
+		app.Post("/device-profiles", func(c fiber.Ctx) error { return c.SendStatus(nethttp.StatusCreated) })
+		return app
+	}
+	do := func(t *testing.T, app *fiber.App, method string) *nethttp.Response {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(method, "/device-profiles", nil))
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("reports the bucket on every response", func(t *testing.T) {
+		app := newApp(&recordingStore{store: ratelimit.NewMemoryStore()})
+
+		resp := do(t, app, nethttp.MethodGet)
+		assert.Equal(t, nethttp.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "2", resp.Header.Get("RateLimit-Reset"))
+		assert.Empty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+	})
+
+	t.Run("rejects an empty bucket with problem details", func(t *testing.T) {
+		app := newApp(&recordingStore{store: ratelimit.NewMemoryStore()})
+		require.Equal(t, nethttp.StatusCreated, do(t, app, nethttp.MethodPost).StatusCode)
+
+		resp := do(t, app, nethttp.MethodPost)
+		assert.Equal(t, nethttp.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get(fiber.HeaderRetryAfter))
+		assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+		var body struct {
+			Status int    `json:"status"`
+			Code   string `json:"code"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, nethttp.StatusTooManyRequests, body.Status)
+		assert.Equal(t, "RATE_LIMITED", body.Code)
+
+		// Reads have a bucket of their own.
+		assert.Equal(t, nethttp.StatusOK, do(t, app, nethttp.MethodGet).StatusCode)
+	})
+
+	t.Run("keys anonymous requests by client IP", func(t *testing.T) {
+		store := &recordingStore{store: ratelimit.NewMemoryStore()}
+		app := newApp(store)
+
+		do(t, app, nethttp.MethodGet)
+		assert.Regexp(t, `^api:read:ip:.+$`, store.lastKey())
+		do(t, app, nethttp.MethodPost)
+		assert.Regexp(t, `^api:write:ip:.+$`, store.lastKey())
+	})
+
+	t.Run("lets requests through when the store fails", func(t *testing.T) {
+		app := newApp(&recordingStore{err: errors.New("connection refused")})
+
+		resp := do(t, app, nethttp.MethodPost)
+		assert.Equal(t, nethttp.StatusCreated, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+	})
+}
+
+// passwordAuth knows the users of its map, by name, with their password and ID.
+type passwordAuth map[string]struct{ password, id string }
+
+func (a passwordAuth) CheckCredentials(_ context.Context, username string, password redact.Secret) (string, error) {
+	if u, ok := a[username]; ok && string(password) == u.password {
+		return u.id, nil
+	}
+	return "", apperr.NewNotAuthorizedErr("invalid credentials", nil)
+}
+
+func (passwordAuth) CheckCertificateUser(context.Context, string) (string, error) {
+	return "", apperr.NewNotAuthorizedErr("unknown user", nil)
+}
+
+func (passwordAuth) RecordAuthFailure(context.Context, string, string) {}
+
+// okHandlers answers every route with an empty 200.
+type okHandlers struct{}
+
+func (okHandlers) List(fiber.Ctx) error                       { return nil }
+func (okHandlers) ListDeviceProfilesByUserID(fiber.Ctx) error { return nil }
+func (okHandlers) GetDeviceProfile(fiber.Ctx) error           { return nil }
+func (okHandlers) CreateDeviceProfile(fiber.Ctx) error        { return nil }
+func (okHandlers) UpdateDeviceProfile(fiber.Ctx) error        { return nil }
+func (okHandlers) DeleteDeviceProfile(fiber.Ctx) error        { return nil }
+func (okHandlers) GetUsage(fiber.Ctx) error                   { return nil }
+func (okHandlers) ListAuditEvents(fiber.Ctx) error            { return nil }
+func (okHandlers) ListMyAuditEvents(fiber.Ctx) error          { return nil }
+
+// TestRateLimitRoutes checks the limiters as RegisterRoutes chains them around
+// authentication: by client IP before it and by user after it.
+func TestRateLimitRoutes(t *testing.T) {
+	aliceID, bobID := uuid.NewString(), uuid.NewString()
+	users := passwordAuth{"alice": {"alice-pw", aliceID}, "bob": {"bob-pw", bobID}}
+	log := applog.NewAppLoggerTo(io.Discard)
+	limiter := func(store ratelimit.Store, policy ratelimit.Policy) fiber.Handler {
+		return middleware.RateLimitMiddleware(log, store, func() ratelimit.Policy { return policy })
+	}
+	newApp := func(store ratelimit.Store, authBurst, apiBurst int) *fiber.App {
+		next := func(c fiber.Ctx) error { return c.Next() }
+		app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+		httpadapter.RegisterRoutes(app, httpadapter.Routes{
+			PublicLimit:     next,
+			AuthLimit:       limiter(store, ratelimit.Policy{Name: "auth", Read: ratelimit.Limit{Rate: 0.01, Burst: authBurst}}),
+			Authenticate:    []fiber.Handler{middleware.BasicAuthCheckMiddleware(users, validator.New())},
+			APILimit:        limiter(store, ratelimit.Policy{Name: "api", Read: ratelimit.Limit{Rate: 0.01, Burst: apiBurst}}),
+			Idempotency:     next,
+			Livez:           next,
+			Readyz:          next,
+			DeviceTemplates: okHandlers{},
+			DeviceProfiles:  okHandlers{},
+			Users:           okHandlers{},
+			Audit:           okHandlers{},
+			OpenAPI:         httpadapter.NewOpenAPIHandlerImpl(),
+		})
+		return app
+	}
+	get := func(t *testing.T, app *fiber.App, username, password string) *nethttp.Response {
+		t.Helper()
+		req := httptest.NewRequest(nethttp.MethodGet, "/device-templates", nil)
+		req.SetBasicAuth(username, password)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("users sharing a client IP get buckets of their own", func(t *testing.T) {
+		store := &recordingStore{store: ratelimit.NewMemoryStore()}
+		app := newApp(store, 10, 1)
+
+		require.Equal(t, nethttp.StatusOK, get(t, app, "alice", "alice-pw").StatusCode)
+		assert.Equal(t, "api:read:user:"+aliceID, store.lastKey())
+		assert.Equal(t, nethttp.StatusTooManyRequests, get(t, app, "alice", "alice-pw").StatusCode)
+
+		assert.Equal(t, nethttp.StatusOK, get(t, app, "bob", "bob-pw").StatusCode, "alice's bucket is not bob's")
+		assert.Equal(t, "api:read:user:"+bobID, store.lastKey())
+	})
+
+	t.Run("limits password guessing by client IP", func(t *testing.T) {
+		store := &recordingStore{store: ratelimit.NewMemoryStore()}
+		app := newApp(store, 2, 10)
+
+		for range 2 {
+			require.Equal(t, nethttp.StatusUnauthorized, get(t, app, "alice", "guess").StatusCode)
+			assert.Regexp(t, `^auth:read:ip:.+$`, store.lastKey())
+		}
+		assert.Equal(t, nethttp.StatusTooManyRequests, get(t, app, "alice", "alice-pw").StatusCode)
+	})
+}
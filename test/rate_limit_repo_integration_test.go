@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/adapter/repo"
+	"zenrows-challenge/internal/pkg/applog"
+	"zenrows-challenge/internal/pkg/ratelimit"
+	"zenrows-challenge/test/util"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitRepo(t *testing.T) {
+	require.NoError(t, util.LoadConfig())
+	if _, err := util.InitTestContainers(t); err != nil {
+		require.NoError(t, err)
+	}
+	dbConn, err := util.NewTestDB()
+	require.NoError(t, err)
+	r := repo.NewRateLimitRepoImpl(applog.NewAppDefaultLogger(), dbConn)
+	limit := ratelimit.Limit{Rate: 1, Burst: 2}
+
+	t.Run("takes tokens until the bucket is empty", func(t *testing.T) {
+		key := "test:" + uuid.NewString()
+		for i, remaining := range []int{1, 0} {
+			res, err := r.Take(t.Context(), key, limit)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed, "take %d", i)
+			assert.Equal(t, remaining, res.Remaining)
+		}
+		res, err := r.Take(t.Context(), key, limit)
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Positive(t, res.RetryAfter)
+	})
+
+	t.Run("serialises concurrent takes of a key", func(t *testing.T) {
+		key := "test:" + uuid.NewString()
+		slow := ratelimit.Limit{Rate: 0.001, Burst: 5}
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed int
+		)
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := r.Take(context.Background(), key, slow)
+				assert.NoError(t, err)
+				if res.Allowed {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, slow.Burst, allowed)
+	})
+
+	t.Run("prunes the buckets that are full again", func(t *testing.T) {
+		full, drained := "test:"+uuid.NewString(), "test:"+uuid.NewString()
+		_, err := r.Take(t.Context(), full, ratelimit.Limit{Rate: 1000, Burst: 1})
+		require.NoError(t, err)
+		_, err = r.Take(t.Context(), drained, ratelimit.Limit{Rate: 0.001, Burst: 1})
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		_, err = r.PruneRateLimitBuckets(t.Context())
+		require.NoError(t, err)
+		var keys []string
+		require.NoError(t, dbConn.Raw(`SELECT key FROM zenrows.rate_limit_bucket WHERE key IN (?, ?)`, full, drained).Scan(&keys).Error)
+		assert.Equal(t, []string{drained}, keys)
+	})
+}
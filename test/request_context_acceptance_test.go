@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/middleware"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestContextMiddleware_ClientDisconnect(t *testing.T) {
+	// serve starts a server whose /slow handler reports the cause of its context
+	// cancellation, or nil when it was not cancelled within wait.
+	serve := func(t *testing.T, disconnectCheck, wait time.Duration) (addr string, started <-chan struct{}, cause <-chan error) {
+		startedCh, causeCh := make(chan struct{}), make(chan error, 1)
+		app := fiber.New()
+		app.Use(middleware.RequestContextMiddleware(disconnectCheck))
+		app.Get("/slow", func(c fiber.Ctx) error {
+			close(startedCh)
+			select {
+			case <-c.Context().Done():
+				causeCh <- context.Cause(c.Context())
+			case <-time.After(wait):
+				causeCh <- nil
+			}
+			return c.SendString("done")
+		})
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go func() { _ = app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+		t.Cleanup(func() { _ = app.Shutdown() })
+		return ln.Addr().String(), startedCh, causeCh
+	}
+	send := func(t *testing.T, addr string) *net.TCPConn {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		_, err = fmt.Fprintf(conn, "GET /slow HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+		require.NoError(t, err)
+		return conn.(*net.TCPConn)
+	}
+
+	t.Run("cancels the work of a client that closed the connection", func(t *testing.T) {
+		addr, started, cause := serve(t, 50*time.Millisecond, 5*time.Second)
+		conn := send(t, addr)
+		<-started
+		require.NoError(t, conn.Close())
+
+		select {
+		case err := <-cause:
+			assert.ErrorIs(t, err, middleware.ErrClientDisconnected)
+		case <-time.After(10 * time.Second):
+			t.Fatal("handler did not return")
+		}
+	})
+
+	t.Run("answers a client that half-closed the connection when the check is off", func(t *testing.T) {
+		addr, started, cause := serve(t, 0, 300*time.Millisecond)
+		conn := send(t, addr)
+		<-started
+		require.NoError(t, conn.CloseWrite())
+
+		assert.NoError(t, <-cause)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		buf := make([]byte, 512)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		assert.Contains(t, string(buf[:n]), "200 OK")
+	})
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/middleware"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTimeoutMiddleware(t *testing.T) {
+	cases := []struct {
+		name     string
+		handler  fiber.Handler
+		expected int
+	}{
+		{
+			name: "work aborted by the deadline yields gateway timeout",
+			handler: func(c fiber.Ctx) error {
+				<-c.Context().Done()
+				return c.Status(nethttp.StatusServiceUnavailable).SendString("aborted")
+			},
+			expected: nethttp.StatusGatewayTimeout,
+		},
+		{
+			name: "response completed after the deadline is kept",
+			handler: func(c fiber.Ctx) error {
+				<-c.Context().Done()
+				return c.SendStatus(nethttp.StatusNoContent)
+			},
+			expected: nethttp.StatusNoContent,
+		},
+		{
+			name:     "fast requests are unaffected",
+			handler:  func(c fiber.Ctx) error { return c.SendStatus(nethttp.StatusOK) },
+			expected: nethttp.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(middleware.RequestContextMiddleware(0), middleware.RequestTimeoutMiddleware(20*time.Millisecond))
+			app.Get("/slow", tc.handler)
+
+			resp, err := app.Test(httptest.NewRequest(nethttp.MethodGet, "/slow", nil), fiber.TestConfig{Timeout: 5 * time.Second})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, resp.StatusCode)
+		})
+	}
+}
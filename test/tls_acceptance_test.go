@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	nethttp "net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/infra"
+	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/redact"
+	"zenrows-challenge/internal/pkg/util"
+	testutil "zenrows-challenge/test/util"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// certAuthService knows alice, who may also log in with Basic credentials.
+type certAuthService struct {
+	aliceID string
+}
+
+func (s certAuthService) CheckCredentials(_ context.Context, username string, password redact.Secret) (string, error) {
+	if username == "alice" && password.Reveal() == "secret" {
+		return s.aliceID, nil
+	}
+	return "", errors.New("invalid credentials")
+}
+
+func (s certAuthService) CheckCertificateUser(_ context.Context, username string) (string, error) {
+	if username == "alice" {
+		return s.aliceID, nil
+	}
+	return "", errors.New("unknown user")
+}
+
+func (certAuthService) RecordAuthFailure(context.Context, string, string) {}
+
+func TestTLSServer(t *testing.T) {
+	require.NoError(t, testutil.LoadConfig())
+	dir := t.TempDir()
+	ca := testutil.NewTestCA(t)
+	serverCert, serverKey := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+	ca.Issue(t, serverCert, serverKey, "server-1", true)
+
+	settings := map[string]any{
+		"server.tls.enabled":              true,
+		"server.tls.cert_file":            serverCert,
+		"server.tls.key_file":             serverKey,
+		"server.tls.min_version":          "1.3",
+		"server.tls.reload_interval":      time.Nanosecond,
+		"server.tls.client_auth.mode":     infra.ClientAuthOptional,
+		"server.tls.client_auth.ca_file":  ca.CertFile,
+		"server.tls.client_auth.identity": infra.CertIdentityCN,
+	}
+	for k, v := range settings {
+		viper.Set(k, v)
+	}
+	t.Cleanup(func() {
+		viper.Set("server.tls.enabled", false)
+		viper.Set("server.tls.client_auth.mode", infra.ClientAuthNone)
+	})
+
+	tlsConfig, err := infra.ServerTLSConfig(noopLogger{})
+	require.NoError(t, err)
+
+	svc := certAuthService{aliceID: uuid.NewString()}
+	app := fiber.New()
+	app.Use(middleware.RequestContextMiddleware(50*time.Millisecond))
+	app.Get("/whoami",
+		middleware.ClientCertAuthMiddleware(svc, infra.CertificateIdentity()),
+		middleware.BasicAuthCheckMiddleware(svc, validator.New()),
+		func(c fiber.Ctx) error {
+			p, _ := util.PrincipalFrom(c.Context())
+			return c.SendString(p.Username)
+		})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = app.Listener(tls.NewListener(ln, tlsConfig), fiber.ListenConfig{DisableStartupMessage: true})
+	}()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	url := "https://" + ln.Addr().String() + "/whoami"
+
+	client := func(certs ...tls.Certificate) *nethttp.Client {
+		return &nethttp.Client{Transport: &nethttp.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      ca.Pool(),
+			Certificates: certs,
+		}}}
+	}
+	clientCert := func(cn string) tls.Certificate {
+		certFile, keyFile := filepath.Join(dir, cn+".pem"), filepath.Join(dir, cn+"-key.pem")
+		ca.Issue(t, certFile, keyFile, cn, false)
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		require.NoError(t, err)
+		return cert
+	}
+	get := func(c *nethttp.Client, basic bool) (*nethttp.Response, string) {
+		req, err := nethttp.NewRequest(nethttp.MethodGet, url, nil)
+		require.NoError(t, err)
+		if basic {
+			req.SetBasicAuth("alice", "secret")
+		}
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	t.Run("client certificate authenticates its common name", func(t *testing.T) {
+		resp, body := get(client(clientCert("alice")), false)
+		assert.Equal(t, nethttp.StatusOK, resp.StatusCode)
+		assert.Equal(t, "alice", body)
+		assert.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+	})
+
+	t.Run("certificate of an unknown user is rejected", func(t *testing.T) {
+		resp, _ := get(client(clientCert("mallory")), true)
+		assert.Equal(t, nethttp.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("clients without a certificate fall back to Basic auth", func(t *testing.T) {
+		resp, body := get(client(), true)
+		assert.Equal(t, nethttp.StatusOK, resp.StatusCode)
+		assert.Equal(t, "alice", body)
+
+		resp, _ = get(client(), false)
+		assert.Equal(t, nethttp.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("rotated server certificate is served to new connections", func(t *testing.T) {
+		ca.Issue(t, serverCert, serverKey, "server-2", true)
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(serverCert, future, future))
+
+		resp, _ := get(client(), true)
+		assert.Equal(t, "server-2", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+}
@@ -0,0 +1,53 @@
+package test
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zenrows-challenge/internal/pkg/apperr"
+	"zenrows-challenge/internal/pkg/middleware"
+	"zenrows-challenge/internal/pkg/tracing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	app := fiber.New()
+	app.Use(middleware.RequestContextMiddleware(0), middleware.TracingMiddleware())
+	app.Get("/device-profiles/:id", func(c fiber.Ctx) (err error) {
+		_, span := tracing.Start(c.Context(), "device_profile.get", tracing.ProfileID(c.Params("id")))
+		defer tracing.End(span, &err)
+		return apperr.NewNotFoundErr("device profile not found", nil)
+	})
+
+	req := httptest.NewRequest(nethttp.MethodGet, "/device-profiles/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+
+	assert.Equal(t, "GET /device-profiles/:id", server.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Contains(t, server.Attributes(), attribute.String("http.route", "/device-profiles/:id"))
+
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Contains(t, child.Attributes(), tracing.ProfileID("42"))
+	assert.Contains(t, child.Attributes(), tracing.AttrErrorCode.String("NOT_FOUND"))
+}
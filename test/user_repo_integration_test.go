@@ -41,7 +41,7 @@ func TestUserRepositoryIntegration(t *testing.T) {
 					assert.FailNow(t, err.Error())
 				}
 				user := entity.User{Username: "alice", PasswordHash: string(passwordHash)}
-				userID, passHash, err := r.RetrieveCredentials(user)
+				userID, passHash, err := r.RetrieveCredentials(t.Context(), user)
 				if err != nil {
 					return
 				}
@@ -59,7 +59,7 @@ func TestUserRepositoryIntegration(t *testing.T) {
 					assert.FailNow(t, err.Error())
 				}
 				user := entity.User{Username: "wrong", PasswordHash: string(passwordHash)}
-				userID, passHash, err := r.RetrieveCredentials(user)
+				userID, passHash, err := r.RetrieveCredentials(t.Context(), user)
 
 				assert.NoError(t, err)
 				assert.Zero(t, userID)
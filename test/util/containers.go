@@ -4,13 +4,14 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"zenrows-challenge/internal/infra/migrate"
+	"zenrows-challenge/internal/pkg/applog"
+
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
 	"github.com/spf13/viper"
@@ -22,8 +23,8 @@ import (
 
 // InitTestContainers starts a Postgres 16 container for integration tests,
 // aligns credentials with configs/test.yml (or env overrides), updates viper
-// database settings to point to the container, and applies the SQL init scripts
-// from deployments/postgres. It registers a cleanup with t.Cleanup.
+// database settings to point to the container, and applies the embedded migrations
+// and seeds. It registers a cleanup with t.Cleanup.
 func InitTestContainers(t *testing.T) (func(), error) {
 	t.Helper()
 
@@ -111,75 +112,36 @@ func pointViperToContainer(host string, port int, dbName, dbUser, dbPass string)
 	viper.Set("database.sslmode", "disable")
 }
 
-// applyInitScripts connects to the new DB and executes all SQL files from
-// deployments/postgres in lexical order.
+// applyInitScripts connects to the new DB, applies the embedded schema migrations and
+// runs the seed scripts, leaving out the device templates when SKIP_DEVICE_TEMPLATE_SEED
+// is true.
 func applyInitScripts(host string, port int, dbName, dbUser, dbPass string) error {
 	dsn := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=disable", host, port, dbName, dbUser, dbPass)
 	gdb, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return err
-    }
-    scriptsDir, err := locateScriptsDir()
-    if err != nil {
-        return err
-    }
-    entries, err := os.ReadDir(scriptsDir)
-    if err != nil {
-        return err
-    }
-	var files []string
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		if filepath.Ext(name) == ".sql" {
-			files = append(files, filepath.Join(scriptsDir, name))
-		}
 	}
-	sort.Strings(files)
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	ctx := context.Background()
+	m := migrate.New(applog.NewAppDefaultLogger(), sqlDB)
+	if _, err := m.Up(ctx); err != nil {
+		return err
+	}
 	skipDeviceTemplates := strings.EqualFold(os.Getenv("SKIP_DEVICE_TEMPLATE_SEED"), "true")
-	for _, f := range files {
-		if skipDeviceTemplates && strings.Contains(strings.ToLower(filepath.Base(f)), "device_template") {
+	var seeds []string
+	for _, s := range migrate.Seeds() {
+		if skipDeviceTemplates && strings.Contains(s.Name, "device_template") {
 			continue
 		}
-		b, err := os.ReadFile(f)
-		if err != nil {
-			return err
-		}
-		if err := gdb.Exec(string(b)).Error; err != nil {
-			return fmt.Errorf("apply %s: %w", filepath.Base(f), err)
-		}
+		seeds = append(seeds, s.Name)
 	}
-    return nil
-}
-
-// locateScriptsDir finds the deployments/postgres directory robustly by checking
-// multiple candidate paths relative to config file location and working dir.
-func locateScriptsDir() (string, error) {
-    var candidates []string
-    if cfg := viper.ConfigFileUsed(); cfg != "" {
-        base := filepath.Dir(cfg)
-        candidates = append(candidates,
-            filepath.Join(base, "deployments", "postgres"),
-            filepath.Join(base, "..", "deployments", "postgres"),
-            filepath.Join(base, "..", "..", "deployments", "postgres"),
-        )
-    }
-    if wd, err := os.Getwd(); err == nil {
-        candidates = append(candidates,
-            filepath.Join(wd, "deployments", "postgres"),
-            filepath.Join(wd, "..", "deployments", "postgres"),
-            filepath.Join(wd, "..", "..", "deployments", "postgres"),
-        )
-    }
-    // Fallback
-    candidates = append(candidates, filepath.Join("deployments", "postgres"))
-
-    for _, p := range candidates {
-        if fi, err := os.Stat(p); err == nil && fi.IsDir() {
-            return p, nil
-        }
-    }
-    return "", fmt.Errorf("deployments/postgres directory not found")
+	if len(seeds) == 0 {
+		return nil
+	}
+	return m.Seed(ctx, seeds...)
 }